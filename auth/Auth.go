@@ -0,0 +1,92 @@
+// Package auth hashes passwords and tokens, and carries the signed in user through a request's context
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const MinPasswordLength = 8
+
+// bcrypt ignores everything past 72 bytes, so longer passwords are refused rather than silently truncated
+const MaxPasswordLength = 72
+
+func HashPassword(password string) (string, error) {
+	if len(password) < MinPasswordLength {
+		return "", errors.New("password must be at least 8 characters")
+	}
+	if len(password) > MaxPasswordLength {
+		return "", errors.New("password must be at most 72 bytes")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyHash lets a login for an unknown user take as long as one with a wrong password
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not a real password"), bcrypt.DefaultCost)
+
+// CheckUserPassword is CheckPassword for a user that may not exist
+func CheckUserPassword(user *model.User, password string) bool {
+	if user == nil {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return false
+	}
+	return CheckPassword(user.PasswordHash, password)
+}
+
+// ApiKeyPrefix makes API keys recognizable, e.g. to secret scanners
+const ApiKeyPrefix = "food_"
+
+// NewToken returns a random token and the hash to store in its place.
+// Tokens are long and random enough that a fast hash is safe, unlike passwords.
+func NewToken(prefix string) (token string, hash string, err error) {
+	buffer := make([]byte, 32)
+	if _, err := rand.Read(buffer); err != nil {
+		return "", "", err
+	}
+	token = prefix + base64.RawURLEncoding.EncodeToString(buffer)
+	return token, HashToken(token), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// BearerToken returns the token from an "Authorization: Bearer <token>" header
+func BearerToken(header string) (string, bool) {
+	scheme, token, found := strings.Cut(header, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+type contextKey struct{}
+
+func WithUser(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFrom returns the signed in user, or nil for anonymous requests
+func UserFrom(ctx context.Context) *model.User {
+	user, _ := ctx.Value(contextKey{}).(*model.User)
+	return user
+}
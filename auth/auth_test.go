@@ -0,0 +1,97 @@
+package auth_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/auth"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHashPassword(t *testing.T) {
+	type testCase struct {
+		name          string
+		password      string
+		expectedError bool
+	}
+
+	testCases := []testCase{
+		{name: "Valid", password: "correct horse battery staple"},
+		{name: "Too short", password: "hunter2", expectedError: true},
+		{name: "Too long", password: strings.Repeat("a", 73), expectedError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hash, err := auth.HashPassword(tc.password)
+
+			assert := assert.New(t)
+			if tc.expectedError {
+				assert.Error(err)
+				return
+			}
+			assert.NoError(err)
+			assert.NotEqual(tc.password, hash)
+			assert.True(auth.CheckPassword(hash, tc.password))
+			assert.False(auth.CheckPassword(hash, tc.password+"!"))
+		})
+	}
+}
+
+func TestCheckUserPassword(t *testing.T) {
+	hash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+	assert.True(auth.CheckUserPassword(&model.User{PasswordHash: hash}, "correct horse battery staple"))
+	assert.False(auth.CheckUserPassword(&model.User{PasswordHash: hash}, "wrong"))
+	assert.False(auth.CheckUserPassword(nil, "correct horse battery staple"))
+}
+
+func TestNewToken(t *testing.T) {
+	token, hash, err := auth.NewToken(auth.ApiKeyPrefix)
+	other, _, _ := auth.NewToken(auth.ApiKeyPrefix)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.True(strings.HasPrefix(token, auth.ApiKeyPrefix))
+	assert.Equal(hash, auth.HashToken(token))
+	assert.NotEqual(token, other)
+}
+
+func TestBearerToken(t *testing.T) {
+	type testCase struct {
+		name          string
+		header        string
+		expectedToken string
+		expectedFound bool
+	}
+
+	testCases := []testCase{
+		{name: "Bearer", header: "Bearer food_abc", expectedToken: "food_abc", expectedFound: true},
+		{name: "Case insensitive scheme", header: "bearer food_abc", expectedToken: "food_abc", expectedFound: true},
+		{name: "Basic", header: "Basic dXNlcjpwYXNz"},
+		{name: "Empty token", header: "Bearer "},
+		{name: "Missing", header: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			token, found := auth.BearerToken(tc.header)
+
+			assert.Equal(t, tc.expectedFound, found)
+			assert.Equal(t, tc.expectedToken, token)
+		})
+	}
+}
+
+func TestUserFrom(t *testing.T) {
+	user := &model.User{Id: "123", Username: "cook"}
+
+	assert.Nil(t, auth.UserFrom(context.Background()))
+	assert.Equal(t, user, auth.UserFrom(auth.WithUser(context.Background(), user)))
+}
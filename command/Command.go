@@ -0,0 +1,170 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// Action runs a command once the configuration is loaded. args are whatever is left after the flags.
+type Action func(cfg *config.Config, args []string, out io.Writer) error
+
+type Command struct {
+	Name string
+	// describes the positional arguments in the usage message, e.g. <zip>
+	Args    string
+	Summary string
+	// Setup adds the command's own flags, next to the configuration flags every command takes, and returns the action to run
+	Setup func(flags *flag.FlagSet) Action
+}
+
+// Commands lists every subcommand; the first one runs when none is given
+func Commands() []Command {
+	return []Command{
+		serveCommand,
+		migrateCommand,
+		importUsdaCommand,
+		exportCommand,
+		importCommand,
+		purgeCommand,
+		setRoleCommand,
+		routesCommand,
+	}
+}
+
+// errUsage marks errors in how a command was called, as opposed to errors while running it
+var errUsage = errors.New("usage")
+
+// Run parses args as a subcommand followed by its flags, runs it and returns the process's exit code:
+// 0 on success, 1 when the command fails and 2 when it was called incorrectly.
+func Run(args []string, getenv func(string) string, stdout io.Writer, stderr io.Writer) int {
+	commands := Commands()
+	command := commands[0]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name := args[0]
+		args = args[1:]
+
+		if name == "help" {
+			printUsage(stdout, commands)
+			return 0
+		}
+
+		found := false
+		for _, c := range commands {
+			if c.Name == name {
+				command, found = c, true
+				break
+			}
+		}
+		if !found {
+			fmt.Fprintf(stderr, "unknown command %q\n\n", name)
+			printUsage(stderr, commands)
+			return 2
+		}
+	}
+
+	flags := flag.NewFlagSet("food "+command.Name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	action := command.Setup(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: food %s [flags] %s\n\n%s\n\n", command.Name, command.Args, command.Summary)
+		flags.PrintDefaults()
+	}
+
+	cfg, err := config.Load(flags, args, getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	} else if err != nil {
+		fmt.Fprintf(stderr, "invalid configuration:\n%s\n", err)
+		return 2
+	}
+
+	zerolog.SetGlobalLevel(cfg.ZerologLevel())
+
+	err = action(cfg, flags.Args(), stdout)
+	if errors.Is(err, errUsage) {
+		fmt.Fprintln(stderr, err)
+		flags.Usage()
+		return 2
+	} else if err != nil {
+		log.Error().Err(err).Str("command", command.Name).Msg("failed")
+		return 1
+	}
+	return 0
+}
+
+func printUsage(w io.Writer, commands []Command) {
+	fmt.Fprintf(w, "usage: food [command] [flags]\n\ncommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.Name, c.Summary)
+	}
+	fmt.Fprintf(w, "\nWithout a command, food runs %s. Run food <command> -h for a command's flags.\n", commands[0].Name)
+}
+
+func usageError(format string, a ...any) error {
+	return fmt.Errorf("%w: %s", errUsage, fmt.Sprintf(format, a...))
+}
+
+// interruptible returns a context that's cancelled on SIGINT or SIGTERM
+func interruptible() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+}
+
+// how long closing the driver may take
+const closeTimeout = 30 * time.Second
+
+// withDriver connects to Neo4j for the duration of fn
+func withDriver(cfg *config.Config, fn func(driver neo4j.DriverWithContext) error) error {
+	// memory storage belongs to the serve process, so there's nothing for another command to work on
+	if cfg.Storage != config.Neo4jStorage {
+		return usageError("this command needs %s storage", config.Neo4jStorage)
+	}
+
+	auth := neo4j.NoAuth()
+	if cfg.Neo4j.Username != "" {
+		auth = neo4j.BasicAuth(cfg.Neo4j.Username, cfg.Neo4j.Password, "")
+	}
+	useConsoleLogger := func(level neo4j.LogLevel) func(config *neo4j.Config) {
+		return func(config *neo4j.Config) {
+			config.Log = neo4j.ConsoleLogger(level)
+		}
+	}
+	driver, err := neo4j.NewDriverWithContext(cfg.Neo4j.Uri, auth, useConsoleLogger(neo4jLogLevel(cfg.ZerologLevel())))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+		defer cancel()
+		if err := driver.Close(ctx); err != nil {
+			log.Error().Err(err).Msg("could not close neo4j driver")
+		}
+	}()
+
+	return fn(driver)
+}
+
+// neo4jLogLevel maps the application's log level onto the driver's coarser levels
+func neo4jLogLevel(level zerolog.Level) neo4j.LogLevel {
+	switch {
+	case level <= zerolog.DebugLevel:
+		return neo4j.DEBUG
+	case level == zerolog.InfoLevel:
+		return neo4j.INFO
+	case level == zerolog.WarnLevel:
+		return neo4j.WARNING
+	default:
+		return neo4j.ERROR
+	}
+}
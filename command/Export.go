@@ -0,0 +1,113 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// record is one line of an export; exactly one of Food and Recipe is set, depending on Kind
+type record struct {
+	Kind   string        `json:"kind"`
+	Food   *model.Food   `json:"food,omitempty"`
+	Recipe *model.Recipe `json:"recipe,omitempty"`
+}
+
+const foodKind = "food"
+const recipeKind = "recipe"
+
+const exportPageSize = 100
+
+var exportCommand = Command{
+	Name:    "export",
+	Summary: "write foods and recipes as JSON lines, for backups or moving them to another database",
+	Setup: func(flags *flag.FlagSet) Action {
+		output := flags.String("output", "-", "file to write to, or - for stdout")
+		kinds := flags.String("kinds", "food,recipe", "comma separated kinds to export")
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) > 0 {
+				return usageError("export takes no arguments")
+			}
+			exportFoods, exportRecipes := false, false
+			for _, kind := range strings.Split(*kinds, ",") {
+				switch strings.TrimSpace(kind) {
+				case foodKind:
+					exportFoods = true
+				case recipeKind:
+					exportRecipes = true
+				default:
+					return usageError("unknown kind %q, expected %s or %s", kind, foodKind, recipeKind)
+				}
+			}
+
+			if *output != "-" {
+				file, err := os.Create(*output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			encoder := json.NewEncoder(out)
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				ctx, stop := interruptible()
+				defer stop()
+
+				// foods come first, so an import can create them before the recipes that use them
+				if exportFoods {
+					foodRepository := repository.NewFoodRepository(driver, cfg.Neo4j.Database)
+					err := exportAll(ctx, foodRepository.GetAll, func(food model.Food) error {
+						return encoder.Encode(record{Kind: foodKind, Food: &food})
+					})
+					if err != nil {
+						return fmt.Errorf("could not export foods: %w", err)
+					}
+				}
+
+				if exportRecipes {
+					recipeRepository := repository.NewRecipeRepository(driver, cfg.Neo4j.Database)
+					err := exportAll(ctx, recipeRepository.GetAll, func(recipe model.Recipe) error {
+						return encoder.Encode(record{Kind: recipeKind, Recipe: &recipe})
+					})
+					if err != nil {
+						return fmt.Errorf("could not export recipes: %w", err)
+					}
+				}
+				return nil
+			})
+		}
+	},
+}
+
+// exportAll pages through everything getAll returns
+func exportAll[T any](ctx context.Context, getAll func(context.Context, model.ListQuery) (model.Page[T], error), write func(T) error) error {
+	// an export is a backup, so it includes private recipes too
+	query := model.ListQuery{Viewer: model.Viewer{All: true}, PageRequest: model.PageRequest{Limit: exportPageSize}}
+	for {
+		page, err := getAll(ctx, query)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Items {
+			if err := write(item); err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == nil {
+			return nil
+		}
+		query.Cursor = page.NextCursor
+	}
+}
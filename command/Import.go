@@ -0,0 +1,133 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog/log"
+)
+
+var importCommand = Command{
+	Name:    "import",
+	Args:    "<file>",
+	Summary: "load foods and recipes written by export, or - for stdin",
+	Setup: func(flags *flag.FlagSet) Action {
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) != 1 {
+				return usageError("expected a file written by export")
+			}
+
+			var in io.Reader = os.Stdin
+			if args[0] != "-" {
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				in = file
+			}
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				ctx, stop := interruptible()
+				defer stop()
+
+				importer := &importer{
+					foods:   repository.NewFoodRepository(driver, cfg.Neo4j.Database),
+					recipes: repository.NewRecipeRepository(driver, cfg.Neo4j.Database),
+				}
+				if err := importer.importRecords(ctx, in); err != nil {
+					return err
+				}
+
+				_, err := fmt.Fprintf(out, "created %d and updated %d\n", importer.created, importer.updated)
+				return err
+			})
+		}
+	},
+}
+
+// importer updates resources whose ids already exist and creates the rest with their exported ids, so recipes still
+// point at their foods, and importing the same file again changes nothing
+type importer struct {
+	foods   model.FoodRepository
+	recipes model.RecipeRepository
+	created int
+	updated int
+}
+
+func (i *importer) importRecords(ctx context.Context, in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	// recipes with long steps don't fit in the default buffer
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for line := 1; scanner.Scan(); line++ {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		var err error
+		switch {
+		case r.Kind == foodKind && r.Food != nil:
+			err = i.importFood(ctx, *r.Food)
+		case r.Kind == recipeKind && r.Recipe != nil:
+			err = i.importRecipe(ctx, *r.Recipe)
+		default:
+			err = fmt.Errorf("unknown kind %q", r.Kind)
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func (i *importer) importFood(ctx context.Context, food model.Food) error {
+	_, found, err := i.foods.GetById(ctx, food.Id)
+	if err != nil {
+		return err
+	}
+	if found {
+		if _, err := i.foods.Update(ctx, food); err != nil {
+			return err
+		}
+		i.updated++
+		return nil
+	}
+
+	if _, err := i.foods.Create(ctx, food); err != nil {
+		return err
+	}
+	log.Debug().Str("id", food.Id).Msg("created food")
+	i.created++
+	return nil
+}
+
+func (i *importer) importRecipe(ctx context.Context, recipe model.Recipe) error {
+	_, found, err := i.recipes.GetById(ctx, recipe.Id)
+	if err != nil {
+		return err
+	}
+	if found {
+		if _, err := i.recipes.Update(ctx, recipe); err != nil {
+			return err
+		}
+		i.updated++
+		return nil
+	}
+
+	if _, err := i.recipes.Create(ctx, recipe); err != nil {
+		return err
+	}
+	i.created++
+	return nil
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/usda"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+var importUsdaCommand = Command{
+	Name:    "import-usda",
+	Args:    "FoodData_Central_csv_<date>.zip",
+	Summary: "import foods, nutrients and portions from a USDA FoodData Central csv download",
+	Setup: func(flags *flag.FlagSet) Action {
+		batchSize := flags.Int("batch-size", usda.DefaultBatchSize, "rows per write transaction")
+		checkpointFile := flags.String("checkpoint", "", "file recording progress, to resume an interrupted import (default <zip>.checkpoint.json)")
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) != 1 {
+				return usageError("expected the path to a FoodData Central csv zip")
+			}
+			zipPath := args[0]
+			options := usda.Options{BatchSize: *batchSize, CheckpointFile: *checkpointFile}
+			if options.CheckpointFile == "" {
+				options.CheckpointFile = zipPath + ".checkpoint.json"
+			}
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				// the import relies on the id constraint and indexes
+				if err := applyMigrations(context.Background(), cfg, driver, false, out); err != nil {
+					return fmt.Errorf("could not apply migrations: %w", err)
+				}
+
+				// stop between batches on SIGINT or SIGTERM; the checkpoint lets the next run resume
+				ctx, stop := interruptible()
+				defer stop()
+
+				_, err := usda.NewImporter(driver, cfg.Neo4j.Database, options).Import(ctx, zipPath)
+				return err
+			})
+		}
+	},
+}
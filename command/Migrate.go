@@ -0,0 +1,50 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/migrations"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog/log"
+)
+
+var migrateCommand = Command{
+	Name:    "migrate",
+	Summary: "apply pending schema migrations",
+	Setup: func(flags *flag.FlagSet) Action {
+		dryRun := flags.Bool("dry-run", false, "list pending migrations without applying them")
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) > 0 {
+				return usageError("migrate takes no arguments")
+			}
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				return applyMigrations(context.Background(), cfg, driver, *dryRun, out)
+			})
+		}
+	},
+}
+
+// applyMigrations lists the pending migrations on out when dryRun is set
+func applyMigrations(ctx context.Context, cfg *config.Config, driver neo4j.DriverWithContext, dryRun bool, out io.Writer) error {
+	all, err := migrations.Load()
+	if err != nil {
+		return err
+	}
+
+	applied, err := migrations.NewMigrator(driver, cfg.Neo4j.Database).Apply(ctx, all, dryRun)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		for _, migration := range applied {
+			fmt.Fprintf(out, "pending: %04d_%s\n", migration.Version, migration.Name)
+		}
+	}
+	log.Info().Int("count", len(applied)).Bool("dry_run", dryRun).Msg("migrations")
+	return nil
+}
@@ -0,0 +1,46 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+var purgeCommand = Command{
+	Name:    "purge",
+	Summary: "permanently remove foods, recipes and ingredients that were deleted a while ago",
+	Setup: func(flags *flag.FlagSet) Action {
+		olderThan := flags.Duration("older-than", 30*24*time.Hour, "only purge what was deleted at least this long ago")
+		dryRun := flags.Bool("dry-run", false, "count what would be purged without removing it")
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) > 0 {
+				return usageError("purge takes no arguments")
+			}
+			if *olderThan < 0 {
+				return usageError("-older-than must not be negative")
+			}
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				ctx, stop := interruptible()
+				defer stop()
+
+				result, err := repository.NewPurgeRepository(driver, cfg.Neo4j.Database).Purge(ctx, time.Now().Add(-*olderThan), *dryRun)
+				if err != nil {
+					return err
+				}
+
+				verb := "purged"
+				if *dryRun {
+					verb = "would purge"
+				}
+				_, err = fmt.Fprintf(out, "%s %d nodes and %d relationships\n", verb, result.Nodes, result.Relationships)
+				return err
+			})
+		}
+	},
+}
@@ -0,0 +1,28 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/go-chi/chi/v5"
+)
+
+var routesCommand = Command{
+	Name:    "routes",
+	Summary: "list the http server's routes",
+	Setup: func(flags *flag.FlagSet) Action {
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) > 0 {
+				return usageError("routes takes no arguments")
+			}
+			// the routes are the same whatever the storage, so this doesn't need a database
+			return chi.Walk(newApp(cfg, memoryRepositories()).router, func(method, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+				_, err := fmt.Fprintf(out, "%s %s\n", method, route)
+				return err
+			})
+		}
+	},
+}
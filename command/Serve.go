@@ -0,0 +1,167 @@
+package command
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/controller"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/ThomasMatlak/food/static"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog/log"
+)
+
+var serveCommand = Command{
+	Name:    "serve",
+	Summary: "apply pending migrations and run the http server",
+	Setup: func(flags *flag.FlagSet) Action {
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) > 0 {
+				return usageError("serve takes no arguments")
+			}
+			if cfg.Storage == config.MemoryStorage {
+				log.Warn().Msg("keeping data in memory; it will be gone when the server stops")
+				return serve(cfg, memoryRepositories())
+			}
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				// the server depends on the constraints and indexes, so it doesn't start without them
+				if err := applyMigrations(context.Background(), cfg, driver, false, io.Discard); err != nil {
+					return fmt.Errorf("could not apply migrations: %w", err)
+				}
+				return serve(cfg, neo4jRepositories(cfg, driver))
+			})
+		}
+	},
+}
+
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 15 * time.Second
+	writeTimeout      = 30 * time.Second
+	idleTimeout       = 2 * time.Minute
+	// how long readiness fails before the server stops accepting connections, so the load balancer notices first
+	readinessDrainDelay = 5 * time.Second
+	// how long in-flight requests get to finish after SIGINT or SIGTERM
+	shutdownTimeout = 30 * time.Second
+)
+
+type repositories struct {
+	foods      model.FoodRepository
+	recipes    model.RecipeRepository
+	users      model.UserRepository
+	households model.HouseholdRepository
+	listItems  model.ListItemRepository
+	health     model.HealthRepository
+}
+
+func neo4jRepositories(cfg *config.Config, driver neo4j.DriverWithContext) repositories {
+	return repositories{
+		foods:      repository.NewFoodRepository(driver, cfg.Neo4j.Database),
+		recipes:    repository.NewRecipeRepository(driver, cfg.Neo4j.Database),
+		users:      repository.NewUserRepository(driver, cfg.Neo4j.Database),
+		households: repository.NewHouseholdRepository(driver, cfg.Neo4j.Database),
+		listItems:  repository.NewListItemRepository(driver, cfg.Neo4j.Database),
+		health:     repository.NewHealthRepository(driver, cfg.Neo4j.Database),
+	}
+}
+
+func memoryRepositories() repositories {
+	store := repository.NewMemoryStore()
+	return repositories{
+		foods:      repository.NewMemoryFoodRepository(store),
+		recipes:    repository.NewMemoryRecipeRepository(store),
+		users:      repository.NewMemoryUserRepository(store),
+		households: repository.NewMemoryHouseholdRepository(store),
+		listItems:  repository.NewMemoryListItemRepository(store),
+		health:     repository.NewMemoryHealthRepository(),
+	}
+}
+
+// app is the http handler along with the parts of it the server needs to reach during shutdown
+type app struct {
+	router           chi.Router
+	healthController *controller.HealthController
+}
+
+func newApp(cfg *config.Config, repositories repositories) app {
+	recipeController := controller.NewRecipeController(repositories.recipes, repositories.households, repositories.foods)
+	foodController := controller.NewFoodController(repositories.foods)
+	healthController := controller.NewHealthController(repositories.health)
+	authController := controller.NewAuthController(repositories.users, cfg.Signup == config.OpenSignup, cfg.UseSecureCookies())
+	householdController := controller.NewHouseholdController(repositories.households, repositories.users, repositories.listItems, repositories.foods)
+
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Use(middleware.RealIP)
+	router.Use(middleware.Logger)
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.StripSlashes)
+	router.Use(controller.RefuseCrossSite)
+	router.Use(authController.Authenticate)
+
+	router.Group(func(r chi.Router) {
+		r.Use(controller.RequireUserForWrites)
+		recipeController.RecipeRoutes(r)
+		foodController.FoodRoutes(r)
+	})
+	authController.AuthRoutes(router)
+	householdController.HouseholdRoutes(router)
+	healthController.HealthRoutes(router)
+	router.Handle("/static/*", http.StripPrefix("/static/", static.Handler()))
+
+	return app{router: router, healthController: healthController}
+}
+
+func serve(cfg *config.Config, repositories repositories) error {
+	app := newApp(cfg, repositories)
+	server := &http.Server{
+		Addr:              cfg.Server.Address,
+		Handler:           app.router,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+
+	signals, stop := interruptible()
+	defer stop()
+
+	listenErr := make(chan error, 1)
+	go func() {
+		if cfg.UseTls() {
+			listenErr <- server.ListenAndServeTLS(cfg.Server.TlsCertFile, cfg.Server.TlsKeyFile)
+		} else {
+			listenErr <- server.ListenAndServe()
+		}
+	}()
+	log.Info().Str("address", cfg.Server.Address).Bool("tls", cfg.UseTls()).Msg("listening")
+
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("could not listen on %s: %w", cfg.Server.Address, err)
+	case <-signals.Done():
+	}
+	// a second signal kills the process without waiting
+	stop()
+
+	app.healthController.ShuttingDown()
+	log.Info().Dur("delay", readinessDrainDelay).Msg("shutting down, failing readiness checks")
+	time.Sleep(readinessDrainDelay)
+
+	log.Info().Dur("timeout", shutdownTimeout).Msg("draining requests")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("could not drain requests: %w", err)
+	}
+	return nil
+}
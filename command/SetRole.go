@@ -0,0 +1,47 @@
+package command
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+var setRoleCommand = Command{
+	Name:    "set-role",
+	Args:    "<username> <role>",
+	Summary: "change what a user is allowed to do: viewer, cook, curator or admin",
+	Setup: func(flags *flag.FlagSet) Action {
+		return func(cfg *config.Config, args []string, out io.Writer) error {
+			if len(args) != 2 {
+				return usageError("set-role takes a username and a role")
+			}
+			role, err := model.ParseRole(args[1])
+			if err != nil {
+				return usageError("%s", err)
+			}
+
+			return withDriver(cfg, func(driver neo4j.DriverWithContext) error {
+				ctx, stop := interruptible()
+				defer stop()
+
+				// usernames are stored the way signup normalizes them
+				username := strings.ToLower(strings.TrimSpace(args[0]))
+				user, found, err := repository.NewUserRepository(driver, cfg.Neo4j.Database).SetRole(ctx, username, role)
+				if err != nil {
+					return err
+				} else if !found {
+					return fmt.Errorf("no user is named %q", username)
+				}
+
+				_, err = fmt.Fprintf(out, "%s's role is now %s\n", user.Username, user.Role)
+				return err
+			})
+		}
+	},
+}
@@ -0,0 +1,49 @@
+package command_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ThomasMatlak/food/command"
+	"github.com/stretchr/testify/assert"
+)
+
+func env(key string) string {
+	return ""
+}
+
+func TestRun(t *testing.T) {
+	type testCase struct {
+		name         string
+		args         []string
+		expectedCode int
+		stdout       string
+		stderr       string
+	}
+
+	testCases := []testCase{
+		{name: "Help", args: []string{"help"}, expectedCode: 0, stdout: "import-usda"},
+		{name: "Unknown command", args: []string{"frobnicate"}, expectedCode: 2, stderr: `unknown command "frobnicate"`},
+		{name: "Command help", args: []string{"purge", "-h"}, expectedCode: 0, stderr: "-older-than"},
+		{name: "Invalid configuration", args: []string{"routes", "-log-level", "loud"}, expectedCode: 2, stderr: "invalid configuration"},
+		{name: "Missing argument", args: []string{"import"}, expectedCode: 2, stderr: "usage: food import [flags] <file>"},
+		{name: "Unexpected argument", args: []string{"migrate", "now"}, expectedCode: 2, stderr: "migrate takes no arguments"},
+		{name: "Unknown export kind", args: []string{"export", "-kinds", "food,pantry"}, expectedCode: 2, stderr: `unknown kind "pantry"`},
+		{name: "Needs neo4j", args: []string{"export", "--storage=memory"}, expectedCode: 2, stderr: "needs neo4j storage"},
+		{name: "Missing role", args: []string{"set-role", "sam"}, expectedCode: 2, stderr: "set-role takes a username and a role"},
+		{name: "Unknown role", args: []string{"set-role", "sam", "chef"}, expectedCode: 2, stderr: `role "chef" is not one of`},
+		{name: "Routes", args: []string{"routes"}, expectedCode: 0, stdout: "GET /healthz\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+
+			code := command.Run(tc.args, env, stdout, stderr)
+
+			assert.Equal(t, tc.expectedCode, code, stderr.String())
+			assert.Contains(t, stdout.String(), tc.stdout)
+			assert.Contains(t, stderr.String(), tc.stderr)
+		})
+	}
+}
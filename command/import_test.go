@@ -0,0 +1,55 @@
+package command
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+const export = `{"kind": "food", "food": {"id": "Food:Resource:garlic", "name": "Garlic"}}
+{"kind": "recipe", "recipe": {"id": "Recipe:Resource:roast-garlic", "title": "Roast garlic", "steps": ["Roast"], "ingredients": [{"ingredient_id": "Food:Resource:garlic", "amount": 1, "unit": "head"}]}}
+`
+
+func TestImportTwice(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryStore()
+	newImporter := func() *importer {
+		return &importer{foods: repository.NewMemoryFoodRepository(store), recipes: repository.NewMemoryRecipeRepository(store)}
+	}
+	assert := assert.New(t)
+
+	first := newImporter()
+	assert.NoError(first.importRecords(ctx, strings.NewReader(export)))
+	assert.Equal(2, first.created)
+	assert.Equal(0, first.updated)
+
+	second := newImporter()
+	assert.NoError(second.importRecords(ctx, strings.NewReader(export)))
+	assert.Equal(0, second.created)
+	assert.Equal(2, second.updated)
+
+	recipe, found, err := second.recipes.GetById(ctx, "Recipe:Resource:roast-garlic")
+	assert.NoError(err)
+	if assert.True(found) && assert.Len(recipe.Ingredients, 1) {
+		assert.Equal("Food:Resource:garlic", recipe.Ingredients[0].IngredientId)
+	}
+	foods, err := second.foods.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
+	assert.NoError(err)
+	assert.Len(foods.Items, 1, "importing again doesn't duplicate the food")
+}
+
+func TestImportCountsOnlyWhatWasWritten(t *testing.T) {
+	store := repository.NewMemoryStore()
+	i := &importer{foods: repository.NewMemoryFoodRepository(store), recipes: repository.NewMemoryRecipeRepository(store)}
+
+	// the recipe's ingredient isn't in the export or the database
+	err := i.importRecords(context.Background(), strings.NewReader(strings.SplitAfter(export, "\n")[1]))
+
+	assert.ErrorContains(t, err, "line 1")
+	assert.Equal(t, 0, i.created)
+	assert.Equal(t, 0, i.updated)
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+type Config struct {
+	// neo4j, or memory to run without a database; memory keeps nothing once the process exits
+	Storage string       `json:"storage"`
+	Neo4j   Neo4jConfig  `json:"neo4j"`
+	Server  ServerConfig `json:"server"`
+	// one of zerolog's levels: trace, debug, info, warn, error, fatal, panic or disabled
+	LogLevel string `json:"log_level"`
+	// open lets anyone sign up; closed only lets the first user sign up
+	Signup string `json:"signup"`
+}
+
+type Neo4jConfig struct {
+	Uri string `json:"uri"`
+	// leave the username and password empty to connect without auth
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// empty uses the server's default database
+	Database string `json:"database"`
+}
+
+type ServerConfig struct {
+	Address string `json:"address"`
+	// serve https when both are set
+	TlsCertFile string `json:"tls_cert_file"`
+	TlsKeyFile  string `json:"tls_key_file"`
+	// true to only send the session cookie over https, which is still the case when a proxy terminates TLS in front of
+	// the server; false for plain http anywhere but localhost, which browsers treat as secure anyway
+	SecureCookies string `json:"secure_cookies"`
+}
+
+func Default() Config {
+	return Config{
+		Storage:  Neo4jStorage,
+		Neo4j:    Neo4jConfig{Uri: "bolt://localhost:7687"},
+		Server:   ServerConfig{Address: ":8080", SecureCookies: "true"},
+		LogLevel: "info",
+		Signup:   ClosedSignup,
+	}
+}
+
+const Neo4jStorage = "neo4j"
+const MemoryStorage = "memory"
+
+const OpenSignup = "open"
+const ClosedSignup = "closed"
+
+type setting struct {
+	flag        string
+	env         string
+	description string
+	value       func(*Config) *string
+}
+
+var settings = []setting{
+	{flag: "storage", env: "FOOD_STORAGE", description: "where to keep data: neo4j, or memory to run without a database", value: func(c *Config) *string { return &c.Storage }},
+	{flag: "neo4j-uri", env: "FOOD_NEO4J_URI", description: "Neo4j connection uri", value: func(c *Config) *string { return &c.Neo4j.Uri }},
+	{flag: "neo4j-username", env: "FOOD_NEO4J_USERNAME", description: "Neo4j username", value: func(c *Config) *string { return &c.Neo4j.Username }},
+	{flag: "neo4j-password", env: "FOOD_NEO4J_PASSWORD", description: "Neo4j password", value: func(c *Config) *string { return &c.Neo4j.Password }},
+	{flag: "neo4j-database", env: "FOOD_NEO4J_DATABASE", description: "Neo4j database name", value: func(c *Config) *string { return &c.Neo4j.Database }},
+	{flag: "listen-address", env: "FOOD_LISTEN_ADDRESS", description: "address for the http server to listen on", value: func(c *Config) *string { return &c.Server.Address }},
+	{flag: "tls-cert-file", env: "FOOD_TLS_CERT_FILE", description: "TLS certificate file", value: func(c *Config) *string { return &c.Server.TlsCertFile }},
+	{flag: "tls-key-file", env: "FOOD_TLS_KEY_FILE", description: "TLS private key file", value: func(c *Config) *string { return &c.Server.TlsKeyFile }},
+	{flag: "secure-cookies", env: "FOOD_SECURE_COOKIES", description: "only send the session cookie over https: true or false", value: func(c *Config) *string { return &c.Server.SecureCookies }},
+	{flag: "log-level", env: "FOOD_LOG_LEVEL", description: "log level", value: func(c *Config) *string { return &c.LogLevel }},
+	{flag: "signup", env: "FOOD_SIGNUP", description: "who can sign up: open, or closed to only let the first user", value: func(c *Config) *string { return &c.Signup }},
+}
+
+const configFileFlag = "config"
+const configFileEnv = "FOOD_CONFIG"
+
+// Load builds the configuration from, in increasing order of precedence: defaults, a JSON config file, environment variables and command line flags.
+// The config file is optional, and is read from the -config flag or FOOD_CONFIG.
+// The settings are added to flags, so callers can define their own flags alongside them.
+func Load(flags *flag.FlagSet, args []string, getenv func(string) string) (*Config, error) {
+	configFile := flags.String(configFileFlag, getenv(configFileEnv), "path to a JSON config file (env "+configFileEnv+")")
+	flagValues := map[string]*string{}
+	for _, s := range settings {
+		flagValues[s.flag] = flags.String(s.flag, "", fmt.Sprintf("%s (env %s)", s.description, s.env))
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	config := Default()
+
+	if *configFile != "" {
+		if err := readFile(*configFile, &config); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, s := range settings {
+		if value := getenv(s.env); value != "" {
+			*s.value(&config) = value
+		}
+	}
+
+	// only flags that were actually passed override the other sources
+	flags.Visit(func(f *flag.Flag) {
+		for _, s := range settings {
+			if s.flag == f.Name {
+				*s.value(&config) = *flagValues[s.flag]
+			}
+		}
+	})
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func readFile(path string, config *Config) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not read config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(config); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// Validate reports every problem with the configuration at once
+func (c *Config) Validate() error {
+	problems := []error{}
+
+	if c.Storage != Neo4jStorage && c.Storage != MemoryStorage {
+		problems = append(problems, fmt.Errorf("storage %q is not one of %s or %s", c.Storage, Neo4jStorage, MemoryStorage))
+	}
+
+	if uri, err := url.Parse(c.Neo4j.Uri); err != nil || uri.Host == "" {
+		problems = append(problems, fmt.Errorf("neo4j uri %q is not a valid uri", c.Neo4j.Uri))
+	} else {
+		switch uri.Scheme {
+		case "bolt", "bolt+s", "bolt+ssc", "neo4j", "neo4j+s", "neo4j+ssc":
+		default:
+			problems = append(problems, fmt.Errorf("neo4j uri scheme %q is not one of bolt, bolt+s, bolt+ssc, neo4j, neo4j+s or neo4j+ssc", uri.Scheme))
+		}
+	}
+	if (c.Neo4j.Username == "") != (c.Neo4j.Password == "") {
+		problems = append(problems, errors.New("neo4j username and password must be set together"))
+	}
+
+	if strings.TrimSpace(c.Server.Address) == "" {
+		problems = append(problems, errors.New("listen address must not be empty"))
+	}
+	if (c.Server.TlsCertFile == "") != (c.Server.TlsKeyFile == "") {
+		problems = append(problems, errors.New("tls cert file and tls key file must be set together"))
+	}
+	for _, path := range []string{c.Server.TlsCertFile, c.Server.TlsKeyFile} {
+		if path == "" {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			problems = append(problems, fmt.Errorf("tls file %s: %w", path, err))
+		}
+	}
+
+	if _, err := strconv.ParseBool(c.Server.SecureCookies); err != nil {
+		problems = append(problems, fmt.Errorf("secure cookies %q is not true or false", c.Server.SecureCookies))
+	}
+
+	// zerolog treats an empty level as "no level"
+	if _, err := zerolog.ParseLevel(c.LogLevel); err != nil || c.LogLevel == "" {
+		problems = append(problems, fmt.Errorf("log level %q is not one of trace, debug, info, warn, error, fatal, panic or disabled", c.LogLevel))
+	}
+
+	if c.Signup != OpenSignup && c.Signup != ClosedSignup {
+		problems = append(problems, fmt.Errorf("signup %q is not one of %s or %s", c.Signup, OpenSignup, ClosedSignup))
+	}
+
+	return errors.Join(problems...)
+}
+
+func (c *Config) UseTls() bool {
+	return c.Server.TlsCertFile != "" && c.Server.TlsKeyFile != ""
+}
+
+func (c *Config) UseSecureCookies() bool {
+	secure, _ := strconv.ParseBool(c.Server.SecureCookies)
+	return secure
+}
+
+func (c *Config) ZerologLevel() zerolog.Level {
+	level, _ := zerolog.ParseLevel(c.LogLevel)
+	return level
+}
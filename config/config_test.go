@@ -0,0 +1,101 @@
+package config_test
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ThomasMatlak/food/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := config.Load(flag.NewFlagSet("food", flag.ContinueOnError), []string{}, env(nil))
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(config.Default(), *cfg)
+	assert.False(cfg.UseTls())
+	assert.True(cfg.UseSecureCookies(), "a proxy may be terminating TLS")
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(configFile, []byte(`{
+		"neo4j": {"uri": "neo4j://file:7687", "database": "file-db"},
+		"server": {"address": ":1111"},
+		"log_level": "warn"
+	}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load(
+		flag.NewFlagSet("food", flag.ContinueOnError),
+		[]string{"-listen-address", ":3333"},
+		env(map[string]string{"FOOD_CONFIG": configFile, "FOOD_LISTEN_ADDRESS": ":2222", "FOOD_NEO4J_DATABASE": "env-db"}),
+	)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal("neo4j://file:7687", cfg.Neo4j.Uri, "file overrides defaults")
+	assert.Equal("env-db", cfg.Neo4j.Database, "env overrides file")
+	assert.Equal(":3333", cfg.Server.Address, "flags override env")
+	assert.Equal("warn", cfg.LogLevel)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	type testCase struct {
+		name string
+		args []string
+		env  map[string]string
+	}
+
+	testCases := []testCase{
+		{name: "Unknown flag", args: []string{"-port", "80"}},
+		{name: "Missing config file", env: map[string]string{"FOOD_CONFIG": filepath.Join(t.TempDir(), "missing.json")}},
+		{name: "Unsupported uri scheme", args: []string{"-neo4j-uri", "http://localhost:7474"}},
+		{name: "Username without password", args: []string{"-neo4j-username", "neo4j"}},
+		{name: "Empty listen address", env: map[string]string{"FOOD_LISTEN_ADDRESS": " "}},
+		{name: "Cert without key", args: []string{"-tls-cert-file", "cert.pem"}},
+		{name: "Unknown log level", args: []string{"-log-level", "loud"}},
+		{name: "Unknown storage", args: []string{"--storage=files"}},
+		{name: "Unknown signup", args: []string{"-signup", "invite-only"}},
+		{name: "Unknown secure cookies", args: []string{"-secure-cookies", "sometimes"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := config.Load(flag.NewFlagSet("food", flag.ContinueOnError), tc.args, env(tc.env))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestLoadWithCallerFlags(t *testing.T) {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "")
+
+	cfg, err := config.Load(flags, []string{"-dry-run", "-neo4j-database", "flag-db"}, env(nil))
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.True(*dryRun)
+	assert.Equal("flag-db", cfg.Neo4j.Database)
+}
+
+func TestValidateReportsEveryProblem(t *testing.T) {
+	cfg := config.Default()
+	cfg.Neo4j.Uri = "localhost"
+	cfg.LogLevel = "loud"
+
+	err := cfg.Validate()
+
+	assert.ErrorContains(t, err, "neo4j uri")
+	assert.ErrorContains(t, err, "log level")
+}
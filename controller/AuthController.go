@@ -0,0 +1,424 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/auth"
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+)
+
+const sessionCookie = "food_session"
+const sessionLifetime = 30 * 24 * time.Hour
+
+type AuthController struct {
+	userRepository model.UserRepository
+	// anyone may sign up; otherwise only the first user can
+	openSignup bool
+	// whether the session cookie is only sent over https
+	secureCookies bool
+}
+
+func NewAuthController(userRepository model.UserRepository, openSignup bool, secureCookies bool) *AuthController {
+	return &AuthController{userRepository: userRepository, openSignup: openSignup, secureCookies: secureCookies}
+}
+
+func (ac *AuthController) AuthRoutes(router chi.Router) {
+	router.Get("/login", ac.loginForm)
+	router.Post("/login", ac.login)
+	router.Post("/logout", ac.logout)
+	router.Get("/signup", ac.signupForm)
+	router.Post("/signup", ac.signup)
+
+	router.Route("/account", func(r chi.Router) {
+		r.Use(RequireUser)
+		r.Get("/", ac.account)
+		r.Post("/api-keys", ac.createApiKey)
+		r.Delete("/api-keys/{id}", ac.deleteApiKey)
+	})
+}
+
+// Authenticate puts the user signed in with an API key or a session cookie into the request's context.
+// Anonymous requests carry on without one; a bearer token that doesn't match a key is refused outright.
+func (ac *AuthController) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if header := r.Header.Get("Authorization"); header != "" {
+			token, ok := auth.BearerToken(header)
+			if !ok {
+				unauthorized(w, r)
+				return
+			}
+			user, found, err := ac.userRepository.GetApiKeyUser(r.Context(), auth.HashToken(token))
+			if err != nil {
+				httpError(w, r, err.Error(), http.StatusInternalServerError)
+				return
+			} else if !found {
+				unauthorized(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithUser(r.Context(), user)))
+			return
+		}
+
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			user, found, err := ac.userRepository.GetSessionUser(r.Context(), auth.HashToken(cookie.Value))
+			if err != nil {
+				httpError(w, r, err.Error(), http.StatusInternalServerError)
+				return
+			} else if found {
+				r = r.WithContext(auth.WithUser(r.Context(), user))
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireUser refuses anonymous requests
+func RequireUser(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth.UserFrom(r.Context()) == nil {
+			unauthorized(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RefuseCrossSite stops other sites from posting forms here, in place of CSRF tokens.
+// Browsers say where a request came from in Sec-Fetch-Site, or failing that Origin, and a page can't forge either.
+// Requests with an API key carry no cookie a browser would add on its own, and clients other than browsers send neither header.
+func RefuseCrossSite(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") == "" && crossSite(r) {
+			httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func crossSite(r *http.Request) bool {
+	switch r.Header.Get("Sec-Fetch-Site") {
+	case "same-origin", "none":
+		return false
+	case "":
+	default:
+		return true
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return false
+	}
+	originUrl, err := url.Parse(origin)
+	return err != nil || originUrl.Host != r.Host
+}
+
+// RequireUserForWrites lets anyone read, but only signed in users change anything.
+// The session cookie is SameSite=Lax and RefuseCrossSite turns away other sites' forms, so they can't make writes with it.
+func RequireUserForWrites(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+		default:
+			RequireUser(next).ServeHTTP(w, r)
+		}
+	})
+}
+
+// RequireRole refuses anonymous requests, and those from users without the role or one above it
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user := auth.UserFrom(r.Context())
+			if user == nil {
+				unauthorized(w, r)
+				return
+			} else if !user.HasRole(role) {
+				httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unauthorized sends API clients a 401, and people to the login page
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	if wantsJson(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="food"`)
+		httpError(w, r, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	login := "/login"
+	if r.Method == http.MethodGet {
+		login += "?" + url.Values{"next": {r.URL.RequestURI()}}.Encode()
+	}
+	if r.Header.Get("HX-Request") == "true" {
+		// htmx would swap a redirect's page into the target instead of following it
+		w.Header().Set("HX-Redirect", login)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, login, http.StatusSeeOther)
+}
+
+// safeNext only allows redirecting within the site after logging in
+func safeNext(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, `/\`) {
+		return "/recipe"
+	}
+	return next
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func (ac *AuthController) signupOpen(r *http.Request) (bool, error) {
+	if ac.openSignup {
+		return true, nil
+	}
+	hasUsers, err := ac.userRepository.HasUsers(r.Context())
+	return !hasUsers, err
+}
+
+func (ac *AuthController) loginForm(w http.ResponseWriter, r *http.Request) {
+	signupOpen, err := ac.signupOpen(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	templ.Handler(response.Login(r.URL.Query().Get("next"), "", signupOpen)).ServeHTTP(w, r)
+}
+
+func (ac *AuthController) login(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	next := r.PostForm.Get("next")
+
+	user, found, err := ac.userRepository.GetByUsername(r.Context(), normalizeUsername(r.PostForm.Get("username")))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		user = nil
+	}
+	if !auth.CheckUserPassword(user, r.PostForm.Get("password")) {
+		signupOpen, err := ac.signupOpen(r)
+		if err != nil {
+			httpError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		templ.Handler(response.Login(next, "Wrong username or password", signupOpen), templ.WithStatus(http.StatusUnauthorized)).ServeHTTP(w, r)
+		return
+	}
+
+	if err := ac.startSession(w, r, user); err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, safeNext(next), http.StatusSeeOther)
+}
+
+func (ac *AuthController) startSession(w http.ResponseWriter, r *http.Request, user *model.User) error {
+	token, hash, err := auth.NewToken("")
+	if err != nil {
+		return err
+	}
+	expires := time.Now().Add(sessionLifetime)
+	if err := ac.userRepository.CreateSession(r.Context(), user.Id, hash, expires); err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   ac.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func (ac *AuthController) logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		if err := ac.userRepository.DeleteSession(r.Context(), auth.HashToken(cookie.Value)); err != nil {
+			httpError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   ac.secureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (ac *AuthController) signupForm(w http.ResponseWriter, r *http.Request) {
+	signupOpen, err := ac.signupOpen(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !signupOpen {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+	templ.Handler(response.Signup("")).ServeHTTP(w, r)
+}
+
+func (ac *AuthController) signup(w http.ResponseWriter, r *http.Request) {
+	signupOpen, err := ac.signupOpen(r)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !signupOpen {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	username := normalizeUsername(r.PostForm.Get("username"))
+	if username == "" {
+		templ.Handler(response.Signup("Username is required"), templ.WithStatus(http.StatusUnprocessableEntity)).ServeHTTP(w, r)
+		return
+	}
+	passwordHash, err := auth.HashPassword(r.PostForm.Get("password"))
+	if err != nil {
+		templ.Handler(response.Signup(err.Error()), templ.WithStatus(http.StatusUnprocessableEntity)).ServeHTTP(w, r)
+		return
+	}
+
+	// the first user sets the site up, so they get to administer it. Checking for them and creating them happen together,
+	// since two signups at once could otherwise both be first.
+	user, first, err := ac.userRepository.CreateFirst(r.Context(), model.User{Username: username, PasswordHash: passwordHash, Role: model.AdminRole})
+	if err == nil && !first {
+		if !ac.openSignup {
+			httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+			return
+		}
+		user, err = ac.userRepository.Create(r.Context(), model.User{Username: username, PasswordHash: passwordHash, Role: model.CookRole})
+	}
+	if errors.Is(err, model.ErrUsernameTaken) {
+		templ.Handler(response.Signup("That username is taken"), templ.WithStatus(http.StatusConflict)).ServeHTTP(w, r)
+		return
+	} else if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if err := ac.startSession(w, r, user); err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/recipe", http.StatusSeeOther)
+}
+
+func (ac *AuthController) account(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFrom(r.Context())
+
+	keys, err := ac.userRepository.GetApiKeys(r.Context(), user.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.GetAccountResponse{User: user, ApiKeys: keys})
+	} else {
+		templ.Handler(response.Account(user, keys, nil)).ServeHTTP(w, r)
+	}
+}
+
+func (ac *AuthController) createApiKey(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFrom(r.Context())
+
+	var createRequest struct {
+		Name string `json:"name"`
+	}
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(r.PostForm) == 0 {
+		json.NewDecoder(r.Body).Decode(&createRequest)
+	} else {
+		createRequest.Name = r.PostForm.Get("name")
+	}
+	name := strings.TrimSpace(createRequest.Name)
+	if name == "" {
+		httpError(w, r, "name is required", http.StatusUnprocessableEntity)
+		return
+	}
+
+	token, hash, err := auth.NewToken(auth.ApiKeyPrefix)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	key, err := ac.userRepository.CreateApiKey(r.Context(), user.Id, name, hash)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	created := response.CreateApiKeyResponse{ApiKey: *key, Key: token}
+
+	if wantsJson(r) {
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(created)
+		return
+	}
+
+	keys, err := ac.userRepository.GetApiKeys(r.Context(), user.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// the key can only be shown in this response, so the page is rendered instead of redirecting
+	w.Header().Set("Cache-Control", "no-store")
+	templ.Handler(response.Account(user, keys, &created)).ServeHTTP(w, r)
+}
+
+func (ac *AuthController) deleteApiKey(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFrom(r.Context())
+	id := chi.URLParam(r, "id")
+
+	deleted, err := ac.userRepository.DeleteApiKey(r.Context(), user.Id, id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !deleted {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.DeleteApiKeyResponse{Id: id})
+	}
+}
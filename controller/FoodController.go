@@ -4,11 +4,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
+	"github.com/ThomasMatlak/food/auth"
 	"github.com/ThomasMatlak/food/controller/request"
 	"github.com/ThomasMatlak/food/controller/response"
 	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/validation"
 	"github.com/a-h/templ"
 	"github.com/go-chi/chi/v5"
 )
@@ -22,29 +26,31 @@ func NewFoodController(foodRepository model.FoodRepository) *FoodController {
 }
 
 func (ic *FoodController) FoodRoutes(router chi.Router) {
+	// cooks can add their own private foods; the handlers check that only curators change the catalog
+	cook := RequireRole(model.CookRole)
 	router.Route("/food", func(r chi.Router) {
-		// TODO search
-		r.Post("/", ic.createFood)
+		r.With(cook).Post("/", ic.createFood)
 		r.Get("/", ic.allFoods)
+		r.Get("/search", ic.searchFoods)
 
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", ic.getFood)
-			r.Put("/", ic.replaceFood)
-			r.Patch("/", ic.updateFood)
-			r.Delete("/", ic.deleteFood)
+			r.With(cook).Put("/", ic.replaceFood)
+			r.With(cook).Patch("/", ic.updateFood)
+			r.With(cook).Delete("/", ic.deleteFood)
 		})
 
 		r.Route("/create", func(r chi.Router) {
-			r.Get("/", ic.createFoodForm)
+			r.With(cook).Get("/", ic.createFoodForm)
 		})
 		r.Route("/{id}/edit", func(r chi.Router) {
-			r.Get("/", ic.editFoodForm)
+			r.With(cook).Get("/", ic.editFoodForm)
 		})
 	})
 }
 
 func (ic *FoodController) createFoodForm(w http.ResponseWriter, r *http.Request) {
-	component := response.CreateFood()
+	component := response.CreateFood(&model.Food{}, nil)
 	templ.Handler(component).ServeHTTP(w, r)
 }
 
@@ -53,30 +59,100 @@ func (ic *FoodController) editFoodForm(w http.ResponseWriter, r *http.Request) {
 
 	food, found, err := ic.foodRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !food.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditFood(*food) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
-	component := response.EditFoodForm(food)
+	component := response.EditFoodForm(food, nil)
 	templ.Handler(component).ServeHTTP(w, r)
 }
 
 func (ic *FoodController) allFoods(w http.ResponseWriter, r *http.Request) {
-	foods, err := ic.foodRepository.GetAll(r.Context())
+	listQuery, err := listQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// TODO pagination
-	if r.Header.Get("Accept") == "application/json" {
-		response := response.GetFoodsResponse{Foods: foods}
+	foods, err := ic.foodRepository.GetAll(r.Context(), listQuery)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	next := nextPageUrl(r.URL.Path, r.URL.Query(), listQuery.PageRequest, foods.NextCursor)
+
+	if wantsJson(r) {
+		response := response.GetFoodsResponse{Foods: foods.Items, Next: next}
+		json.NewEncoder(w).Encode(response)
+	} else if r.Header.Get("HX-Request") == "true" {
+		// "load more" only needs the next rows
+		templ.Handler(response.FoodRows(foods.Items, next)).ServeHTTP(w, r)
+	} else {
+		templ.Handler(response.ViewFoods(foods.Items, next)).ServeHTTP(w, r)
+	}
+}
+
+func (ic *FoodController) searchFoods(w http.ResponseWriter, r *http.Request) {
+	text := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	limit, ok := pageSize(r)
+	if !ok {
+		httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	offset, err := intQueryParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	// the recipe form's food picker wants <option>s instead of table rows
+	optionsOnly := r.URL.Query().Get("format") == "options"
+
+	// clearing the live search box shows every food again
+	if text == "" && !wantsJson(r) && !optionsOnly {
+		listQuery := model.ListQuery{Viewer: viewer(r), PageRequest: model.PageRequest{Limit: limit}}
+		foods, err := ic.foodRepository.GetAll(r.Context(), listQuery)
+		if err != nil {
+			httpError(w, r, err.Error(), 500)
+			return
+		}
+		next := nextPageUrl("/food", url.Values{}, listQuery.PageRequest, foods.NextCursor)
+		templ.Handler(response.FoodRows(foods.Items, next)).ServeHTTP(w, r)
+		return
+	}
+
+	// fetch one extra result to know whether there is another page
+	results, err := ic.foodRepository.Search(r.Context(), model.FoodSearch{Text: text, Viewer: viewer(r), Skip: offset, Limit: limit + 1})
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	var next *string
+	if len(results) > limit {
+		results = results[:limit]
+		nextUrl := fmt.Sprintf("/food/search?%s", url.Values{
+			"q":      {text},
+			"limit":  {strconv.Itoa(limit)},
+			"offset": {strconv.Itoa(offset + limit)},
+		}.Encode())
+		next = &nextUrl
+	}
+
+	if wantsJson(r) {
+		response := response.FoodSearchResponse{Results: results, Next: next}
 		json.NewEncoder(w).Encode(response)
+	} else if optionsOnly {
+		templ.Handler(response.FoodOptions(results)).ServeHTTP(w, r)
 	} else {
-		templ.Handler(response.ViewFoods(foods)).ServeHTTP(w, r)
+		templ.Handler(response.FoodSearchResults(results, next)).ServeHTTP(w, r)
 	}
 }
 
@@ -85,14 +161,14 @@ func (ic *FoodController) getFood(w http.ResponseWriter, r *http.Request) {
 
 	food, found, err := ic.foodRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !food.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 
-	if r.Header.Get("Accept") == "application/json" {
+	if wantsJson(r) {
 		json.NewEncoder(w).Encode(food)
 	} else {
 		templ.Handler(response.GetFood(food)).ServeHTTP(w, r)
@@ -104,42 +180,82 @@ func (ic *FoodController) createFood(w http.ResponseWriter, r *http.Request) {
 
 	err := r.ParseForm()
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 	form := r.Form
 
+	var problems validation.Errors
 	if len(form) == 0 {
-		json.NewDecoder(r.Body).Decode(&createFoodRequest)
+		if !decodeJson(w, r, &createFoodRequest) {
+			return
+		}
 	} else {
-		createFoodRequest.Name = form.Get("name")
+		createFoodRequest, problems = request.ParseFoodForm(form)
+	}
+
+	problems.Merge(request.ValidateFood(&createFoodRequest))
+	if !problems.Valid() {
+		ic.invalidFood(w, r, nil, createFoodRequest, problems)
+		return
 	}
 
 	var newFood model.Food
 	newFood.Name = strings.TrimSpace(createFoodRequest.Name)
+	newFood.Density = createFoodRequest.Density
+	// curators add to the shared catalog; everyone else's foods are their own
+	if user := auth.UserFrom(r.Context()); !user.HasRole(model.CuratorRole) {
+		newFood.AuthorId = user.Id
+		newFood.Visibility = model.PrivateVisibility
+	}
 
 	food, err := ic.foodRepository.Create(r.Context(), newFood)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	if r.Header.Get("Accept") == "application/json" {
+	if wantsJson(r) {
 		json.NewEncoder(w).Encode(food)
 	} else {
 		http.Redirect(w, r, fmt.Sprint("/food/", food.Id), http.StatusSeeOther)
 	}
 }
 
+// invalidFood answers a food that didn't pass validation. API clients get the problems as JSON, and the form is shown again
+// with what was entered and what's wrong with it. existing is the food being replaced, or nil for a new one.
+func (ic *FoodController) invalidFood(w http.ResponseWriter, r *http.Request, existing *model.Food, submitted request.CreateFoodRequest, problems validation.Errors) {
+	if wantsJson(r) || len(r.Form) == 0 {
+		invalid(w, r, problems)
+		return
+	}
+
+	food := model.Food{Name: submitted.Name, Density: submitted.Density}
+	component := response.CreateFood(&food, problems)
+	if existing != nil {
+		food.Id = existing.Id
+		component = response.EditFoodForm(&food, problems)
+	}
+	status := http.StatusUnprocessableEntity
+	if r.Header.Get("HX-Request") == "true" {
+		// htmx only swaps in successful responses
+		status = http.StatusOK
+	}
+	templ.Handler(component, templ.WithStatus(status)).ServeHTTP(w, r)
+}
+
 func (ic *FoodController) replaceFood(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	food, found, err := ic.foodRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
+		return
+	} else if !found || !food.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !auth.UserFrom(r.Context()).CanEditFood(*food) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
@@ -147,26 +263,36 @@ func (ic *FoodController) replaceFood(w http.ResponseWriter, r *http.Request) {
 
 	err = r.ParseForm()
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 	form := r.Form
 
+	var problems validation.Errors
 	if len(form) == 0 {
-		json.NewDecoder(r.Body).Decode(&replaceFoodRequest)
+		if !decodeJson(w, r, &replaceFoodRequest) {
+			return
+		}
 	} else {
-		replaceFoodRequest.Name = form.Get("name")
+		replaceFoodRequest, problems = request.ParseFoodForm(form)
+	}
+
+	problems.Merge(request.ValidateFood(&replaceFoodRequest))
+	if !problems.Valid() {
+		ic.invalidFood(w, r, food, replaceFoodRequest, problems)
+		return
 	}
 
 	food.Name = strings.TrimSpace(replaceFoodRequest.Name)
+	food.Density = replaceFoodRequest.Density
 
 	updatedFood, err := ic.foodRepository.Update(r.Context(), *food)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	if r.Header.Get("Accept") == "application/json" {
+	if wantsJson(r) {
 		json.NewEncoder(w).Encode(updatedFood)
 	} else {
 		templ.Handler(response.GetFood(updatedFood)).ServeHTTP(w, r)
@@ -178,27 +304,41 @@ func (ic *FoodController) updateFood(w http.ResponseWriter, r *http.Request) {
 
 	food, found, err := ic.foodRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !food.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditFood(*food) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	var replaceFoodRequest request.UpdateFoodRequest
-	json.NewDecoder(r.Body).Decode(&replaceFoodRequest)
+	if !decodeJson(w, r, &replaceFoodRequest) {
+		return
+	}
+
+	if problems := request.ValidateUpdateFood(&replaceFoodRequest); !problems.Valid() {
+		invalid(w, r, problems)
+		return
+	}
 
 	if replaceFoodRequest.Name != nil {
 		food.Name = strings.TrimSpace(*replaceFoodRequest.Name)
 	}
 
+	if replaceFoodRequest.Density != nil {
+		food.Density = replaceFoodRequest.Density
+	}
+
 	updatedFood, err := ic.foodRepository.Update(r.Context(), *food)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	if r.Header.Get("Accept") == "application/json" {
+	if wantsJson(r) {
 		json.NewEncoder(w).Encode(updatedFood)
 	}
 }
@@ -208,20 +348,23 @@ func (ic *FoodController) deleteFood(w http.ResponseWriter, r *http.Request) {
 
 	food, found, err := ic.foodRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
+		return
+	} else if !found || !food.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !auth.UserFrom(r.Context()).CanEditFood(*food) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	deletedId, err := ic.foodRepository.Delete(r.Context(), food.Id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	if r.Header.Get("Accept") == "application/json" {
+	if wantsJson(r) {
 		deleteFoodResponse := response.DeleteFoodResponse{Id: deletedId}
 		json.NewEncoder(w).Encode(deleteFoodResponse)
 	}
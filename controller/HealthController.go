@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/go-chi/chi/v5"
+)
+
+// how long a readiness check may take before the load balancer should treat it as failed
+const readyTimeout = 2 * time.Second
+
+type HealthController struct {
+	healthRepository model.HealthRepository
+	shuttingDown     atomic.Bool
+}
+
+func NewHealthController(healthRepository model.HealthRepository) *HealthController {
+	return &HealthController{healthRepository: healthRepository}
+}
+
+func (hc *HealthController) HealthRoutes(router *chi.Mux) {
+	router.Get("/healthz", hc.healthz)
+	router.Get("/readyz", hc.readyz)
+	router.Get("/version", hc.version)
+}
+
+// ShuttingDown makes readiness fail, so the load balancer stops sending new requests while the server drains
+func (hc *HealthController) ShuttingDown() {
+	hc.shuttingDown.Store(true)
+}
+
+func (hc *HealthController) healthz(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(response.HealthResponse{Status: "ok"})
+}
+
+func (hc *HealthController) readyz(w http.ResponseWriter, r *http.Request) {
+	if hc.shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(response.HealthResponse{Status: "unavailable", Error: "shutting down"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
+	if err := hc.healthRepository.Ready(ctx); err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(response.HealthResponse{Status: "unavailable", Error: err.Error()})
+		return
+	}
+
+	json.NewEncoder(w).Encode(response.HealthResponse{Status: "ok"})
+}
+
+func (hc *HealthController) version(w http.ResponseWriter, r *http.Request) {
+	buildInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		httpError(w, r, "build info is not available", http.StatusInternalServerError)
+		return
+	}
+
+	versionResponse := response.VersionResponse{
+		Path:      buildInfo.Main.Path,
+		Version:   buildInfo.Main.Version,
+		GoVersion: buildInfo.GoVersion,
+	}
+	for _, setting := range buildInfo.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			versionResponse.Revision = setting.Value
+		case "vcs.time":
+			versionResponse.RevisionTime = setting.Value
+		case "vcs.modified":
+			versionResponse.Modified = setting.Value == "true"
+		}
+	}
+	json.NewEncoder(w).Encode(versionResponse)
+}
@@ -0,0 +1,405 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ThomasMatlak/food/auth"
+	"github.com/ThomasMatlak/food/controller/request"
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/a-h/templ"
+	"github.com/go-chi/chi/v5"
+)
+
+type HouseholdController struct {
+	householdRepository model.HouseholdRepository
+	// invitations name the user by username
+	userRepository     model.UserRepository
+	listItemRepository model.ListItemRepository
+	// only foods the user can see go on a list
+	foodRepository model.FoodRepository
+}
+
+func NewHouseholdController(householdRepository model.HouseholdRepository, userRepository model.UserRepository, listItemRepository model.ListItemRepository, foodRepository model.FoodRepository) *HouseholdController {
+	return &HouseholdController{householdRepository: householdRepository, userRepository: userRepository, listItemRepository: listItemRepository, foodRepository: foodRepository}
+}
+
+func (hc *HouseholdController) HouseholdRoutes(router chi.Router) {
+	router.Route("/household", func(r chi.Router) {
+		r.Use(RequireUser)
+		// viewers can join a household they're invited to, and read its pantry and shopping list, but not change anything
+		cook := RequireRole(model.CookRole)
+		r.Get("/", hc.households)
+		r.With(cook).Post("/", hc.createHousehold)
+
+		r.Post("/invitations/{id}/accept", hc.acceptInvitation)
+		r.Delete("/invitations/{id}", hc.declineInvitation)
+
+		r.Route("/{id}", func(r chi.Router) {
+			r.Get("/", hc.getHousehold)
+			r.With(cook).Post("/invitations", hc.invite)
+			r.Post("/leave", hc.leaveHousehold)
+
+			r.Route(fmt.Sprintf("/{list:%s}", strings.Join(model.Lists, "|")), func(r chi.Router) {
+				r.Get("/", hc.listItems)
+				r.With(cook).Post("/", hc.addListItem)
+				r.With(cook).Patch("/{itemId}", hc.updateListItem)
+				r.With(cook).Delete("/{itemId}", hc.deleteListItem)
+			})
+		})
+	})
+}
+
+// formValue reads a field from a form, or from a JSON object when the request isn't a form.
+// A body it can't read is refused with a 400 and false.
+func formValue(w http.ResponseWriter, r *http.Request, field string) (string, bool) {
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return "", false
+	}
+	if len(r.PostForm) > 0 {
+		return r.PostForm.Get(field), true
+	}
+	body := map[string]string{}
+	if !decodeJson(w, r, &body) {
+		return "", false
+	}
+	return body[field], true
+}
+
+// memberHousehold returns the household with the id in the path, treating one the user isn't a member of as not found
+func (hc *HouseholdController) memberHousehold(w http.ResponseWriter, r *http.Request) (*model.Household, bool) {
+	household, found, err := hc.householdRepository.GetById(r.Context(), chi.URLParam(r, "id"), auth.UserFrom(r.Context()).Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	} else if !found {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, false
+	}
+	return household, true
+}
+
+func (hc *HouseholdController) households(w http.ResponseWriter, r *http.Request) {
+	user := auth.UserFrom(r.Context())
+
+	households, err := hc.householdRepository.GetForUser(r.Context(), user.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	invitations, err := hc.householdRepository.GetInvitations(r.Context(), user.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.GetHouseholdsResponse{Households: households, Invitations: invitations})
+	} else {
+		templ.Handler(response.Households(households, invitations)).ServeHTTP(w, r)
+	}
+}
+
+func (hc *HouseholdController) createHousehold(w http.ResponseWriter, r *http.Request) {
+	name, ok := formValue(w, r, "name")
+	if !ok {
+		return
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		httpError(w, r, "name is required", http.StatusUnprocessableEntity)
+		return
+	}
+
+	household, err := hc.householdRepository.Create(r.Context(), model.Household{Name: name}, auth.UserFrom(r.Context()).Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(household)
+	} else {
+		http.Redirect(w, r, fmt.Sprint("/household/", household.Id), http.StatusSeeOther)
+	}
+}
+
+func (hc *HouseholdController) getHousehold(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(household)
+	} else {
+		templ.Handler(response.GetHousehold(household, "")).ServeHTTP(w, r)
+	}
+}
+
+// invite asks a user to join the household. Inviting a member, or someone already invited, does nothing.
+func (hc *HouseholdController) invite(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	username, ok := formValue(w, r, "username")
+	if !ok {
+		return
+	}
+	invited, found, err := hc.userRepository.GetByUsername(r.Context(), normalizeUsername(username))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !found {
+		if wantsJson(r) {
+			httpError(w, r, "no user has that username", http.StatusUnprocessableEntity)
+		} else {
+			templ.Handler(response.GetHousehold(household, "No user has that username"), templ.WithStatus(http.StatusUnprocessableEntity)).ServeHTTP(w, r)
+		}
+		return
+	}
+
+	if err := hc.householdRepository.Invite(r.Context(), household.Id, invited.Id, auth.UserFrom(r.Context()).Id); err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(household)
+	} else {
+		http.Redirect(w, r, fmt.Sprint("/household/", household.Id), http.StatusSeeOther)
+	}
+}
+
+func (hc *HouseholdController) acceptInvitation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	accepted, err := hc.householdRepository.Accept(r.Context(), auth.UserFrom(r.Context()).Id, id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !accepted {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.InvitationResponse{Id: id})
+	} else {
+		http.Redirect(w, r, "/household", http.StatusSeeOther)
+	}
+}
+
+func (hc *HouseholdController) declineInvitation(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	declined, err := hc.householdRepository.Decline(r.Context(), auth.UserFrom(r.Context()).Id, id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !declined {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.InvitationResponse{Id: id})
+	}
+}
+
+// leaveHousehold takes the user out of the household. Recipes they shared with it stay there.
+func (hc *HouseholdController) leaveHousehold(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+
+	left, err := hc.householdRepository.Leave(r.Context(), household.Id, auth.UserFrom(r.Context()).Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	} else if !left {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.LeaveHouseholdResponse{Id: household.Id})
+	} else {
+		http.Redirect(w, r, "/household", http.StatusSeeOther)
+	}
+}
+
+func (hc *HouseholdController) listItems(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+	list := chi.URLParam(r, "list")
+
+	items, err := hc.listItemRepository.GetForHousehold(r.Context(), household.Id, auth.UserFrom(r.Context()).Id, list)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.GetListItemsResponse{Items: items})
+	} else {
+		templ.Handler(response.HouseholdList(household, list, items, nil)).ServeHTTP(w, r)
+	}
+}
+
+func (hc *HouseholdController) addListItem(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+	list := chi.URLParam(r, "list")
+
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var createRequest request.CreateListItemRequest
+	if len(r.Form) == 0 {
+		if !decodeJson(w, r, &createRequest) {
+			return
+		}
+	} else {
+		createRequest = request.ParseListItemForm(r.Form)
+	}
+
+	problems := request.ValidateCreateListItem(&createRequest)
+	if problems.Valid() {
+		food, found, err := hc.foodRepository.GetById(r.Context(), createRequest.FoodId)
+		if err != nil {
+			httpError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		} else if !found || !food.VisibleTo(viewer(r)) {
+			problems.Add("food_id", "There's no such food")
+		}
+	}
+	if !problems.Valid() {
+		if wantsJson(r) || len(r.Form) == 0 {
+			invalid(w, r, problems)
+			return
+		}
+		items, err := hc.listItemRepository.GetForHousehold(r.Context(), household.Id, auth.UserFrom(r.Context()).Id, list)
+		if err != nil {
+			httpError(w, r, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		status := http.StatusUnprocessableEntity
+		if r.Header.Get("HX-Request") == "true" {
+			// htmx only swaps in successful responses
+			status = http.StatusOK
+		}
+		templ.Handler(response.HouseholdList(household, list, items, problems), templ.WithStatus(status)).ServeHTTP(w, r)
+		return
+	}
+
+	item, err := hc.listItemRepository.Create(r.Context(), model.ListItem{
+		HouseholdId: household.Id,
+		List:        list,
+		FoodId:      createRequest.FoodId,
+		Quantity:    strings.TrimSpace(createRequest.Quantity),
+	}, auth.UserFrom(r.Context()).Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(item)
+	} else {
+		http.Redirect(w, r, fmt.Sprintf("/household/%s/%s", household.Id, list), http.StatusSeeOther)
+	}
+}
+
+// listItem returns the item with the id in the path, treating one on another list as not found
+func (hc *HouseholdController) listItem(w http.ResponseWriter, r *http.Request, household *model.Household) (*model.ListItem, bool) {
+	item, found, err := hc.listItemRepository.GetById(r.Context(), household.Id, auth.UserFrom(r.Context()).Id, chi.URLParam(r, "itemId"))
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	} else if !found || item.List != chi.URLParam(r, "list") {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return nil, false
+	}
+	return item, true
+}
+
+// updateListItem changes an item's quantity, or moves it to the other list, e.g. into the pantry once it's bought
+func (hc *HouseholdController) updateListItem(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+	item, ok := hc.listItem(w, r, household)
+	if !ok {
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		httpError(w, r, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var updateRequest request.UpdateListItemRequest
+	if len(r.Form) == 0 {
+		if !decodeJson(w, r, &updateRequest) {
+			return
+		}
+	} else {
+		updateRequest = request.ParseUpdateListItemForm(r.Form)
+	}
+
+	if problems := request.ValidateUpdateListItem(&updateRequest); !problems.Valid() {
+		invalid(w, r, problems)
+		return
+	}
+
+	if updateRequest.List != nil {
+		item.List = *updateRequest.List
+	}
+	if updateRequest.Quantity != nil {
+		item.Quantity = strings.TrimSpace(*updateRequest.Quantity)
+	}
+
+	updated, err := hc.listItemRepository.Update(r.Context(), *item, auth.UserFrom(r.Context()).Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(updated)
+	}
+}
+
+func (hc *HouseholdController) deleteListItem(w http.ResponseWriter, r *http.Request) {
+	household, ok := hc.memberHousehold(w, r)
+	if !ok {
+		return
+	}
+	item, ok := hc.listItem(w, r, household)
+	if !ok {
+		return
+	}
+
+	deletedId, err := hc.listItemRepository.Delete(r.Context(), household.Id, auth.UserFrom(r.Context()).Id, item.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(response.DeleteListItemResponse{Id: deletedId})
+	}
+}
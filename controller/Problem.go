@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/validation"
+)
+
+// wantsJson reports whether the request comes from an API client: one that accepts JSON, or signs in with an API key.
+// Every handler answers in JSON, and with problem+json errors, when this is true.
+func wantsJson(r *http.Request) bool {
+	if r.Header.Get("Authorization") != "" {
+		return true
+	}
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(accepted)
+		if err == nil && (mediaType == "application/json" || mediaType == "application/problem+json") {
+			return true
+		}
+	}
+	return false
+}
+
+// httpError is http.Error for API clients too, who get the error as RFC 7807 problem+json. Everyone else gets plain text, as before.
+func httpError(w http.ResponseWriter, r *http.Request, detail string, status int) {
+	writeProblem(w, r, response.Problem{Status: status, Detail: detail})
+}
+
+// invalid refuses a request whose fields didn't pass validation, listing each problem
+func invalid(w http.ResponseWriter, r *http.Request, problems validation.Errors) {
+	writeProblem(w, r, response.Problem{Status: http.StatusUnprocessableEntity, Detail: "The request has invalid fields", Errors: problems})
+}
+
+// decodeJson reads the request's JSON body into v. A body that isn't JSON is refused with a 400 and false; an empty body leaves v as it is.
+func decodeJson(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		httpError(w, r, "The body isn't valid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeProblem(w http.ResponseWriter, r *http.Request, problem response.Problem) {
+	problem.Title = http.StatusText(problem.Status)
+	if problem.Detail == problem.Title {
+		problem.Detail = ""
+	}
+
+	if !wantsJson(r) {
+		text := problem.Title
+		if len(problem.Errors) > 0 {
+			text = problem.Errors.Error()
+		} else if problem.Detail != "" {
+			text = problem.Detail
+		}
+		http.Error(w, text, problem.Status)
+		return
+	}
+
+	// every problem here is described by its status code and detail, so there are no problem types of our own
+	problem.Type = "about:blank"
+	problem.Instance = r.URL.Path
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
@@ -2,72 +2,317 @@ package controller
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/ThomasMatlak/food/auth"
 	"github.com/ThomasMatlak/food/controller/request"
 	"github.com/ThomasMatlak/food/controller/response"
 	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/validation"
+	"github.com/a-h/templ"
 	"github.com/go-chi/chi/v5"
 )
 
 type RecipeController struct {
-	recipeRepository model.RecipeRepository
+	recipeRepository    model.RecipeRepository
+	householdRepository model.HouseholdRepository
+	// names the ingredients when a form is shown again
+	foodRepository model.FoodRepository
 }
 
-func NewRecipeController(recipeRepository model.RecipeRepository) *RecipeController {
-	return &RecipeController{recipeRepository: recipeRepository}
+func NewRecipeController(recipeRepository model.RecipeRepository, householdRepository model.HouseholdRepository, foodRepository model.FoodRepository) *RecipeController {
+	return &RecipeController{recipeRepository: recipeRepository, householdRepository: householdRepository, foodRepository: foodRepository}
 }
 
-func (rc *RecipeController) RecipeRoutes(router *chi.Mux) {
+func (rc *RecipeController) RecipeRoutes(router chi.Router) {
+	// viewers can only read; the handlers check that cooks only change their own recipes
+	cook := RequireRole(model.CookRole)
 	router.Route("/recipe", func(r chi.Router) {
-		// TODO search
-		r.Post("/", rc.createRecipe)
+		r.With(cook).Post("/", rc.createRecipe)
 		r.Get("/", rc.allRecipes)
+		r.Get("/search", rc.searchRecipes)
+		r.Get("/ingredient-row", rc.ingredientRow)
 
 		r.Route("/{id}", func(r chi.Router) {
 			r.Get("/", rc.getRecipe)
-			r.Put("/", rc.replaceRecipe)
-			r.Patch("/", rc.updateRecipe)
-			r.Delete("/", rc.deleteRecipe)
+			r.With(cook).Put("/", rc.replaceRecipe)
+			r.With(cook).Patch("/", rc.updateRecipe)
+			r.With(cook).Delete("/", rc.deleteRecipe)
+			r.Get("/nutrition", rc.getRecipeNutrition)
+		})
+
+		r.Route("/create", func(r chi.Router) {
+			r.With(cook).Get("/", rc.createRecipeForm)
+		})
+		r.Route("/{id}/edit", func(r chi.Router) {
+			r.With(cook).Get("/", rc.editRecipeForm)
 		})
 	})
 }
 
+// households returns the households the signed in user can share recipes with
+func (rc *RecipeController) households(r *http.Request) ([]model.Household, error) {
+	return rc.householdRepository.GetForUser(r.Context(), auth.UserFrom(r.Context()).Id)
+}
+
+// canShareWith reports whether the signed in user may put a recipe in the household, where nil and empty need no membership
+func canShareWith(r *http.Request, householdId *string) bool {
+	return householdId == nil || *householdId == "" || auth.UserFrom(r.Context()).InHousehold(*householdId)
+}
+
+func (rc *RecipeController) createRecipeForm(w http.ResponseWriter, r *http.Request) {
+	households, err := rc.households(r)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	component := response.CreateRecipe(&model.Recipe{Visibility: model.PrivateVisibility, Ingredients: []model.ContainsIngredient{{}}}, households, nil)
+	templ.Handler(component).ServeHTTP(w, r)
+}
+
+func (rc *RecipeController) editRecipeForm(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditRecipe(*recipe) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	households, err := rc.households(r)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	component := response.EditRecipeForm(recipe, households, nil)
+	templ.Handler(component).ServeHTTP(w, r)
+}
+
+// ingredientRow is a blank row for the recipe form's "Add Ingredient" button
+func (rc *RecipeController) ingredientRow(w http.ResponseWriter, r *http.Request) {
+	component := response.IngredientRow(model.ContainsIngredient{})
+	templ.Handler(component).ServeHTTP(w, r)
+}
+
 func (rc *RecipeController) allRecipes(w http.ResponseWriter, r *http.Request) {
-	recipes, err := rc.recipeRepository.GetAll(r.Context())
+	listQuery, err := listQuery(r)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// TODO pagination
-	response := response.GetRecipesResponse{Recipes: recipes}
-	json.NewEncoder(w).Encode(response)
+	recipes, err := rc.recipeRepository.GetAll(r.Context(), listQuery)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	next := nextPageUrl(r.URL.Path, r.URL.Query(), listQuery.PageRequest, recipes.NextCursor)
+
+	if wantsJson(r) {
+		response := response.GetRecipesResponse{Recipes: recipes.Items, Next: next}
+		json.NewEncoder(w).Encode(response)
+	} else if r.Header.Get("HX-Request") == "true" {
+		// "load more" only needs the next rows
+		templ.Handler(response.RecipeRows(recipes.Items, next)).ServeHTTP(w, r)
+	} else {
+		templ.Handler(response.ViewRecipes(recipes.Items, next)).ServeHTTP(w, r)
+	}
 
 }
 
+func (rc *RecipeController) searchRecipes(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	limit, ok := pageSize(r)
+	if !ok {
+		httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	offset, err := intQueryParam(r, "offset", 0)
+	if err != nil || offset < 0 {
+		httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	search := model.RecipeSearch{
+		Text:           strings.TrimSpace(query.Get("q")),
+		IncludeFoodIds: query["include"],
+		ExcludeFoodIds: query["exclude"],
+		Viewer:         viewer(r),
+		Skip:           offset,
+		// fetch one extra result to know whether there is another page
+		Limit: limit + 1,
+	}
+	if query.Has("max_ingredients") {
+		maxIngredients, err := intQueryParam(r, "max_ingredients", 0)
+		if err != nil || maxIngredients < 0 {
+			httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		search.MaxIngredients = &maxIngredients
+	}
+
+	results, err := rc.recipeRepository.Search(r.Context(), search)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	var next *string
+	if len(results) > limit {
+		results = results[:limit]
+		query.Set("limit", strconv.Itoa(limit))
+		query.Set("offset", strconv.Itoa(offset+limit))
+		nextUrl := "/recipe/search?" + query.Encode()
+		next = &nextUrl
+	}
+
+	response := response.RecipeSearchResponse{Results: results, Next: next}
+	json.NewEncoder(w).Encode(response)
+}
+
 func (rc *RecipeController) getRecipe(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
 	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
+		return
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	rawScale := r.URL.Query().Get("scale")
+	rawServings := r.URL.Query().Get("servings")
+	if rawScale != "" && rawServings != "" {
+		httpError(w, r, "scale and servings cannot be used together", http.StatusBadRequest)
+		return
+	}
+
+	if rawScale != "" {
+		scale, err := strconv.ParseFloat(rawScale, 64)
+		// NaN and infinite multipliers make amounts that can't be encoded
+		if err != nil || scale <= 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+			httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		*recipe = recipe.Scale(scale)
+	} else if rawServings != "" {
+		servings, err := strconv.ParseInt(rawServings, 10, 64)
+		if err != nil || servings < 1 {
+			httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		if recipe.Servings == nil {
+			httpError(w, r, "recipe does not have a number of servings to scale from", http.StatusUnprocessableEntity)
+			return
+		}
+		*recipe = recipe.Scale(float64(servings) / float64(*recipe.Servings))
+	}
+
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(recipe)
+	} else {
+		templ.Handler(response.GetRecipe(recipe)).ServeHTTP(w, r)
+	}
+}
+
+func (rc *RecipeController) getRecipeNutrition(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	}
+
+	nutrition, found, err := rc.recipeRepository.GetNutrition(r.Context(), recipe.Id)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
 		return
 	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
 		return
 	}
 
-	json.NewEncoder(w).Encode(recipe)
+	// default to the recipe's own yield
+	servings := int64(1)
+	if nutrition.Servings != nil {
+		servings = *nutrition.Servings
+	}
+	if rawServings := r.URL.Query().Get("servings"); rawServings != "" {
+		parsedServings, err := strconv.ParseInt(rawServings, 10, 64)
+		if err != nil || parsedServings < 1 {
+			httpError(w, r, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+			return
+		}
+		servings = parsedServings
+	}
+
+	nutritionResponse := response.RecipeNutritionResponse{
+		RecipeId:                 nutrition.RecipeId,
+		Servings:                 servings,
+		Total:                    nutrition.Nutrients,
+		PerServing:               nutrition.PerServing(servings),
+		UnconvertedIngredientIds: nutrition.UnconvertedIngredientIds,
+	}
+	json.NewEncoder(w).Encode(nutritionResponse)
 }
 
 func (rc *RecipeController) createRecipe(w http.ResponseWriter, r *http.Request) {
 	var createRecipeRequest request.CreateRecipeRequest
-	json.NewDecoder(r.Body).Decode(&createRecipeRequest)
 
-	if !request.CanCreateRecipe(&createRecipeRequest) {
-		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+	err := r.ParseForm()
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	form := r.Form
+
+	var problems validation.Errors
+	if len(form) == 0 {
+		if !decodeJson(w, r, &createRecipeRequest) {
+			return
+		}
+	} else {
+		createRecipeRequest, problems = request.ParseRecipeForm(form)
+	}
+
+	problems.Merge(request.ValidateCreateRecipe(&createRecipeRequest))
+	// recipes stay private until their author shares them
+	visibility := model.PrivateVisibility
+	if createRecipeRequest.Visibility != "" {
+		visibility = createRecipeRequest.Visibility
+	}
+	ingredientProblems, err := rc.ingredientProblems(r, createRecipeRequest.Ingredients, visibility, nil)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	problems.Merge(ingredientProblems)
+	if !problems.Valid() {
+		rc.invalidRecipe(w, r, nil, createRecipeRequest, problems)
+		return
+	}
+	if !canShareWith(r, createRecipeRequest.HouseholdId) {
+		httpError(w, r, "not a member of that household", http.StatusForbidden)
 		return
 	}
 
@@ -75,18 +320,109 @@ func (rc *RecipeController) createRecipe(w http.ResponseWriter, r *http.Request)
 
 	newRecipe.Title = strings.TrimSpace(createRecipeRequest.Title)
 	if createRecipeRequest.Description != nil {
-		*newRecipe.Description = strings.TrimSpace(*createRecipeRequest.Description)
+		description := strings.TrimSpace(*createRecipeRequest.Description)
+		newRecipe.Description = &description
 	}
-	newRecipe.Ingredients = createRecipeRequest.Ingredients
+	newRecipe.Ingredients = request.NormalizeUnits(createRecipeRequest.Ingredients)
 	newRecipe.Steps = createRecipeRequest.Steps
+	newRecipe.Servings = createRecipeRequest.Servings
+	newRecipe.AuthorId = auth.UserFrom(r.Context()).Id
+	newRecipe.Visibility = visibility
+	if createRecipeRequest.HouseholdId != nil {
+		newRecipe.HouseholdId = *createRecipeRequest.HouseholdId
+	}
 
 	recipe, err := rc.recipeRepository.Create(r.Context(), newRecipe)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	json.NewEncoder(w).Encode(recipe)
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(recipe)
+	} else {
+		http.Redirect(w, r, fmt.Sprint("/recipe/", recipe.Id), http.StatusSeeOther)
+	}
+}
+
+// ingredientProblems checks that every ingredient is a food the user can see, so a recipe can't pass on the name and nutrients
+// of someone else's private food. Foods already in the existing recipe are kept, since household members edit each other's recipes.
+// A recipe everyone can see can't use a private food at all, even its author's own, since anyone could read the food off it.
+// It also checks that each amount is in a registered unit or one of the food's portions, like "medium" or "slice".
+// visibility is what the recipe's will be once it's saved.
+func (rc *RecipeController) ingredientProblems(r *http.Request, ingredients []model.ContainsIngredient, visibility string, existing *model.Recipe) (validation.Errors, error) {
+	kept := map[string]bool{}
+	if existing != nil {
+		for _, ci := range existing.Ingredients {
+			kept[ci.IngredientId] = true
+		}
+	}
+
+	var problems validation.Errors
+	for i, ci := range ingredients {
+		if ci.IngredientId == "" {
+			continue
+		}
+		food, found, err := rc.foodRepository.GetById(r.Context(), ci.IngredientId)
+		if err != nil {
+			return nil, err
+		} else if !found || (!kept[ci.IngredientId] && !food.VisibleTo(viewer(r))) {
+			problems.Add(validation.Item("ingredients", i, "ingredient_id"), "There's no such food")
+			continue
+		}
+		if visibility != model.PrivateVisibility && !food.VisibleTo(model.Viewer{}) {
+			problems.Add(validation.Item("ingredients", i, "ingredient_id"), "A public recipe can't use a private food")
+		}
+		if strings.TrimSpace(ci.Unit) != "" && !food.Measures(ci.Unit) {
+			problems.Add(validation.Item("ingredients", i, "unit"), fmt.Sprintf("Unknown unit %q", ci.Unit))
+		}
+	}
+	return problems, nil
+}
+
+// invalidRecipe answers a recipe that didn't pass validation. API clients get the problems as JSON, and the form is shown again
+// with what was entered and what's wrong with it. existing is the recipe being replaced, or nil for a new one.
+func (rc *RecipeController) invalidRecipe(w http.ResponseWriter, r *http.Request, existing *model.Recipe, submitted request.CreateRecipeRequest, problems validation.Errors) {
+	if wantsJson(r) || len(r.Form) == 0 {
+		invalid(w, r, problems)
+		return
+	}
+
+	households, err := rc.households(r)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+
+	recipe := model.Recipe{
+		Title:       submitted.Title,
+		Description: submitted.Description,
+		Servings:    submitted.Servings,
+		Steps:       submitted.Steps,
+		Visibility:  submitted.Visibility,
+		Ingredients: submitted.Ingredients,
+	}
+	if submitted.HouseholdId != nil {
+		recipe.HouseholdId = *submitted.HouseholdId
+	}
+	// the form only sends food ids, so the names shown in the food pickers are looked up again
+	for i, ci := range recipe.Ingredients {
+		if food, found, err := rc.foodRepository.GetById(r.Context(), ci.IngredientId); err == nil && found && food.VisibleTo(viewer(r)) {
+			recipe.Ingredients[i].IngredientName = food.Name
+		}
+	}
+
+	component := response.CreateRecipe(&recipe, households, problems)
+	if existing != nil {
+		recipe.Id = existing.Id
+		component = response.EditRecipeForm(&recipe, households, problems)
+	}
+	status := http.StatusUnprocessableEntity
+	if r.Header.Get("HX-Request") == "true" {
+		// htmx only swaps in successful responses
+		status = http.StatusOK
+	}
+	templ.Handler(component, templ.WithStatus(status)).ServeHTTP(w, r)
 }
 
 func (rc *RecipeController) replaceRecipe(w http.ResponseWriter, r *http.Request) {
@@ -94,37 +430,80 @@ func (rc *RecipeController) replaceRecipe(w http.ResponseWriter, r *http.Request
 
 	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditRecipe(*recipe) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	var replaceRecipeRequest request.CreateRecipeRequest
-	json.NewDecoder(r.Body).Decode(&replaceRecipeRequest)
 
-	if !request.CanCreateRecipe(&replaceRecipeRequest) {
-		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+	err = r.ParseForm()
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	form := r.Form
+
+	var problems validation.Errors
+	if len(form) == 0 {
+		if !decodeJson(w, r, &replaceRecipeRequest) {
+			return
+		}
+	} else {
+		replaceRecipeRequest, problems = request.ParseRecipeForm(form)
+	}
+
+	problems.Merge(request.ValidateCreateRecipe(&replaceRecipeRequest))
+	visibility := recipe.Visibility
+	if replaceRecipeRequest.Visibility != "" {
+		visibility = replaceRecipeRequest.Visibility
+	}
+	ingredientProblems, err := rc.ingredientProblems(r, replaceRecipeRequest.Ingredients, visibility, recipe)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	problems.Merge(ingredientProblems)
+	if !problems.Valid() {
+		rc.invalidRecipe(w, r, recipe, replaceRecipeRequest, problems)
+		return
+	}
+	if !canShareWith(r, replaceRecipeRequest.HouseholdId) {
+		httpError(w, r, "not a member of that household", http.StatusForbidden)
 		return
 	}
 
 	recipe.Title = strings.TrimSpace(replaceRecipeRequest.Title)
 	if replaceRecipeRequest.Description != nil {
-		*recipe.Description = strings.TrimSpace(*replaceRecipeRequest.Description)
+		description := strings.TrimSpace(*replaceRecipeRequest.Description)
+		recipe.Description = &description
 	} else {
 		recipe.Description = nil
 	}
-	recipe.Ingredients = replaceRecipeRequest.Ingredients
+	recipe.Ingredients = request.NormalizeUnits(replaceRecipeRequest.Ingredients)
 	recipe.Steps = replaceRecipeRequest.Steps
+	recipe.Servings = replaceRecipeRequest.Servings
+	recipe.Visibility = visibility
+	if replaceRecipeRequest.HouseholdId != nil {
+		recipe.HouseholdId = *replaceRecipeRequest.HouseholdId
+	}
 
 	updatedRecipe, err := rc.recipeRepository.Update(r.Context(), *recipe)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	json.NewEncoder(w).Encode(updatedRecipe)
+	if wantsJson(r) {
+		json.NewEncoder(w).Encode(updatedRecipe)
+	} else {
+		templ.Handler(response.GetRecipe(updatedRecipe)).ServeHTTP(w, r)
+	}
 }
 
 func (rc *RecipeController) updateRecipe(w http.ResponseWriter, r *http.Request) {
@@ -132,18 +511,43 @@ func (rc *RecipeController) updateRecipe(w http.ResponseWriter, r *http.Request)
 
 	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditRecipe(*recipe) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	var updateRecipeRequest request.UpdateRecipeRequest
-	json.NewDecoder(r.Body).Decode(&updateRecipeRequest)
+	if !decodeJson(w, r, &updateRecipeRequest) {
+		return
+	}
 
-	if !request.CanUpdateRecipe(&updateRecipeRequest) {
-		http.Error(w, http.StatusText(http.StatusUnprocessableEntity), http.StatusUnprocessableEntity)
+	problems := request.ValidateUpdateRecipe(&updateRecipeRequest)
+	// the ingredients are checked even when only the visibility changes, since making a recipe public shares them
+	ingredients := recipe.Ingredients
+	if updateRecipeRequest.Ingredients != nil {
+		ingredients = *updateRecipeRequest.Ingredients
+	}
+	visibility := recipe.Visibility
+	if updateRecipeRequest.Visibility != nil {
+		visibility = *updateRecipeRequest.Visibility
+	}
+	ingredientProblems, err := rc.ingredientProblems(r, ingredients, visibility, recipe)
+	if err != nil {
+		httpError(w, r, err.Error(), 500)
+		return
+	}
+	problems.Merge(ingredientProblems)
+	if !problems.Valid() {
+		invalid(w, r, problems)
+		return
+	}
+	if !canShareWith(r, updateRecipeRequest.HouseholdId) {
+		httpError(w, r, "not a member of that household", http.StatusForbidden)
 		return
 	}
 
@@ -152,20 +556,31 @@ func (rc *RecipeController) updateRecipe(w http.ResponseWriter, r *http.Request)
 	}
 
 	if updateRecipeRequest.Description != nil {
-		*recipe.Description = strings.TrimSpace(*updateRecipeRequest.Description)
+		description := strings.TrimSpace(*updateRecipeRequest.Description)
+		recipe.Description = &description
 	}
 
 	if updateRecipeRequest.Ingredients != nil {
-		recipe.Ingredients = *updateRecipeRequest.Ingredients
+		recipe.Ingredients = request.NormalizeUnits(*updateRecipeRequest.Ingredients)
 	}
 
 	if updateRecipeRequest.Steps != nil {
 		recipe.Steps = *updateRecipeRequest.Steps
 	}
 
+	if updateRecipeRequest.Servings != nil {
+		recipe.Servings = updateRecipeRequest.Servings
+	}
+
+	recipe.Visibility = visibility
+
+	if updateRecipeRequest.HouseholdId != nil {
+		recipe.HouseholdId = *updateRecipeRequest.HouseholdId
+	}
+
 	updatedRecipe, err := rc.recipeRepository.Update(r.Context(), *recipe)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
@@ -177,19 +592,24 @@ func (rc *RecipeController) deleteRecipe(w http.ResponseWriter, r *http.Request)
 
 	recipe, found, err := rc.recipeRepository.GetById(r.Context(), id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
-	} else if !found {
-		http.Error(w, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+	} else if !found || !recipe.VisibleTo(viewer(r)) {
+		httpError(w, r, http.StatusText(http.StatusNotFound), http.StatusNotFound)
+		return
+	} else if !auth.UserFrom(r.Context()).CanEditRecipe(*recipe) {
+		httpError(w, r, http.StatusText(http.StatusForbidden), http.StatusForbidden)
 		return
 	}
 
 	deletedId, err := rc.recipeRepository.Delete(r.Context(), recipe.Id)
 	if err != nil {
-		http.Error(w, err.Error(), 500)
+		httpError(w, r, err.Error(), 500)
 		return
 	}
 
-	deleteRecipeResponse := response.DeleteRecipeResponse{Id: deletedId}
-	json.NewEncoder(w).Encode(deleteRecipeResponse)
+	if wantsJson(r) {
+		deleteRecipeResponse := response.DeleteRecipeResponse{Id: deletedId}
+		json.NewEncoder(w).Encode(deleteRecipeResponse)
+	}
 }
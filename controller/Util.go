@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/ThomasMatlak/food/auth"
+	"github.com/ThomasMatlak/food/model"
+)
+
+const defaultPageSize = 20
+const maxPageSize = 100
+
+func intQueryParam(r *http.Request, name string, defaultValue int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+	return strconv.Atoi(raw)
+}
+
+func pageSize(r *http.Request) (int, bool) {
+	limit, err := intQueryParam(r, "limit", defaultPageSize)
+	if err != nil || limit < 1 || limit > maxPageSize {
+		return 0, false
+	}
+	return limit, true
+}
+
+func pageRequest(r *http.Request) (model.PageRequest, bool) {
+	limit, ok := pageSize(r)
+	if !ok {
+		return model.PageRequest{}, false
+	}
+
+	page := model.PageRequest{Limit: limit}
+	if rawCursor := r.URL.Query().Get("cursor"); rawCursor != "" {
+		cursor, err := model.DecodeCursor(rawCursor)
+		if err != nil {
+			return model.PageRequest{}, false
+		}
+		page.Cursor = cursor
+	}
+	return page, true
+}
+
+// timeQueryParam accepts RFC 3339 timestamps or plain dates
+func timeQueryParam(r *http.Request, name string) (*time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		t, err = time.ParseInLocation(time.DateOnly, raw, time.Local)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a date or an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// listQuery reads the sort, filter and paging parameters shared by the list endpoints
+func listQuery(r *http.Request) (model.ListQuery, error) {
+	page, ok := pageRequest(r)
+	if !ok {
+		return model.ListQuery{}, errors.New("limit or cursor is invalid")
+	}
+
+	sort, err := model.ParseSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		return model.ListQuery{}, err
+	}
+	// cursors only make sense for the sort order they were made for
+	if page.Cursor != nil && len(page.Cursor.Values) != len(sort) {
+		return model.ListQuery{}, errors.New("cursor does not match the sort order")
+	}
+
+	createdAfter, err := timeQueryParam(r, "created_after")
+	if err != nil {
+		return model.ListQuery{}, err
+	}
+	modifiedSince, err := timeQueryParam(r, "modified_since")
+	if err != nil {
+		return model.ListQuery{}, err
+	}
+
+	return model.ListQuery{
+		Sort:          sort,
+		CreatedAfter:  createdAfter,
+		ModifiedSince: modifiedSince,
+		NamePrefix:    r.URL.Query().Get("name_prefix"),
+		Viewer:        viewer(r),
+		PageRequest:   page,
+	}, nil
+}
+
+// viewer is whoever made the request, or an anonymous viewer
+func viewer(r *http.Request) model.Viewer {
+	return auth.UserFrom(r.Context()).Viewer()
+}
+
+// nextPageUrl keeps the other query parameters, so filters carry over to the next page
+func nextPageUrl(path string, query url.Values, page model.PageRequest, cursor *model.Cursor) *string {
+	if cursor == nil {
+		return nil
+	}
+
+	query.Set("limit", strconv.Itoa(page.Limit))
+	query.Set("cursor", cursor.Encode())
+	nextUrl := path + "?" + query.Encode()
+	return &nextUrl
+}
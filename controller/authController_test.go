@@ -0,0 +1,267 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/auth"
+	"github.com/ThomasMatlak/food/controller"
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// authRouter serves the auth routes, along with /thing, which answers with the signed in username and is protected like the food and recipe routes
+func authRouter(openSignup bool) chi.Router {
+	authController := controller.NewAuthController(repository.NewMemoryUserRepository(repository.NewMemoryStore()), openSignup, false)
+
+	router := chi.NewRouter()
+	router.Use(controller.RefuseCrossSite)
+	router.Use(authController.Authenticate)
+	router.Group(func(r chi.Router) {
+		r.Use(controller.RequireUserForWrites)
+		r.HandleFunc("/thing", func(w http.ResponseWriter, r *http.Request) {
+			if user := auth.UserFrom(r.Context()); user != nil {
+				w.Write([]byte(user.Username))
+			}
+		})
+	})
+	authController.AuthRoutes(router)
+	return router
+}
+
+func serve(router http.Handler, request *http.Request) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func postForm(path string, values url.Values, cookies ...*http.Cookie) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(values.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+func sessionCookie(recorder *httptest.ResponseRecorder) *http.Cookie {
+	for _, cookie := range recorder.Result().Cookies() {
+		if cookie.Name == "food_session" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func signup(t *testing.T, router http.Handler, username string) *http.Cookie {
+	recorder := serve(router, postForm("/signup", url.Values{"username": {username}, "password": {"correct horse battery staple"}}))
+	if recorder.Code != http.StatusSeeOther {
+		t.Fatalf("signup failed with %d: %s", recorder.Code, recorder.Body.String())
+	}
+	return sessionCookie(recorder)
+}
+
+func TestRequireUserForWrites(t *testing.T) {
+	type testCase struct {
+		name             string
+		method           string
+		headers          map[string]string
+		expectedStatus   int
+		expectedLocation string
+	}
+
+	testCases := []testCase{
+		{name: "Anonymous read", method: http.MethodGet, expectedStatus: http.StatusOK},
+		{name: "Anonymous API write", method: http.MethodDelete, headers: map[string]string{"Accept": "application/json"}, expectedStatus: http.StatusUnauthorized},
+		{name: "Anonymous form write", method: http.MethodPost, expectedStatus: http.StatusSeeOther, expectedLocation: "/login"},
+		{name: "Anonymous htmx write", method: http.MethodDelete, headers: map[string]string{"HX-Request": "true"}, expectedStatus: http.StatusUnauthorized},
+		{name: "Unknown API key", method: http.MethodGet, headers: map[string]string{"Authorization": "Bearer food_nope"}, expectedStatus: http.StatusUnauthorized},
+		{name: "Not a bearer token", method: http.MethodPost, headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz"}, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request := httptest.NewRequest(tc.method, "/thing", nil)
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			recorder := serve(authRouter(false), request)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestRefuseCrossSite(t *testing.T) {
+	type testCase struct {
+		name           string
+		method         string
+		headers        map[string]string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Same origin", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "same-origin", "Origin": "http://example.com"}, expectedStatus: http.StatusSeeOther},
+		{name: "Typed into the address bar", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "none"}, expectedStatus: http.StatusSeeOther},
+		{name: "Another site", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Origin": "https://evil.example"}, expectedStatus: http.StatusForbidden},
+		{name: "A sibling subdomain", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "same-site"}, expectedStatus: http.StatusForbidden},
+		{name: "Another site, from an older browser", method: http.MethodPost, headers: map[string]string{"Origin": "https://evil.example"}, expectedStatus: http.StatusForbidden},
+		{name: "Same origin, from an older browser", method: http.MethodPost, headers: map[string]string{"Origin": "http://example.com"}, expectedStatus: http.StatusSeeOther},
+		{name: "Sandboxed page", method: http.MethodPost, headers: map[string]string{"Origin": "null"}, expectedStatus: http.StatusForbidden},
+		{name: "Not a browser", method: http.MethodPost, expectedStatus: http.StatusSeeOther},
+		{name: "Another site's link", method: http.MethodGet, headers: map[string]string{"Sec-Fetch-Site": "cross-site"}, expectedStatus: http.StatusOK},
+		{name: "API key", method: http.MethodPost, headers: map[string]string{"Sec-Fetch-Site": "cross-site", "Authorization": "Bearer food_nope"}, expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// httptest requests are for example.com
+			request := httptest.NewRequest(tc.method, "/thing", nil)
+			for name, value := range tc.headers {
+				request.Header.Set(name, value)
+			}
+
+			recorder := serve(authRouter(false), request)
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestSessions(t *testing.T) {
+	router := authRouter(false)
+	assert := assert.New(t)
+
+	cookie := signup(t, router, " Cook ")
+	if assert.NotNil(cookie) {
+		assert.True(cookie.HttpOnly)
+		assert.Equal(http.SameSiteLaxMode, cookie.SameSite)
+	}
+
+	recorder := serve(router, postForm("/thing", url.Values{}, cookie))
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("cook", recorder.Body.String(), "usernames are trimmed and lower cased")
+
+	recorder = serve(router, postForm("/signup", url.Values{"username": {"baker"}, "password": {"correct horse battery staple"}}))
+	assert.Equal(http.StatusNotFound, recorder.Code, "only the first user can sign up when signup is closed")
+
+	recorder = serve(router, postForm("/login", url.Values{"username": {"cook"}, "password": {"wrong password"}}))
+	assert.Equal(http.StatusUnauthorized, recorder.Code)
+	assert.Nil(sessionCookie(recorder))
+
+	recorder = serve(router, postForm("/login", url.Values{"username": {"Cook"}, "password": {"correct horse battery staple"}, "next": {"/food/123"}}))
+	assert.Equal(http.StatusSeeOther, recorder.Code)
+	assert.Equal("/food/123", recorder.Header().Get("Location"))
+	second := sessionCookie(recorder)
+	assert.NotNil(second)
+
+	recorder = serve(router, postForm("/logout", url.Values{}, second))
+	assert.Equal(http.StatusSeeOther, recorder.Code)
+	assert.Equal(-1, sessionCookie(recorder).MaxAge)
+
+	recorder = serve(router, postForm("/thing", url.Values{}, second))
+	assert.Equal(http.StatusSeeOther, recorder.Code, "a logged out session is refused")
+
+	recorder = serve(router, postForm("/thing", url.Values{}, cookie))
+	assert.Equal(http.StatusOK, recorder.Code, "logging out leaves other sessions alone")
+}
+
+func TestSignup(t *testing.T) {
+	type testCase struct {
+		name           string
+		username       string
+		password       string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Valid", username: "baker", password: "correct horse battery staple", expectedStatus: http.StatusSeeOther},
+		{name: "Username taken", username: "COOK", password: "correct horse battery staple", expectedStatus: http.StatusConflict},
+		{name: "Missing username", username: " ", password: "correct horse battery staple", expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Short password", username: "baker", password: "hunter2", expectedStatus: http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := authRouter(true)
+			signup(t, router, "cook")
+
+			recorder := serve(router, postForm("/signup", url.Values{"username": {tc.username}, "password": {tc.password}}))
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
+
+func TestLoginRedirect(t *testing.T) {
+	type testCase struct {
+		name             string
+		next             string
+		expectedLocation string
+	}
+
+	testCases := []testCase{
+		{name: "Local path", next: "/recipe/123?servings=2", expectedLocation: "/recipe/123?servings=2"},
+		{name: "No next", next: "", expectedLocation: "/recipe"},
+		{name: "Other site", next: "https://example.com", expectedLocation: "/recipe"},
+		{name: "Protocol relative", next: "//example.com", expectedLocation: "/recipe"},
+		{name: "Backslash", next: `/\example.com`, expectedLocation: "/recipe"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			router := authRouter(false)
+			signup(t, router, "cook")
+
+			recorder := serve(router, postForm("/login", url.Values{"username": {"cook"}, "password": {"correct horse battery staple"}, "next": {tc.next}}))
+
+			assert.Equal(t, http.StatusSeeOther, recorder.Code)
+			assert.Equal(t, tc.expectedLocation, recorder.Header().Get("Location"))
+		})
+	}
+}
+
+func TestApiKeys(t *testing.T) {
+	router := authRouter(false)
+	cookie := signup(t, router, "cook")
+	assert := assert.New(t)
+
+	request := httptest.NewRequest(http.MethodPost, "/account/api-keys", strings.NewReader(`{"name": "meal planner"}`))
+	request.Header.Set("Accept", "application/json")
+	request.AddCookie(cookie)
+	recorder := serve(router, request)
+	assert.Equal(http.StatusCreated, recorder.Code)
+
+	var created response.CreateApiKeyResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	key := created.Key
+	assert.Equal("meal planner", created.ApiKey.Name)
+	assert.True(strings.HasPrefix(key, auth.ApiKeyPrefix))
+
+	request = httptest.NewRequest(http.MethodDelete, "/thing", nil)
+	request.Header.Set("Authorization", "Bearer "+key)
+	recorder = serve(router, request)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.Equal("cook", recorder.Body.String())
+
+	request = httptest.NewRequest(http.MethodDelete, "/account/api-keys/"+created.ApiKey.Id, nil)
+	request.Header.Set("Authorization", "Bearer "+key)
+	assert.Equal(http.StatusOK, serve(router, request).Code, "a key can revoke itself")
+
+	request = httptest.NewRequest(http.MethodDelete, "/thing", nil)
+	request.Header.Set("Authorization", "Bearer "+key)
+	assert.Equal(http.StatusUnauthorized, serve(router, request).Code)
+
+	request = httptest.NewRequest(http.MethodGet, "/account", nil)
+	assert.Equal(http.StatusSeeOther, serve(router, request).Code, "the account page needs a user")
+}
@@ -0,0 +1,168 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller"
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// foodRouter serves the food routes behind the same auth as the app, over a store with one catalog food, whose id it returns
+func foodRouter() (chi.Router, *repository.MemoryUserRepository, string) {
+	store := repository.NewMemoryStore()
+	store.AddFood(model.Food{Id: "Food:Resource:salt", Name: "Salt"})
+	userRepository := repository.NewMemoryUserRepository(store)
+	authController := controller.NewAuthController(userRepository, true, false)
+	foodController := controller.NewFoodController(repository.NewMemoryFoodRepository(store))
+
+	router := chi.NewRouter()
+	router.Use(authController.Authenticate)
+	router.Group(func(r chi.Router) {
+		r.Use(controller.RequireUserForWrites)
+		foodController.FoodRoutes(r)
+	})
+	authController.AuthRoutes(router)
+	return router, userRepository, "Food:Resource:salt"
+}
+
+func createFood(t *testing.T, router http.Handler, cookie *http.Cookie, body string) model.Food {
+	recorder := serve(router, jsonRequest(http.MethodPost, "/food", body, cookie))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("creating a food failed with %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var food model.Food
+	if err := json.NewDecoder(recorder.Body).Decode(&food); err != nil {
+		t.Fatal(err)
+	}
+	return food
+}
+
+func TestFoodRoles(t *testing.T) {
+	router, userRepository, catalogId := foodRouter()
+	signup(t, router, "admin")
+	curator := signup(t, router, "curator")
+	cook := signup(t, router, "cook")
+	other := signup(t, router, "other")
+	viewer := signup(t, router, "viewer")
+	for username, role := range map[string]string{"curator": model.CuratorRole, "viewer": model.ViewerRole} {
+		if _, _, err := userRepository.SetRole(context.Background(), username, role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	custom := createFood(t, router, cook, `{"name": "Smoked salt"}`)
+	assert.Equal(t, model.PrivateVisibility, custom.Visibility)
+	assert.NotEmpty(t, custom.AuthorId)
+	curated := createFood(t, router, curator, `{"name": "Celery salt"}`)
+	assert.True(t, curated.InCatalog())
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		cookie         *http.Cookie
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Viewer creates a food", method: http.MethodPost, path: "/food", body: `{"name": "Pepper"}`, cookie: viewer, expectedStatus: http.StatusForbidden},
+		{name: "Cook edits a catalog food", method: http.MethodPatch, path: "/food/" + catalogId, body: `{"name": "Sea salt"}`, cookie: cook, expectedStatus: http.StatusForbidden},
+		{name: "Cook deletes a catalog food", method: http.MethodDelete, path: "/food/" + catalogId, cookie: cook, expectedStatus: http.StatusForbidden},
+		{name: "Someone else reads a custom food", method: http.MethodGet, path: "/food/" + custom.Id, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Curator edits someone's custom food", method: http.MethodPatch, path: "/food/" + custom.Id, body: `{"name": "Mine now"}`, cookie: curator, expectedStatus: http.StatusNotFound},
+		{name: "Author edits a custom food", method: http.MethodPatch, path: "/food/" + custom.Id, body: `{"name": "Hickory salt"}`, cookie: cook, expectedStatus: http.StatusOK},
+		{name: "Curator edits a catalog food", method: http.MethodPatch, path: "/food/" + catalogId, body: `{"name": "Sea salt"}`, cookie: curator, expectedStatus: http.StatusOK},
+		{name: "Author deletes a custom food", method: http.MethodDelete, path: "/food/" + custom.Id, cookie: cook, expectedStatus: http.StatusOK},
+		{name: "Curator deletes a catalog food", method: http.MethodDelete, path: "/food/" + curated.Id, cookie: curator, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, tc.cookie))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestFoodValidationProblems(t *testing.T) {
+	router, _, _ := foodRouter()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+
+	type testCase struct {
+		name           string
+		body           string
+		expectedStatus int
+		field          string
+	}
+
+	testCases := []testCase{
+		{name: "No name", body: `{"density": 1}`, expectedStatus: http.StatusUnprocessableEntity, field: "name"},
+		{name: "Blank name", body: `{"name": "  "}`, expectedStatus: http.StatusUnprocessableEntity, field: "name"},
+		{name: "Negative density", body: `{"name": "Air", "density": -1}`, expectedStatus: http.StatusUnprocessableEntity, field: "density"},
+		{name: "Not JSON", body: `name=Garlic`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(http.MethodPost, "/food", tc.body, cook))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+			assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+			var problem response.Problem
+			if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			if tc.field != "" {
+				assert.NotEmpty(t, problem.Errors.Message(tc.field))
+			}
+		})
+	}
+
+	recorder := serve(router, postForm("/food", url.Values{"name": {"Garlic"}, "density": {"heavy"}}, cook))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Couldn&#39;t read the density &#34;heavy&#34;")
+	assert.Contains(t, recorder.Body.String(), `value="Garlic"`)
+}
+
+func TestFoodJsonNegotiation(t *testing.T) {
+	router, _, catalogId := foodRouter()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+
+	recorder := serve(router, jsonRequest(http.MethodPost, "/account/api-keys", `{"name": "script"}`, cook))
+	var created response.CreateApiKeyResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+
+	// an API key is enough to be answered in JSON, without an Accept header
+	request := httptest.NewRequest(http.MethodPost, "/food", strings.NewReader(`{"name": "Garlic"}`))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Authorization", "Bearer "+created.Key)
+	recorder = serve(router, request)
+	assert.Equal(http.StatusOK, recorder.Code, recorder.Body.String())
+	var food model.Food
+	assert.NoError(json.NewDecoder(recorder.Body).Decode(&food))
+	assert.Equal("Garlic", food.Name)
+
+	request = httptest.NewRequest(http.MethodGet, "/food/"+catalogId, nil)
+	request.Header.Set("Accept", "application/json; charset=utf-8")
+	recorder = serve(router, request)
+	assert.Equal(http.StatusOK, recorder.Code)
+	assert.NoError(json.NewDecoder(recorder.Body).Decode(&food))
+	assert.Equal(catalogId, food.Id)
+}
@@ -0,0 +1,54 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeHealthRepository struct {
+	err error
+}
+
+func (f fakeHealthRepository) Ready(ctx context.Context) error { return f.err }
+
+func TestHealthController(t *testing.T) {
+	type testCase struct {
+		name           string
+		readyErr       error
+		shuttingDown   bool
+		path           string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Alive", path: "/healthz", expectedStatus: http.StatusOK},
+		{name: "Alive while the database is down", readyErr: errors.New("connection refused"), path: "/healthz", expectedStatus: http.StatusOK},
+		{name: "Ready", path: "/readyz", expectedStatus: http.StatusOK},
+		{name: "Not ready", readyErr: errors.New("connection refused"), path: "/readyz", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Not ready while shutting down", shuttingDown: true, path: "/readyz", expectedStatus: http.StatusServiceUnavailable},
+		{name: "Version", path: "/version", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			healthController := controller.NewHealthController(fakeHealthRepository{err: tc.readyErr})
+			if tc.shuttingDown {
+				healthController.ShuttingDown()
+			}
+			router := chi.NewRouter()
+			healthController.HealthRoutes(router)
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package controller_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func createHousehold(t *testing.T, router http.Handler, cookie *http.Cookie, name string) model.Household {
+	recorder := serve(router, jsonRequest(http.MethodPost, "/household", `{"name": "`+name+`"}`, cookie))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("creating a household failed with %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var household model.Household
+	if err := json.NewDecoder(recorder.Body).Decode(&household); err != nil {
+		t.Fatal(err)
+	}
+	return household
+}
+
+// joinHousehold invites the user by username and accepts for them
+func joinHousehold(t *testing.T, router http.Handler, household model.Household, member *http.Cookie, username string, cookie *http.Cookie) {
+	recorder := serve(router, jsonRequest(http.MethodPost, "/household/"+household.Id+"/invitations", `{"username": "`+username+`"}`, member))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("inviting %s failed with %d: %s", username, recorder.Code, recorder.Body.String())
+	}
+
+	var households response.GetHouseholdsResponse
+	recorder = serve(router, jsonRequest(http.MethodGet, "/household", "", cookie))
+	if err := json.NewDecoder(recorder.Body).Decode(&households); err != nil {
+		t.Fatal(err)
+	}
+	if len(households.Invitations) != 1 {
+		t.Fatalf("expected 1 invitation, got %v", households.Invitations)
+	}
+
+	recorder = serve(router, jsonRequest(http.MethodPost, "/household/invitations/"+households.Invitations[0].Id+"/accept", "", cookie))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("accepting the invitation failed with %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
+
+func TestHouseholdRecipes(t *testing.T) {
+	router, foodId := recipeRouter()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	member := signup(t, router, "member")
+	other := signup(t, router, "other")
+
+	household := createHousehold(t, router, cook, "Home")
+	joinHousehold(t, router, household, cook, "member", member)
+
+	ingredients := `"ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cup"}], "steps": ["Boil"]`
+	shared := createRecipe(t, router, cook, `{"title": "Household rice", "household_id": "`+household.Id+`", `+ingredients+`}`)
+	assert.Equal(t, household.Id, shared.HouseholdId)
+	assert.Equal(t, model.PrivateVisibility, shared.Visibility)
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		cookie         *http.Cookie
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Member reads a household recipe", method: http.MethodGet, path: "/recipe/" + shared.Id, cookie: member, expectedStatus: http.StatusOK},
+		{name: "Someone else reads a household recipe", method: http.MethodGet, path: "/recipe/" + shared.Id, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Member edits a household recipe", method: http.MethodPatch, path: "/recipe/" + shared.Id, body: `{"title": "Fried rice"}`, cookie: member, expectedStatus: http.StatusOK},
+		{name: "Someone else shares with a household", method: http.MethodPost, path: "/recipe", body: `{"title": "Sneaky rice", "household_id": "` + household.Id + `", ` + ingredients + `}`, cookie: other, expectedStatus: http.StatusForbidden},
+		{name: "Someone else reads a household", method: http.MethodGet, path: "/household/" + household.Id, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Someone else invites to a household", method: http.MethodPost, path: "/household/" + household.Id + "/invitations", body: `{"username": "other"}`, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Invite an unknown user", method: http.MethodPost, path: "/household/" + household.Id + "/invitations", body: `{"username": "nobody"}`, cookie: cook, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Anonymous lists households", method: http.MethodGet, path: "/household", expectedStatus: http.StatusUnauthorized},
+		{name: "Member leaves", method: http.MethodPost, path: "/household/" + household.Id + "/leave", cookie: member, expectedStatus: http.StatusOK},
+		{name: "Former member reads a household recipe", method: http.MethodGet, path: "/recipe/" + shared.Id, cookie: member, expectedStatus: http.StatusNotFound},
+		{name: "Author reads a household recipe", method: http.MethodGet, path: "/recipe/" + shared.Id, cookie: cook, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, tc.cookie))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestHouseholdInvitations(t *testing.T) {
+	router, _ := recipeRouter()
+	cook := signup(t, router, "cook")
+	guest := signup(t, router, "guest")
+	other := signup(t, router, "other")
+
+	household := createHousehold(t, router, cook, "Home")
+	recorder := serve(router, jsonRequest(http.MethodPost, "/household/"+household.Id+"/invitations", `{"username": " Guest "}`, cook))
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	var households response.GetHouseholdsResponse
+	recorder = serve(router, jsonRequest(http.MethodGet, "/household", "", guest))
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&households))
+	assert.Empty(t, households.Households)
+	if !assert.Len(t, households.Invitations, 1) {
+		return
+	}
+	invitation := households.Invitations[0]
+	assert.Equal(t, "Home", invitation.HouseholdName)
+	assert.Equal(t, "cook", invitation.InvitedBy)
+
+	recorder = serve(router, jsonRequest(http.MethodDelete, "/household/invitations/"+invitation.Id, "", other))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	recorder = serve(router, jsonRequest(http.MethodDelete, "/household/invitations/"+invitation.Id, "", guest))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	recorder = serve(router, jsonRequest(http.MethodPost, "/household/invitations/"+invitation.Id+"/accept", "", guest))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+	recorder = serve(router, jsonRequest(http.MethodGet, "/household/"+household.Id, "", cook))
+	var got model.Household
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&got))
+	assert.Len(t, got.Members, 1)
+}
+
+func TestHouseholdRoles(t *testing.T) {
+	router, userRepository, _ := recipeRouterWithUsers()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	viewer := signup(t, router, "viewer")
+	signup(t, router, "guest")
+	if _, _, err := userRepository.SetRole(context.Background(), "viewer", model.ViewerRole); err != nil {
+		t.Fatal(err)
+	}
+	household := createHousehold(t, router, cook, "Home")
+	joinHousehold(t, router, household, cook, "viewer", viewer)
+
+	type testCase struct {
+		name           string
+		path           string
+		body           string
+		cookie         *http.Cookie
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Viewer creates a household", path: "/household", body: `{"name": "Mine"}`, cookie: viewer, expectedStatus: http.StatusForbidden},
+		{name: "Viewer invites someone", path: "/household/" + household.Id + "/invitations", body: `{"username": "guest"}`, cookie: viewer, expectedStatus: http.StatusForbidden},
+		{name: "Viewer leaves", path: "/household/" + household.Id + "/leave", cookie: viewer, expectedStatus: http.StatusOK},
+		{name: "Not JSON", path: "/household", body: `{"name": `, cookie: cook, expectedStatus: http.StatusBadRequest},
+		{name: "Not a string", path: "/household/" + household.Id + "/invitations", body: `{"username": 7}`, cookie: cook, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(http.MethodPost, tc.path, tc.body, tc.cookie))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestHouseholdLists(t *testing.T) {
+	router, foodId := recipeRouter()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	member := signup(t, router, "member")
+	other := signup(t, router, "other")
+
+	household := createHousehold(t, router, cook, "Home")
+	joinHousehold(t, router, household, cook, "member", member)
+	shopping := "/household/" + household.Id + "/shopping"
+	pantry := "/household/" + household.Id + "/pantry"
+
+	recorder := serve(router, jsonRequest(http.MethodPost, shopping, `{"food_id": "`+foodId+`", "quantity": " 2 bags "}`, cook))
+	if !assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String()) {
+		return
+	}
+	var item model.ListItem
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&item))
+	assert.Equal(t, model.ShoppingList, item.List)
+	assert.Equal(t, "Rice", item.FoodName)
+	assert.Equal(t, "2 bags", item.Quantity)
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		cookie         *http.Cookie
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Someone else reads a list", method: http.MethodGet, path: shopping, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Someone else adds to a list", method: http.MethodPost, path: pantry, body: `{"food_id": "` + foodId + `"}`, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Someone else removes an item", method: http.MethodDelete, path: shopping + "/" + item.Id, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Unknown list", method: http.MethodGet, path: "/household/" + household.Id + "/freezer", cookie: cook, expectedStatus: http.StatusNotFound},
+		{name: "Item on the other list", method: http.MethodDelete, path: pantry + "/" + item.Id, cookie: cook, expectedStatus: http.StatusNotFound},
+		{name: "No food", method: http.MethodPost, path: pantry, body: `{"quantity": "1"}`, cookie: cook, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Someone else's private food", method: http.MethodPost, path: pantry, body: `{"food_id": "` + someoneElsesFoodId + `"}`, cookie: cook, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Move to an unknown list", method: http.MethodPatch, path: shopping + "/" + item.Id, body: `{"list": "freezer"}`, cookie: cook, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Member buys an item", method: http.MethodPatch, path: shopping + "/" + item.Id, body: `{"list": "pantry"}`, cookie: member, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, tc.cookie))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		})
+	}
+
+	var lists [2]response.GetListItemsResponse
+	for i, path := range []string{shopping, pantry} {
+		recorder = serve(router, jsonRequest(http.MethodGet, path, "", member))
+		assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&lists[i]))
+	}
+	assert.Empty(t, lists[0].Items)
+	if assert.Len(t, lists[1].Items, 1) {
+		assert.Equal(t, item.Id, lists[1].Items[0].Id)
+		assert.Equal(t, "2 bags", lists[1].Items[0].Quantity)
+	}
+
+	recorder = serve(router, jsonRequest(http.MethodDelete, pantry+"/"+item.Id, "", member))
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	recorder = serve(router, jsonRequest(http.MethodGet, pantry, "", cook))
+	assert.NotContains(t, recorder.Body.String(), item.Id)
+}
+
+func TestHouseholdListForm(t *testing.T) {
+	router, foodId := recipeRouter()
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	household := createHousehold(t, router, cook, "Home")
+	pantry := "/household/" + household.Id + "/pantry"
+
+	recorder := serve(router, postForm(pantry, url.Values{"food_id": {foodId}, "quantity": {"1 bag"}}, cook))
+	assert.Equal(t, http.StatusSeeOther, recorder.Code, recorder.Body.String())
+
+	request := httptest.NewRequest(http.MethodGet, pantry, nil)
+	request.AddCookie(cook)
+	recorder = serve(router, request)
+	assert.Contains(t, recorder.Body.String(), "Rice")
+	assert.Contains(t, recorder.Body.String(), "1 bag")
+
+	recorder = serve(router, postForm(pantry, url.Values{"food_id": {someoneElsesFoodId}}, cook))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "There&#39;s no such food")
+}
@@ -0,0 +1,335 @@
+package controller_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller"
+	"github.com/ThomasMatlak/food/controller/response"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// someoneElsesFoodId is a private food in recipeRouter's store, whose author isn't any of the test's users
+const someoneElsesFoodId = "Food:Resource:saffron"
+
+// potatoId is a catalog food in recipeRouter's store that's measured in portions
+const potatoId = "Food:Resource:potato"
+
+// recipeRouter serves the recipe and household routes behind the same auth as the app, over a store of a few foods, and returns the id of its plain catalog food
+func recipeRouter() (chi.Router, string) {
+	router, _, foodId := recipeRouterWithUsers()
+	return router, foodId
+}
+
+// recipeRouterWithUsers also returns the user repository, to change users' roles
+func recipeRouterWithUsers() (chi.Router, *repository.MemoryUserRepository, string) {
+	store := repository.NewMemoryStore()
+	store.AddFood(model.Food{Id: "Food:Resource:rice", Name: "Rice"})
+	store.AddFood(model.Food{Id: potatoId, Name: "Potato", Portions: []model.Portion{{Amount: 1, Unit: "medium", GramWeight: 213}}})
+	store.AddFood(model.Food{Id: someoneElsesFoodId, Name: "Saffron", AuthorId: "User:Resource:someone", Visibility: model.PrivateVisibility})
+	userRepository := repository.NewMemoryUserRepository(store)
+	householdRepository := repository.NewMemoryHouseholdRepository(store)
+	authController := controller.NewAuthController(userRepository, true, false)
+	foodRepository := repository.NewMemoryFoodRepository(store)
+	recipeController := controller.NewRecipeController(repository.NewMemoryRecipeRepository(store), householdRepository, foodRepository)
+	foodController := controller.NewFoodController(foodRepository)
+	householdController := controller.NewHouseholdController(householdRepository, userRepository, repository.NewMemoryListItemRepository(store), foodRepository)
+
+	router := chi.NewRouter()
+	router.Use(authController.Authenticate)
+	router.Group(func(r chi.Router) {
+		r.Use(controller.RequireUserForWrites)
+		recipeController.RecipeRoutes(r)
+		foodController.FoodRoutes(r)
+	})
+	authController.AuthRoutes(router)
+	householdController.HouseholdRoutes(router)
+	return router, userRepository, "Food:Resource:rice"
+}
+
+func jsonRequest(method string, path string, body string, cookie *http.Cookie) *http.Request {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	if cookie != nil {
+		request.AddCookie(cookie)
+	}
+	return request
+}
+
+func createRecipe(t *testing.T, router http.Handler, cookie *http.Cookie, body string) model.Recipe {
+	recorder := serve(router, jsonRequest(http.MethodPost, "/recipe", body, cookie))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("creating a recipe failed with %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var recipe model.Recipe
+	if err := json.NewDecoder(recorder.Body).Decode(&recipe); err != nil {
+		t.Fatal(err)
+	}
+	return recipe
+}
+
+func TestRecipeScaling(t *testing.T) {
+	router, foodId := recipeRouter()
+	cook := signup(t, router, "cook")
+	recipe := createRecipe(t, router, cook, `{"title": "Rice", "servings": 2, "ingredients": [{"ingredient_id": "`+foodId+`", "amount": 1, "unit": "cup"}], "steps": ["Boil"]}`)
+
+	type testCase struct {
+		name           string
+		query          string
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Scale", query: "?scale=1.5", expectedStatus: http.StatusOK},
+		{name: "Servings", query: "?servings=4", expectedStatus: http.StatusOK},
+		{name: "Zero", query: "?scale=0", expectedStatus: http.StatusBadRequest},
+		{name: "NaN", query: "?scale=NaN", expectedStatus: http.StatusBadRequest},
+		{name: "Infinity", query: "?scale=Inf", expectedStatus: http.StatusBadRequest},
+		{name: "Negative infinity", query: "?scale=-Inf", expectedStatus: http.StatusBadRequest},
+		{name: "Scale and servings", query: "?scale=2&servings=4", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(http.MethodGet, "/recipe/"+recipe.Id+tc.query, "", cook))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+			assert.NotEmpty(t, recorder.Body.String())
+		})
+	}
+}
+
+func TestRecipeVisibility(t *testing.T) {
+	router, foodId := recipeRouter()
+	// the first user is an admin
+	admin := signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	other := signup(t, router, "other")
+
+	ingredients := `"ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cup"}], "steps": ["Boil"]`
+	private := createRecipe(t, router, cook, `{"title": "Private rice", `+ingredients+`}`)
+	public := createRecipe(t, router, cook, `{"title": "Public rice", "visibility": "public", `+ingredients+`}`)
+
+	assert.Equal(t, model.PrivateVisibility, private.Visibility)
+	assert.Equal(t, model.PublicVisibility, public.Visibility)
+	assert.NotEmpty(t, private.AuthorId)
+	assert.Equal(t, private.AuthorId, public.AuthorId)
+
+	type testCase struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		cookie         *http.Cookie
+		expectedStatus int
+	}
+
+	testCases := []testCase{
+		{name: "Anonymous reads a private recipe", method: http.MethodGet, path: "/recipe/" + private.Id, expectedStatus: http.StatusNotFound},
+		{name: "Someone else reads a private recipe", method: http.MethodGet, path: "/recipe/" + private.Id, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Author reads a private recipe", method: http.MethodGet, path: "/recipe/" + private.Id, cookie: cook, expectedStatus: http.StatusOK},
+		{name: "Someone else reads a private recipe's nutrition", method: http.MethodGet, path: "/recipe/" + private.Id + "/nutrition", cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Anonymous reads a public recipe", method: http.MethodGet, path: "/recipe/" + public.Id, expectedStatus: http.StatusOK},
+		{name: "Someone else edits a private recipe", method: http.MethodPatch, path: "/recipe/" + private.Id, body: `{"title": "Mine now"}`, cookie: other, expectedStatus: http.StatusNotFound},
+		{name: "Someone else edits a public recipe", method: http.MethodPatch, path: "/recipe/" + public.Id, body: `{"title": "Mine now"}`, cookie: other, expectedStatus: http.StatusForbidden},
+		{name: "Someone else deletes a public recipe", method: http.MethodDelete, path: "/recipe/" + public.Id, cookie: other, expectedStatus: http.StatusForbidden},
+		{name: "Unknown visibility", method: http.MethodPatch, path: "/recipe/" + public.Id, body: `{"visibility": "friends"}`, cookie: cook, expectedStatus: http.StatusUnprocessableEntity},
+		{name: "Author edits a private recipe", method: http.MethodPatch, path: "/recipe/" + private.Id, body: `{"title": "Fried rice"}`, cookie: cook, expectedStatus: http.StatusOK},
+		{name: "Admin edits a public recipe", method: http.MethodPatch, path: "/recipe/" + public.Id, body: `{"title": "Steamed rice"}`, cookie: admin, expectedStatus: http.StatusOK},
+		{name: "Admin reads a private recipe", method: http.MethodGet, path: "/recipe/" + private.Id, cookie: admin, expectedStatus: http.StatusOK},
+		{name: "Admin edits a private recipe", method: http.MethodPatch, path: "/recipe/" + private.Id, body: `{"title": "Egg fried rice"}`, cookie: admin, expectedStatus: http.StatusOK},
+		{name: "Admin deletes a private recipe", method: http.MethodDelete, path: "/recipe/" + private.Id, cookie: admin, expectedStatus: http.StatusOK},
+		{name: "Author reads a deleted recipe", method: http.MethodGet, path: "/recipe/" + private.Id, cookie: cook, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, tc.cookie))
+			assert.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+		})
+	}
+}
+
+func TestRecipeListVisibility(t *testing.T) {
+	router, foodId := recipeRouter()
+	cook := signup(t, router, "cook")
+	other := signup(t, router, "other")
+
+	ingredients := `"ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cup"}], "steps": ["Boil"]`
+	createRecipe(t, router, cook, `{"title": "Private rice", `+ingredients+`}`)
+	createRecipe(t, router, cook, `{"title": "Public rice", "visibility": "public", `+ingredients+`}`)
+
+	type testCase struct {
+		name     string
+		cookie   *http.Cookie
+		expected []string
+	}
+
+	testCases := []testCase{
+		{name: "Anonymous", expected: []string{"Public rice"}},
+		{name: "Author", cookie: cook, expected: []string{"Private rice", "Public rice"}},
+		{name: "Someone else", cookie: other, expected: []string{"Public rice"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(http.MethodGet, "/recipe?sort=name", "", tc.cookie))
+			var page response.GetRecipesResponse
+			assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&page))
+
+			titles := []string{}
+			for _, recipe := range page.Recipes {
+				titles = append(titles, recipe.Title)
+			}
+			assert.Equal(t, tc.expected, titles)
+		})
+	}
+}
+
+func TestRecipeValidationProblems(t *testing.T) {
+	router, foodId := recipeRouter()
+	cook := signup(t, router, "cook")
+
+	body := `{"title": " ", "servings": 2, "ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cupz"}]}`
+	recorder := serve(router, jsonRequest(http.MethodPost, "/recipe", body, cook))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+	var problem response.Problem
+	if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, problem.Status)
+	assert.Equal(t, "/recipe", problem.Instance)
+	assert.NotEmpty(t, problem.Errors.Message("title"))
+	assert.NotEmpty(t, problem.Errors.Message("ingredients[0].unit"))
+	assert.Empty(t, problem.Errors.Message("servings"))
+
+	recorder = serve(router, jsonRequest(http.MethodGet, "/recipe/Recipe:Resource:missing", "", cook))
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+}
+
+func TestRecipeFormProblems(t *testing.T) {
+	router, foodId := recipeRouter()
+	cook := signup(t, router, "cook")
+
+	form := url.Values{
+		"title":             {"Rice"},
+		"servings":          {"0"},
+		"steps":             {"Boil"},
+		"ingredient_id":     {foodId},
+		"ingredient_amount": {"1"},
+		"ingredient_unit":   {"cupz"},
+	}
+	recorder := serve(router, postForm("/recipe", form, cook))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("Content-Type"), "text/html")
+	page := recorder.Body.String()
+	assert.Contains(t, page, "There has to be at least 1 serving")
+	assert.Contains(t, page, `Unknown unit &#34;cupz&#34;`)
+	// what was entered is still there, including the name of the food that was picked
+	assert.Contains(t, page, `value="Rice"`)
+	assert.Contains(t, page, ">Rice</option>")
+}
+
+func TestPublicRecipesUsePublicFoods(t *testing.T) {
+	router, foodId := recipeRouter()
+	// the first user is an admin, whose foods go in the catalog
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	own := createFood(t, router, cook, `{"name": "Secret spice"}`)
+	assert.Equal(t, model.PrivateVisibility, own.Visibility)
+
+	withOwnFood := `"ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cup"}, {"ingredient_id": "` + own.Id + `", "amount": 1, "unit": "g"}], "steps": ["Boil"]`
+	recipe := createRecipe(t, router, cook, `{"title": "Spiced rice", `+withOwnFood+`}`)
+
+	type testCase struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}
+
+	testCases := []testCase{
+		{name: "Create", method: http.MethodPost, path: "/recipe", body: `{"title": "Spiced rice", "visibility": "public", ` + withOwnFood + `}`},
+		{name: "Replace", method: http.MethodPut, path: "/recipe/" + recipe.Id, body: `{"title": "Spiced rice", "visibility": "public", ` + withOwnFood + `}`},
+		{name: "Update", method: http.MethodPatch, path: "/recipe/" + recipe.Id, body: `{"visibility": "public"}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, cook))
+			assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+
+			var problem response.Problem
+			if err := json.NewDecoder(recorder.Body).Decode(&problem); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, "A public recipe can't use a private food", problem.Errors.Message("ingredients[1].ingredient_id"))
+		})
+	}
+
+	recorder := serve(router, jsonRequest(http.MethodGet, "/recipe/"+recipe.Id, "", nil))
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "the recipe is still private")
+}
+
+func TestRecipePortionUnits(t *testing.T) {
+	router, foodId := recipeRouter()
+	cook := signup(t, router, "cook")
+
+	recorder := serve(router, jsonRequest(http.MethodPost, "/recipe", `{"title": "Baked potato", "steps": ["Bake"], "ingredients": [{"ingredient_id": "`+potatoId+`", "amount": 2, "unit": "medium"}]}`, cook))
+	assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+	// a portion of one food isn't a unit for another
+	recorder = serve(router, jsonRequest(http.MethodPost, "/recipe", `{"title": "Rice", "steps": ["Boil"], "ingredients": [{"ingredient_id": "`+foodId+`", "amount": 2, "unit": "medium"}]}`, cook))
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	var problem response.Problem
+	assert.NoError(t, json.NewDecoder(recorder.Body).Decode(&problem))
+	assert.Equal(t, `Unknown unit "medium"`, problem.Errors.Message("ingredients[0].unit"))
+}
+
+func TestRecipeIngredientsMustBeVisible(t *testing.T) {
+	router, foodId := recipeRouter()
+	// the first user is an admin, who sees every food
+	signup(t, router, "admin")
+	cook := signup(t, router, "cook")
+	recipe := createRecipe(t, router, cook, `{"title": "Rice", "ingredients": [{"ingredient_id": "`+foodId+`", "amount": 1, "unit": "cup"}], "steps": ["Boil"]}`)
+
+	hidden := `"ingredients": [{"ingredient_id": "` + foodId + `", "amount": 1, "unit": "cup"}, {"ingredient_id": "` + someoneElsesFoodId + `", "amount": 1, "unit": "g"}]`
+	type testCase struct {
+		name   string
+		method string
+		path   string
+		body   string
+	}
+
+	testCases := []testCase{
+		{name: "Create", method: http.MethodPost, path: "/recipe", body: `{"title": "Saffron rice", "steps": ["Boil"], ` + hidden + `}`},
+		{name: "Replace", method: http.MethodPut, path: "/recipe/" + recipe.Id, body: `{"title": "Saffron rice", "steps": ["Boil"], ` + hidden + `}`},
+		{name: "Update", method: http.MethodPatch, path: "/recipe/" + recipe.Id, body: `{` + hidden + `}`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := serve(router, jsonRequest(tc.method, tc.path, tc.body, cook))
+			assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code, recorder.Body.String())
+
+			body := recorder.Body.String()
+			assert.NotContains(t, body, "Saffron")
+
+			var problem response.Problem
+			if err := json.Unmarshal([]byte(body), &problem); err != nil {
+				t.Fatal(err)
+			}
+			assert.NotEmpty(t, problem.Errors.Message("ingredients[1].ingredient_id"))
+		})
+	}
+}
@@ -1,9 +1,64 @@
 package request
 
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasMatlak/food/validation"
+)
+
 type CreateFoodRequest struct {
-	Name string `json:"name"`
+	Name    string   `json:"name"`
+	Density *float64 `json:"density"`
 }
 
 type UpdateFoodRequest struct {
-	Name *string `json:"name"`
+	Name    *string  `json:"name"`
+	Density *float64 `json:"density"`
+}
+
+// ValidateFood returns what's wrong with a new or replacement food, field by field
+func ValidateFood(request *CreateFoodRequest) validation.Errors {
+	var problems validation.Errors
+	if len(strings.TrimSpace(request.Name)) == 0 {
+		problems.Add("name", "A name is required")
+	}
+	validateDensity(&problems, request.Density)
+	return problems
+}
+
+// ValidateUpdateFood returns what's wrong with the fields a patch changes
+func ValidateUpdateFood(request *UpdateFoodRequest) validation.Errors {
+	var problems validation.Errors
+	if request.Name != nil && len(strings.TrimSpace(*request.Name)) == 0 {
+		problems.Add("name", "A name is required")
+	}
+	validateDensity(&problems, request.Density)
+	return problems
+}
+
+func validateDensity(problems *validation.Errors, density *float64) {
+	if density != nil && (!(*density > 0) || math.IsInf(*density, 1)) {
+		problems.Add("density", "The density has to be more than 0")
+	}
+}
+
+// ParseFoodForm reads the food form. A density that can't be parsed is left empty, and reported.
+func ParseFoodForm(form url.Values) (CreateFoodRequest, validation.Errors) {
+	var problems validation.Errors
+	request := CreateFoodRequest{Name: form.Get("name")}
+
+	if rawDensity := strings.TrimSpace(form.Get("density")); rawDensity != "" {
+		density, err := strconv.ParseFloat(rawDensity, 64)
+		if err != nil {
+			problems.Add("density", fmt.Sprintf("Couldn't read the density %q", rawDensity))
+		} else {
+			request.Density = &density
+		}
+	}
+
+	return request, problems
 }
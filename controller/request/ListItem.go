@@ -0,0 +1,56 @@
+package request
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/validation"
+)
+
+type CreateListItemRequest struct {
+	FoodId   string `json:"food_id"`
+	Quantity string `json:"quantity"`
+}
+
+type UpdateListItemRequest struct {
+	// moves the item to the household's other list
+	List     *string `json:"list"`
+	Quantity *string `json:"quantity"`
+}
+
+func ParseListItemForm(form url.Values) CreateListItemRequest {
+	return CreateListItemRequest{FoodId: form.Get("food_id"), Quantity: form.Get("quantity")}
+}
+
+// ParseUpdateListItemForm only sets the fields the form has
+func ParseUpdateListItemForm(form url.Values) UpdateListItemRequest {
+	var request UpdateListItemRequest
+	if form.Has("list") {
+		list := form.Get("list")
+		request.List = &list
+	}
+	if form.Has("quantity") {
+		quantity := form.Get("quantity")
+		request.Quantity = &quantity
+	}
+	return request
+}
+
+func ValidateCreateListItem(request *CreateListItemRequest) validation.Errors {
+	var problems validation.Errors
+	if strings.TrimSpace(request.FoodId) == "" {
+		problems.Add("food_id", "Pick a food")
+	}
+	return problems
+}
+
+func ValidateUpdateListItem(request *UpdateListItemRequest) validation.Errors {
+	var problems validation.Errors
+	if request.List != nil {
+		if _, err := model.ParseList(*request.List); err != nil {
+			problems.Add("list", err.Error())
+		}
+	}
+	return problems
+}
@@ -1,9 +1,14 @@
 package request
 
 import (
+	"fmt"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/ThomasMatlak/food/validation"
 )
 
 type CreateRecipeRequest struct {
@@ -11,21 +16,33 @@ type CreateRecipeRequest struct {
 	Description *string                    `json:"description"`
 	Ingredients []model.ContainsIngredient `json:"ingredients"`
 	Steps       []string                   `json:"steps"`
+	Servings    *int64                     `json:"servings"`
+	// public or private; empty means private for a new recipe, or no change for an existing one
+	Visibility string `json:"visibility"`
+	// the household to share the recipe with, or empty for none; nil means none for a new recipe, or no change for an existing one
+	HouseholdId *string `json:"household_id"`
 }
 
-func CanCreateRecipe(request *CreateRecipeRequest) bool {
+// ValidateCreateRecipe returns what's wrong with a new or replacement recipe, field by field
+func ValidateCreateRecipe(request *CreateRecipeRequest) validation.Errors {
+	var problems validation.Errors
 	if len(strings.TrimSpace(request.Title)) == 0 {
-		return false
+		problems.Add("title", "A title is required")
 	}
 	if request.Description != nil && len(strings.TrimSpace(*request.Description)) == 0 {
-		return false
+		problems.Add("description", "The description can't be blank")
 	}
 	if len(request.Ingredients) == 0 {
-		return false
+		problems.Add("ingredients", "At least one ingredient is required")
+	}
+	validateIngredients(&problems, request.Ingredients)
+	validateServings(&problems, request.Servings)
+	if request.Visibility != "" {
+		validateVisibility(&problems, request.Visibility)
 	}
 	// TODO validation of steps?
 
-	return true
+	return problems
 }
 
 type UpdateRecipeRequest struct {
@@ -33,19 +50,120 @@ type UpdateRecipeRequest struct {
 	Description *string                     `json:"description"`
 	Ingredients *[]model.ContainsIngredient `json:"ingredients"`
 	Steps       *[]string                   `json:"steps"`
+	Servings    *int64                      `json:"servings"`
+	Visibility  *string                     `json:"visibility"`
+	// empty takes the recipe out of its household
+	HouseholdId *string `json:"household_id"`
 }
 
-func CanUpdateRecipe(request *UpdateRecipeRequest) bool {
+// ValidateUpdateRecipe returns what's wrong with the fields a patch changes
+func ValidateUpdateRecipe(request *UpdateRecipeRequest) validation.Errors {
+	var problems validation.Errors
 	if request.Title != nil && len(strings.TrimSpace(*request.Title)) == 0 {
-		return false
+		problems.Add("title", "A title is required")
 	}
 	if request.Description != nil && len(strings.TrimSpace(*request.Description)) == 0 {
-		return false
+		problems.Add("description", "The description can't be blank")
+	}
+	if request.Ingredients != nil {
+		if len(*request.Ingredients) == 0 {
+			problems.Add("ingredients", "At least one ingredient is required")
+		}
+		validateIngredients(&problems, *request.Ingredients)
 	}
-	if request.Ingredients != nil && len(*request.Ingredients) == 0 {
-		return false
+	validateServings(&problems, request.Servings)
+	if request.Visibility != nil {
+		validateVisibility(&problems, *request.Visibility)
 	}
 	// TODO validation of steps?
 
-	return true
+	return problems
+}
+
+func validateIngredients(problems *validation.Errors, ingredients []model.ContainsIngredient) {
+	for i, ci := range ingredients {
+		if strings.TrimSpace(ci.IngredientId) == "" {
+			problems.Add(validation.Item("ingredients", i, "ingredient_id"), "Pick a food")
+		}
+		// whether the food can be measured in the unit is up to the food's portions, which the controller checks
+		if strings.TrimSpace(ci.Unit) == "" {
+			problems.Add(validation.Item("ingredients", i, "unit"), "A unit is required")
+		}
+		if ci.Amount.Min <= 0 {
+			problems.Add(validation.Item("ingredients", i, "amount"), "The amount has to be more than 0")
+		} else if !ci.Amount.Valid() {
+			problems.Add(validation.Item("ingredients", i, "amount"), "The range can't end below where it starts")
+		}
+	}
+}
+
+func validateServings(problems *validation.Errors, servings *int64) {
+	if servings != nil && *servings < 1 {
+		problems.Add("servings", "There has to be at least 1 serving")
+	}
+}
+
+func validateVisibility(problems *validation.Errors, visibility string) {
+	if _, err := model.ParseVisibility(visibility); err != nil {
+		problems.Add("visibility", err.Error())
+	}
+}
+
+// NormalizeUnits replaces unit aliases (e.g. "cups" or "c") with the unit's canonical name
+func NormalizeUnits(ingredients []model.ContainsIngredient) []model.ContainsIngredient {
+	return util.MapArray(ingredients, func(ci model.ContainsIngredient) model.ContainsIngredient {
+		if unit, found := model.LookupUnit(ci.Unit); found {
+			ci.Unit = unit.Name
+		}
+		return ci
+	})
+}
+
+// ParseRecipeForm reads the recipe form, where each ingredient row adds one value to each of ingredient_id, ingredient_amount and ingredient_unit, and steps are one per line.
+// Values that can't be parsed are left empty, and reported with the field they came from.
+func ParseRecipeForm(form url.Values) (CreateRecipeRequest, validation.Errors) {
+	request := CreateRecipeRequest{Title: form.Get("title"), Visibility: form.Get("visibility"), Ingredients: []model.ContainsIngredient{}, Steps: []string{}}
+	var problems validation.Errors
+
+	// the household select is only shown to members of a household
+	if form.Has("household_id") {
+		householdId := form.Get("household_id")
+		request.HouseholdId = &householdId
+	}
+
+	if description := form.Get("description"); strings.TrimSpace(description) != "" {
+		request.Description = &description
+	}
+
+	if rawServings := strings.TrimSpace(form.Get("servings")); rawServings != "" {
+		servings, err := strconv.ParseInt(rawServings, 10, 64)
+		if err != nil {
+			problems.Add("servings", "Servings has to be a whole number")
+		} else {
+			request.Servings = &servings
+		}
+	}
+
+	ids := form["ingredient_id"]
+	amounts := form["ingredient_amount"]
+	units := form["ingredient_unit"]
+	if len(amounts) != len(ids) || len(units) != len(ids) {
+		problems.Add("ingredients", fmt.Sprintf("Got %d ingredients, but %d amounts and %d units", len(ids), len(amounts), len(units)))
+		return request, problems
+	}
+	for i := range ids {
+		amount, err := model.ParseAmount(amounts[i])
+		if err != nil {
+			problems.Add(validation.Item("ingredients", i, "amount"), fmt.Sprintf("Couldn't read the amount %q", amounts[i]))
+		}
+		request.Ingredients = append(request.Ingredients, model.ContainsIngredient{IngredientId: ids[i], Amount: amount, Unit: strings.TrimSpace(units[i])})
+	}
+
+	for _, step := range strings.Split(form.Get("steps"), "\n") {
+		if step = strings.TrimSpace(step); step != "" {
+			request.Steps = append(request.Steps, step)
+		}
+	}
+
+	return request, problems
 }
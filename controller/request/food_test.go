@@ -0,0 +1,53 @@
+package request_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFoodForm(t *testing.T) {
+	parsed, problems := request.ParseFoodForm(url.Values{"name": {"Olive oil"}, "density": {" 0.92 "}})
+
+	assert := assert.New(t)
+	assert.True(problems.Valid())
+	assert.Equal("Olive oil", parsed.Name)
+	assert.Equal(0.92, *parsed.Density)
+
+	parsed, problems = request.ParseFoodForm(url.Values{"name": {"Olive oil"}, "density": {"heavy"}})
+	assert.Equal(`Couldn't read the density "heavy"`, problems.Message("density"))
+	assert.Nil(parsed.Density)
+}
+
+func TestValidateFood(t *testing.T) {
+	zero := 0.0
+	negative := -1.0
+
+	type testCase struct {
+		name     string
+		request  request.CreateFoodRequest
+		field    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{name: "Empty name", request: request.CreateFoodRequest{Name: ""}, field: "name", expected: "A name is required"},
+		{name: "Blank name", request: request.CreateFoodRequest{Name: "  "}, field: "name", expected: "A name is required"},
+		{name: "Zero density", request: request.CreateFoodRequest{Name: "Air", Density: &zero}, field: "density", expected: "The density has to be more than 0"},
+		{name: "Negative density", request: request.CreateFoodRequest{Name: "Air", Density: &negative}, field: "density", expected: "The density has to be more than 0"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := request.ValidateFood(&tc.request)
+			assert.Equal(t, tc.expected, problems.Message(tc.field))
+			assert.Len(t, problems, 1, problems.Error())
+		})
+	}
+
+	blank := " "
+	assert.NotEmpty(t, request.ValidateUpdateFood(&request.UpdateFoodRequest{Name: &blank}).Message("name"))
+	assert.True(t, request.ValidateUpdateFood(&request.UpdateFoodRequest{}).Valid())
+}
@@ -0,0 +1,112 @@
+package request_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/ThomasMatlak/food/controller/request"
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRecipeForm(t *testing.T) {
+	form := url.Values{
+		"title":             {"rice and beans"},
+		"description":       {"  "},
+		"servings":          {"4"},
+		"ingredient_id":     {"asdf", "zxcv"},
+		"ingredient_amount": {"1 1/2", "2-3"},
+		"ingredient_unit":   {" cups", "cans"},
+		"steps":             {"cook beans\r\n\r\ncook rice\ncombine"},
+		"visibility":        {"public"},
+	}
+
+	parsed, problems := request.ParseRecipeForm(form)
+
+	assert := assert.New(t)
+	assert.True(problems.Valid())
+	assert.Equal("rice and beans", parsed.Title)
+	assert.Nil(parsed.Description)
+	assert.Equal(int64(4), *parsed.Servings)
+	assert.Equal([]model.ContainsIngredient{
+		{IngredientId: "asdf", Amount: model.ExactAmount(1.5), Unit: "cups"},
+		{IngredientId: "zxcv", Amount: model.AmountRange(2, 3), Unit: "cans"},
+	}, parsed.Ingredients)
+	assert.Equal([]string{"cook beans", "cook rice", "combine"}, parsed.Steps)
+	assert.Equal(model.PublicVisibility, parsed.Visibility)
+	assert.True(request.ValidateCreateRecipe(&parsed).Valid())
+
+	parsed.Visibility = "friends"
+	assert.NotEmpty(request.ValidateCreateRecipe(&parsed).Message("visibility"))
+}
+
+func TestParseRecipeFormMismatchedIngredients(t *testing.T) {
+	form := url.Values{
+		"title":             {"rice and beans"},
+		"ingredient_id":     {"asdf", "zxcv"},
+		"ingredient_amount": {"1"},
+		"ingredient_unit":   {"cup", "cup"},
+	}
+
+	_, problems := request.ParseRecipeForm(form)
+
+	assert.NotEmpty(t, problems.Message("ingredients"))
+}
+
+func TestParseRecipeFormUnreadableValues(t *testing.T) {
+	form := url.Values{
+		"title":             {"rice and beans"},
+		"servings":          {"a few"},
+		"ingredient_id":     {"asdf", "zxcv"},
+		"ingredient_amount": {"1", "some"},
+		"ingredient_unit":   {"cup", "cup"},
+	}
+
+	parsed, problems := request.ParseRecipeForm(form)
+
+	assert := assert.New(t)
+	assert.Equal("Servings has to be a whole number", problems.Message("servings"))
+	assert.Equal(`Couldn't read the amount "some"`, problems.Message("ingredients[1].amount"))
+	// the rest of the form is still read, so it can be shown again
+	assert.Len(parsed.Ingredients, 2)
+}
+
+func TestValidateCreateRecipe(t *testing.T) {
+	description := "  "
+	servings := int64(0)
+
+	type testCase struct {
+		name     string
+		request  request.CreateRecipeRequest
+		field    string
+		expected string
+	}
+
+	valid := []model.ContainsIngredient{{IngredientId: "asdf", Amount: model.ExactAmount(1), Unit: "cup"}}
+	testCases := []testCase{
+		{name: "Empty title", request: request.CreateRecipeRequest{Title: " ", Ingredients: valid}, field: "title", expected: "A title is required"},
+		{name: "Blank description", request: request.CreateRecipeRequest{Title: "Rice", Description: &description, Ingredients: valid}, field: "description", expected: "The description can't be blank"},
+		{name: "No ingredients", request: request.CreateRecipeRequest{Title: "Rice"}, field: "ingredients", expected: "At least one ingredient is required"},
+		{
+			name:     "No unit",
+			request:  request.CreateRecipeRequest{Title: "Rice", Ingredients: append(valid, model.ContainsIngredient{IngredientId: "zxcv", Amount: model.ExactAmount(1), Unit: " "})},
+			field:    "ingredients[1].unit",
+			expected: "A unit is required",
+		},
+		{
+			name:     "Backwards range",
+			request:  request.CreateRecipeRequest{Title: "Rice", Ingredients: []model.ContainsIngredient{{IngredientId: "asdf", Amount: model.AmountRange(3, 2), Unit: "cup"}}},
+			field:    "ingredients[0].amount",
+			expected: "The range can't end below where it starts",
+		},
+		{name: "No servings", request: request.CreateRecipeRequest{Title: "Rice", Ingredients: valid, Servings: &servings}, field: "servings", expected: "There has to be at least 1 serving"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			problems := request.ValidateCreateRecipe(&tc.request)
+			assert.Equal(t, tc.expected, problems.Message(tc.field))
+			assert.Len(t, problems, 1, problems.Error())
+		})
+	}
+}
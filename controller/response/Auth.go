@@ -0,0 +1,29 @@
+package response
+
+import (
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+type GetAccountResponse struct {
+	User    *model.User    `json:"user"`
+	ApiKeys []model.ApiKey `json:"api_keys"`
+}
+
+type CreateApiKeyResponse struct {
+	ApiKey model.ApiKey `json:"api_key"`
+	// the only time the key itself is returned
+	Key string `json:"key"`
+}
+
+type DeleteApiKeyResponse struct {
+	Id string `json:"id"`
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04")
+}
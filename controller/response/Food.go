@@ -1,11 +1,38 @@
 package response
 
-import "github.com/ThomasMatlak/food/model"
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+)
 
 type GetFoodsResponse struct {
 	Foods []model.Food `json:"ingredients"`
+	Next  *string      `json:"next"`
 }
 
 type DeleteFoodResponse struct {
 	Id string `json:"id"`
 }
+
+func portionName(portion model.Portion) string {
+	name := strings.TrimSpace(fmt.Sprintf("%g %s %s", portion.Amount, portion.Unit, portion.Modifier))
+	if portion.Description != "" {
+		name = fmt.Sprintf("%s (%s)", name, portion.Description)
+	}
+	return name
+}
+
+func densityValue(food *model.Food) string {
+	if food.Density == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*food.Density, 'g', -1, 64)
+}
+
+type FoodSearchResponse struct {
+	Results []model.FoodSearchResult `json:"results"`
+	Next    *string                  `json:"next"`
+}
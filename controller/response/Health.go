@@ -0,0 +1,16 @@
+package response
+
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type VersionResponse struct {
+	Path         string `json:"path"`
+	Version      string `json:"version"`
+	GoVersion    string `json:"go_version"`
+	Revision     string `json:"revision,omitempty"`
+	RevisionTime string `json:"revision_time,omitempty"`
+	// whether the build had uncommitted changes
+	Modified bool `json:"modified"`
+}
@@ -0,0 +1,32 @@
+package response
+
+import "github.com/ThomasMatlak/food/model"
+
+type GetHouseholdsResponse struct {
+	Households  []model.Household  `json:"households"`
+	Invitations []model.Invitation `json:"invitations"`
+}
+
+type InvitationResponse struct {
+	Id string `json:"id"`
+}
+
+type LeaveHouseholdResponse struct {
+	Id string `json:"id"`
+}
+
+type GetListItemsResponse struct {
+	Items []model.ListItem `json:"items"`
+}
+
+type DeleteListItemResponse struct {
+	Id string `json:"id"`
+}
+
+// listTitle is what a household list is called on its page
+func listTitle(list string) string {
+	if list == model.ShoppingList {
+		return "Shopping list"
+	}
+	return "Pantry"
+}
@@ -0,0 +1,14 @@
+package response
+
+import "github.com/ThomasMatlak/food/validation"
+
+// Problem is an RFC 7807 problem details object, sent as application/problem+json
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// what's wrong with each field of an invalid request
+	Errors validation.Errors `json:"errors,omitempty"`
+}
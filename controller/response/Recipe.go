@@ -1,11 +1,56 @@
 package response
 
-import "github.com/ThomasMatlak/food/model"
+import (
+	"strconv"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+)
 
 type GetRecipesResponse struct {
 	Recipes []model.Recipe `json:"recipes"`
+	Next    *string        `json:"next"`
+}
+
+type RecipeSearchResponse struct {
+	Results []model.RecipeSearchResult `json:"results"`
+	Next    *string                    `json:"next"`
 }
 
 type DeleteRecipeResponse struct {
 	Id string `json:"id"`
 }
+
+type RecipeNutritionResponse struct {
+	RecipeId                 string              `json:"recipe_id"`
+	Servings                 int64               `json:"servings"`
+	Total                    []model.HasNutrient `json:"total"`
+	PerServing               []model.HasNutrient `json:"per_serving"`
+	UnconvertedIngredientIds []string            `json:"unconverted_ingredient_ids"`
+}
+
+func servingsValue(servings *int64) string {
+	if servings == nil {
+		return ""
+	}
+	return strconv.FormatInt(*servings, 10)
+}
+
+func descriptionValue(description *string) string {
+	if description == nil {
+		return ""
+	}
+	return *description
+}
+
+func stepsValue(steps []string) string {
+	return strings.Join(steps, "\n")
+}
+
+// amountValue leaves new ingredient rows blank instead of showing 0
+func amountValue(amount model.Amount) string {
+	if amount.Min == 0 {
+		return ""
+	}
+	return amount.String()
+}
@@ -0,0 +1,211 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed cypher/*.cypher
+var files embed.FS
+
+var MigrationLabel string = "Migration"
+
+type Migration struct {
+	Version int
+	Name    string
+	// run one at a time, since schema changes can't share a transaction with anything else
+	Statements []string
+	// detects migrations that were edited after being applied
+	Checksum string
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.cypher$`)
+
+// Load reads the embedded migrations, ordered by version
+func Load() ([]Migration, error) {
+	dir, err := fs.Sub(files, "cypher")
+	if err != nil {
+		return nil, err
+	}
+	return LoadFrom(dir)
+}
+
+// LoadFrom reads the migrations at the root of fsys, which are named like 0001_constraints.cypher
+func LoadFrom(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := []Migration{}
+	versions := map[int]string{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file name %q does not look like 0001_name.cypher", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		if existing, found := versions[version]; found {
+			return nil, fmt.Errorf("migrations %s and %s have the same version", existing, entry.Name())
+		}
+		versions[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		checksum := sha256.Sum256(content)
+
+		migrations = append(migrations, Migration{
+			Version:    version,
+			Name:       match[2],
+			Statements: splitStatements(string(content)),
+			Checksum:   hex.EncodeToString(checksum[:]),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// splitStatements drops // comment lines and splits on semicolons at the end of a line
+func splitStatements(content string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(content, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			lines = append(lines, line)
+		}
+	}
+
+	statements := []string{}
+	for _, statement := range regexp.MustCompile(`;\s*(\n|$)`).Split(strings.Join(lines, "\n"), -1) {
+		if statement = strings.TrimSpace(statement); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+type Migrator struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewMigrator(driver neo4j.DriverWithContext, database string) *Migrator {
+	return &Migrator{driver: driver, database: database}
+}
+
+// Pending returns the migrations that haven't been applied yet, and fails if an applied migration has since changed
+func (m *Migrator) Pending(ctx context.Context, migrations []Migration) ([]Migration, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (map[int]string, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (map[int]string, error) {
+			*query = fmt.Sprintf("MATCH (m:`%s`) RETURN m.version AS version, m.checksum AS checksum", MigrationLabel)
+			params = map[string]any{}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			applied := map[int]string{}
+			for _, record := range records {
+				version, _ := repository.TypedGet[int64](record, "version")
+				checksum, _ := repository.TypedGet[string](record, "checksum")
+				applied[int(version)] = checksum
+			}
+			return applied, nil
+		})
+	}
+
+	applied, err := repository.RunQuery(ctx, m.driver, m.database, "get applied migrations", neo4j.AccessModeRead, work)
+	if err != nil {
+		return nil, err
+	}
+
+	pending := []Migration{}
+	for _, migration := range migrations {
+		checksum, found := applied[migration.Version]
+		if !found {
+			pending = append(pending, migration)
+		} else if checksum != migration.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s was changed after it was applied; add a new migration instead", migration.Version, migration.Name)
+		}
+	}
+	return pending, nil
+}
+
+// Apply runs every pending migration in order and returns the ones it ran. With dryRun, it only returns what it would run.
+func (m *Migrator) Apply(ctx context.Context, migrations []Migration, dryRun bool) ([]Migration, error) {
+	pending, err := m.Pending(ctx, migrations)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun {
+		return pending, nil
+	}
+
+	for _, migration := range pending {
+		log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("applying migration")
+
+		for _, statement := range migration.Statements {
+			err := m.runStatement(ctx, statement)
+			if err != nil {
+				return nil, fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+		}
+
+		// MERGE, so that two instances starting at once don't fail on the version constraint
+		err := m.runStatement(ctx, fmt.Sprintf("MERGE (m:`%s` {version: $version})\n"+
+			"ON CREATE SET m += {name: $name, checksum: $checksum, appliedAt: $appliedAt}", MigrationLabel),
+			"version", migration.Version,
+			"name", migration.Name,
+			"checksum", migration.Checksum,
+			"appliedAt", neo4j.LocalDateTime(time.Now()),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not record migration %04d_%s: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	return pending, nil
+}
+
+// runStatement uses an auto-commit transaction, which schema changes need
+func (m *Migrator) runStatement(ctx context.Context, statement string, keysAndValues ...any) error {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		*query = statement
+		params = map[string]any{}
+		for i := 0; i+1 < len(keysAndValues); i += 2 {
+			params[keysAndValues[i].(string)] = keysAndValues[i+1]
+		}
+
+		result, err := session.Run(ctx, *query, params)
+		if err != nil {
+			return nil, err
+		}
+		_, err = result.Consume(ctx)
+		return nil, err
+	}
+
+	_, err := repository.RunQuery(ctx, m.driver, m.database, "run migration", neo4j.AccessModeWrite, work)
+	return err
+}
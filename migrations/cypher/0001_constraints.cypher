@@ -0,0 +1,7 @@
+CREATE CONSTRAINT id IF NOT EXISTS
+FOR (r:Resource)
+REQUIRE r.id IS UNIQUE;
+
+CREATE CONSTRAINT migration_version IF NOT EXISTS
+FOR (m:Migration)
+REQUIRE m.version IS UNIQUE;
@@ -0,0 +1,20 @@
+CREATE TEXT INDEX food_id_idx IF NOT EXISTS
+FOR (f:Food)
+ON f.id;
+
+CREATE FULLTEXT INDEX food_name_search_idx IF NOT EXISTS
+FOR (f:Food)
+ON EACH [f.name];
+
+// recipes keep their steps and ingredient names in stepsText and ingredientsText, since fulltext indexes can't look into lists or across relationships
+CREATE FULLTEXT INDEX recipe_search_idx IF NOT EXISTS
+FOR (r:Recipe)
+ON EACH [r.title, r.description, r.stepsText, r.ingredientsText];
+
+CREATE TEXT INDEX nutrient_id_idx IF NOT EXISTS
+FOR (n:Nutrient)
+ON n.id;
+
+CREATE FULLTEXT INDEX nutrient_name_search_idx IF NOT EXISTS
+FOR (n:Nutrient)
+ON EACH [n.name];
@@ -0,0 +1,12 @@
+// usernames, session tokens and api keys are looked up on every signed in request
+CREATE CONSTRAINT user_username IF NOT EXISTS
+FOR (u:User)
+REQUIRE u.username IS UNIQUE;
+
+CREATE CONSTRAINT session_token_hash IF NOT EXISTS
+FOR (s:Session)
+REQUIRE s.tokenHash IS UNIQUE;
+
+CREATE CONSTRAINT api_key_token_hash IF NOT EXISTS
+FOR (k:ApiKey)
+REQUIRE k.tokenHash IS UNIQUE;
@@ -0,0 +1,4 @@
+// recipes saved before stepsText and ingredientsText existed can't be found by their steps or ingredients until both are filled in
+MATCH (r:Recipe)
+SET r.stepsText = reduce(text = '', step IN coalesce(r.steps, []) | text + step + '\n'),
+r.ingredientsText = reduce(text = '', name IN [(r)-[ci:CONTAINS_INGREDIENT]->(i:Food) WHERE ci.deleted IS NULL AND i.deleted IS NULL | i.name] | text + name + '\n');
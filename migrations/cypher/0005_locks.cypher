@@ -0,0 +1,5 @@
+// writes that have to happen one at a time, like the first signup, take a write lock on a named lock node; MERGE only
+// makes one of each while the name is unique
+CREATE CONSTRAINT lock_name IF NOT EXISTS
+FOR (l:Lock)
+REQUIRE l.name IS UNIQUE;
@@ -0,0 +1,191 @@
+package migrations_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ThomasMatlak/food/migrations"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/stretchr/testify/assert"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestLoadFrom(t *testing.T) {
+	type testCase struct {
+		name       string
+		files      fstest.MapFS
+		expected   []migrations.Migration
+		shouldFail bool
+	}
+
+	testCases := []testCase{
+		{
+			name: "Ordered by version",
+			files: fstest.MapFS{
+				"0002_second.cypher": {Data: []byte("CREATE INDEX a IF NOT EXISTS FOR (n:A) ON n.a;")},
+				"0001_first.cypher": {Data: []byte(
+					"// a comment; with a semicolon\n" +
+						"CREATE CONSTRAINT a IF NOT EXISTS\n" +
+						"FOR (n:A)\n" +
+						"REQUIRE n.id IS UNIQUE;\n" +
+						"\n" +
+						"CREATE CONSTRAINT b IF NOT EXISTS FOR (n:B) REQUIRE n.id IS UNIQUE\n",
+				)},
+			},
+			expected: []migrations.Migration{
+				{Version: 1, Name: "first", Statements: []string{
+					"CREATE CONSTRAINT a IF NOT EXISTS\nFOR (n:A)\nREQUIRE n.id IS UNIQUE",
+					"CREATE CONSTRAINT b IF NOT EXISTS FOR (n:B) REQUIRE n.id IS UNIQUE",
+				}},
+				{Version: 2, Name: "second", Statements: []string{"CREATE INDEX a IF NOT EXISTS FOR (n:A) ON n.a"}},
+			},
+		},
+		{
+			name:       "Bad file name",
+			files:      fstest.MapFS{"constraints.cypher": {Data: []byte("")}},
+			shouldFail: true,
+		},
+		{
+			name: "Duplicate version",
+			files: fstest.MapFS{
+				"0001_a.cypher": {Data: []byte("")},
+				"1_b.cypher":    {Data: []byte("")},
+			},
+			shouldFail: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := migrations.LoadFrom(tc.files)
+
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Len(t, actual, len(tc.expected))
+			for i := range tc.expected {
+				assert.Equal(t, tc.expected[i].Version, actual[i].Version)
+				assert.Equal(t, tc.expected[i].Name, actual[i].Name)
+				assert.Equal(t, tc.expected[i].Statements, actual[i].Statements)
+				assert.NotEmpty(t, actual[i].Checksum)
+			}
+		})
+	}
+}
+
+func TestEmbeddedMigrationsLoad(t *testing.T) {
+	all, err := migrations.Load()
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, all)
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	driver := startNeo4j(ctx, t)
+	migrator := migrations.NewMigrator(driver, "")
+
+	all, err := migrations.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a recipe saved before it had search text
+	seed := "CREATE (r:Recipe {id: 'old', title: 'Rice', steps: ['Rinse', 'Boil']})\n" +
+		"CREATE (r)-[:CONTAINS_INGREDIENT]->(:Food {id: 'rice', name: 'Rice'})\n" +
+		"CREATE (r)-[:CONTAINS_INGREDIENT {deleted: localdatetime()}]->(:Food {id: 'salt', name: 'Salt'})"
+	if _, err := neo4j.ExecuteQuery(ctx, driver, seed, nil, neo4j.EagerResultTransformer); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Dry run applies nothing", func(t *testing.T) {
+		pending, err := migrator.Apply(ctx, all, true)
+		assert.NoError(t, err)
+		assert.Len(t, pending, len(all))
+
+		pending, err = migrator.Pending(ctx, all)
+		assert.NoError(t, err)
+		assert.Len(t, pending, len(all))
+	})
+
+	t.Run("Apply", func(t *testing.T) {
+		applied, err := migrator.Apply(ctx, all, false)
+		assert.NoError(t, err)
+		assert.Len(t, applied, len(all))
+
+		pending, err := migrator.Pending(ctx, all)
+		assert.NoError(t, err)
+		assert.Empty(t, pending)
+	})
+
+	t.Run("Apply fills in search text", func(t *testing.T) {
+		result, err := neo4j.ExecuteQuery(ctx, driver, "MATCH (r:Recipe {id: 'old'}) RETURN r.stepsText AS steps, r.ingredientsText AS ingredients", nil, neo4j.EagerResultTransformer)
+		if !assert.NoError(t, err) || !assert.Len(t, result.Records, 1) {
+			return
+		}
+		steps, _ := result.Records[0].Get("steps")
+		ingredients, _ := result.Records[0].Get("ingredients")
+		assert.Equal(t, "Rinse\nBoil\n", steps)
+		assert.Equal(t, "Rice\n", ingredients)
+	})
+
+	t.Run("Apply again is a no-op", func(t *testing.T) {
+		applied, err := migrator.Apply(ctx, all, false)
+		assert.NoError(t, err)
+		assert.Empty(t, applied)
+	})
+
+	t.Run("Changed migration", func(t *testing.T) {
+		changed := append([]migrations.Migration{}, all...)
+		changed[0].Checksum = "changed"
+
+		_, err := migrator.Pending(ctx, changed)
+		assert.ErrorContains(t, err, "was changed after it was applied")
+	})
+}
+
+func startNeo4j(ctx context.Context, t *testing.T) neo4j.DriverWithContext {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "neo4j:5.5.0-community",
+			ExposedPorts: []string{"7687/tcp"},
+			Env:          map[string]string{"NEO4J_AUTH": "none"},
+			WaitingFor:   wait.ForAll(wait.ForLog("Started."), wait.ForListeningPort("7687/tcp")),
+		},
+		Started: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate neo4j: %s", err)
+		}
+	})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "7687")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	driver, err := neo4j.NewDriverWithContext(fmt.Sprintf("bolt://%s:%s", host, port.Port()), neo4j.NoAuth())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := driver.Close(ctx); err != nil {
+			t.Fatalf("failed to close neo4j driver: %s", err)
+		}
+	})
+	return driver
+}
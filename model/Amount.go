@@ -0,0 +1,194 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Amount is either an exact quantity or a range, e.g. 2–3 cloves
+type Amount struct {
+	Min float64
+	// nil for exact amounts
+	Max *float64
+}
+
+func ExactAmount(amount float64) Amount { return Amount{Min: amount} }
+
+func AmountRange(min float64, max float64) Amount { return Amount{Min: min, Max: &max} }
+
+func (a Amount) IsRange() bool { return a.Max != nil }
+
+// Mean is the amount used for calculations, e.g. nutrition
+func (a Amount) Mean() float64 {
+	if a.Max == nil {
+		return a.Min
+	}
+	return (a.Min + *a.Max) / 2
+}
+
+func (a Amount) Scale(factor float64) Amount {
+	if a.Max == nil {
+		return ExactAmount(a.Min * factor)
+	}
+	return AmountRange(a.Min*factor, *a.Max*factor)
+}
+
+func (a Amount) Valid() bool {
+	return a.Min > 0 && (a.Max == nil || *a.Max >= a.Min)
+}
+
+func (a Amount) String() string {
+	if a.Max == nil {
+		return formatQuantity(a.Min)
+	}
+	return fmt.Sprintf("%s–%s", formatQuantity(a.Min), formatQuantity(*a.Max))
+}
+
+var unicodeFractions = map[string]string{
+	"½": "1/2", "⅓": "1/3", "⅔": "2/3", "¼": "1/4", "¾": "3/4",
+	"⅛": "1/8", "⅜": "3/8", "⅝": "5/8", "⅞": "7/8",
+}
+
+var rangeSeparator = regexp.MustCompile(`\s*(?:-|–|—|\bto\b)\s*`)
+
+// ParseAmount accepts decimals ("1.5"), fractions ("1/2"), mixed numbers ("1 1/2" or "1½") and ranges of those ("2-3", "2 to 3")
+func ParseAmount(s string) (Amount, error) {
+	for unicodeFraction, fraction := range unicodeFractions {
+		s = strings.ReplaceAll(s, unicodeFraction, " "+fraction)
+	}
+	s = strings.TrimSpace(s)
+
+	parts := rangeSeparator.Split(s, -1)
+	switch len(parts) {
+	case 1:
+		amount, err := parseQuantity(parts[0])
+		if err != nil {
+			return Amount{}, err
+		}
+		return ExactAmount(amount), nil
+	case 2:
+		min, err := parseQuantity(parts[0])
+		if err != nil {
+			return Amount{}, err
+		}
+		max, err := parseQuantity(parts[1])
+		if err != nil {
+			return Amount{}, err
+		}
+		return AmountRange(min, max), nil
+	default:
+		return Amount{}, fmt.Errorf("could not parse amount %q", s)
+	}
+}
+
+func parseQuantity(s string) (float64, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields) > 2 {
+		return 0, fmt.Errorf("could not parse quantity %q", s)
+	}
+
+	quantity := 0.0
+	for i, field := range fields {
+		value, isFraction, err := parseNumber(field)
+		if err != nil {
+			return 0, err
+		}
+		// only the last part of a mixed number can be a fraction
+		if i == 0 && len(fields) == 2 && (isFraction || value != math.Trunc(value)) {
+			return 0, fmt.Errorf("could not parse quantity %q", s)
+		}
+		if i == 1 && !isFraction {
+			return 0, fmt.Errorf("could not parse quantity %q", s)
+		}
+		quantity += value
+	}
+	return quantity, nil
+}
+
+func parseNumber(s string) (float64, bool, error) {
+	numerator, denominator, isFraction := strings.Cut(s, "/")
+	if !isFraction {
+		value, err := strconv.ParseFloat(s, 64)
+		return value, false, err
+	}
+
+	n, err := strconv.ParseFloat(numerator, 64)
+	if err != nil {
+		return 0, true, err
+	}
+	d, err := strconv.ParseFloat(denominator, 64)
+	if err != nil {
+		return 0, true, err
+	}
+	if d == 0 {
+		return 0, true, errors.New("fraction has a zero denominator")
+	}
+	return n / d, true, nil
+}
+
+// formatQuantity prefers the fractions cooks actually use, falling back to a decimal
+func formatQuantity(quantity float64) string {
+	whole := math.Floor(quantity)
+	remainder := quantity - whole
+
+	for _, denominator := range []float64{2, 3, 4, 8} {
+		numerator := math.Round(remainder * denominator)
+		if math.Abs(remainder-numerator/denominator) > 0.004 {
+			continue
+		}
+		if numerator == 0 {
+			return strconv.FormatFloat(whole, 'f', -1, 64)
+		}
+		if numerator == denominator {
+			return strconv.FormatFloat(whole+1, 'f', -1, 64)
+		}
+		if whole == 0 {
+			return fmt.Sprintf("%g/%g", numerator, denominator)
+		}
+		return fmt.Sprintf("%g %g/%g", whole, numerator, denominator)
+	}
+
+	return strconv.FormatFloat(math.Round(quantity*100)/100, 'f', -1, 64)
+}
+
+type amountRangeJson struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+func (a Amount) MarshalJSON() ([]byte, error) {
+	if a.Max == nil {
+		return json.Marshal(a.Min)
+	}
+	return json.Marshal(amountRangeJson{Min: a.Min, Max: *a.Max})
+}
+
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	var number float64
+	if err := json.Unmarshal(data, &number); err == nil {
+		*a = ExactAmount(number)
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		parsed, err := ParseAmount(s)
+		if err != nil {
+			return err
+		}
+		*a = parsed
+		return nil
+	}
+
+	var amountRange amountRangeJson
+	if err := json.Unmarshal(data, &amountRange); err != nil {
+		return fmt.Errorf("amount must be a number, a string like \"1 1/2\" or an object like {\"min\": 2, \"max\": 3}: %w", err)
+	}
+	*a = AmountRange(amountRange.Min, amountRange.Max)
+	return nil
+}
@@ -5,14 +5,95 @@ import "context"
 type Food struct {
 	Id   string `json:"id"`
 	Name string `json:"name"`
-	// TODO nutrition
+	// grams per milliliter
+	Density   *float64      `json:"density"`
+	Portions  []Portion     `json:"portions"`
+	Nutrients []HasNutrient `json:"nutrients"`
+	// who made a custom food; empty for catalog foods
+	AuthorId string `json:"author_id"`
+	// public for the shared catalog, private for a user's custom foods; empty is public
+	Visibility string `json:"visibility"`
 	Resource
 }
 
+// InCatalog reports whether the food is part of the shared catalog, like the USDA foods, rather than someone's custom food
+func (f Food) InCatalog() bool {
+	return f.Visibility != PrivateVisibility
+}
+
+func (f Food) VisibleTo(viewer Viewer) bool {
+	return viewer.CanSee(f.Visibility, f.AuthorId, "")
+}
+
+// Measures reports whether amounts of the food can be given in the unit: a registered unit, or one of the food's portions, e.g. "medium"
+func (f *Food) Measures(unit string) bool {
+	if _, found := LookupUnit(unit); found {
+		return true
+	}
+	for _, portion := range f.Portions {
+		if portion.matches(unit) {
+			return true
+		}
+	}
+	return false
+}
+
+// ToGrams uses the food's portions and density when the amount isn't already a mass
+func (f *Food) ToGrams(amount float64, unit string) (float64, bool) {
+	if grams, ok := ToGrams(amount, unit); ok {
+		return grams, true
+	}
+
+	for _, portion := range f.Portions {
+		if portion.matches(unit) && portion.Amount > 0 {
+			return amount * portion.GramWeight / portion.Amount, true
+		}
+	}
+
+	density, found := f.density()
+	if !found {
+		return 0, false
+	}
+	milliliters, err := ConvertUnits(amount, unit, "ml")
+	if err != nil {
+		return 0, false
+	}
+	return milliliters * density, true
+}
+
+// density falls back to the first portion measured by volume
+func (f *Food) density() (float64, bool) {
+	if f.Density != nil {
+		return *f.Density, true
+	}
+
+	for _, portion := range f.Portions {
+		milliliters, err := ConvertUnits(portion.Amount, portion.Unit, "ml")
+		if err == nil && milliliters > 0 {
+			return portion.GramWeight / milliliters, true
+		}
+	}
+	return 0, false
+}
+
 type FoodRepository interface {
-	GetAll(ctx context.Context) ([]Food, error)
+	GetAll(ctx context.Context, query ListQuery) (Page[Food], error)
 	GetById(ctx context.Context, id string) (*Food, bool, error)
+	// Create keeps the food's id if it has one, as an import does, and makes one up otherwise
 	Create(ctx context.Context, food Food) (*Food, error)
 	Update(ctx context.Context, food Food) (*Food, error)
 	Delete(ctx context.Context, id string) (string, error)
+	Search(ctx context.Context, search FoodSearch) ([]FoodSearchResult, error)
+}
+
+type FoodSearch struct {
+	Text   string
+	Viewer Viewer
+	Skip   int
+	Limit  int
+}
+
+type FoodSearchResult struct {
+	Food  Food    `json:"food"`
+	Score float64 `json:"score"`
 }
@@ -0,0 +1,8 @@
+package model
+
+import "context"
+
+type HealthRepository interface {
+	// Ready returns why the database can't serve requests, or nil when it can
+	Ready(ctx context.Context) error
+}
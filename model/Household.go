@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// Household is a group of users who cook together. Recipes can belong to a household, so every member can see and edit them,
+// and the members share the household's pantry and shopping list.
+type Household struct {
+	Id      string            `json:"id"`
+	Name    string            `json:"name"`
+	Members []HouseholdMember `json:"members"`
+	Resource
+}
+
+type HouseholdMember struct {
+	UserId   string     `json:"user_id"`
+	Username string     `json:"username"`
+	Joined   *time.Time `json:"joined"`
+}
+
+// Invitation asks a user to join a household. It's deleted once they accept or decline it.
+type Invitation struct {
+	Id            string `json:"id"`
+	HouseholdId   string `json:"household_id"`
+	HouseholdName string `json:"household_name"`
+	// the username of the member who sent it
+	InvitedBy string `json:"invited_by"`
+	Resource
+}
+
+// HouseholdRepository only reaches a household through one of its members, given by the user id each method takes
+type HouseholdRepository interface {
+	// Create makes the user the household's first member
+	Create(ctx context.Context, household Household, userId string) (*Household, error)
+	// GetById treats a household the user isn't a member of as not found
+	GetById(ctx context.Context, id string, userId string) (*Household, bool, error)
+	// GetForUser returns the households the user is a member of
+	GetForUser(ctx context.Context, userId string) ([]Household, error)
+	// Leave removes the user from the household, and deletes a household without members, along with its lists.
+	// It reports whether they were a member.
+	Leave(ctx context.Context, householdId string, userId string) (bool, error)
+
+	// Invite does nothing when the user already belongs to the household or has been invited to it,
+	// and fails when invitedBy isn't a member
+	Invite(ctx context.Context, householdId string, userId string, invitedBy string) error
+	// GetInvitations returns the user's pending invitations
+	GetInvitations(ctx context.Context, userId string) ([]Invitation, error)
+	// Accept and Decline only act on the user's own invitations, and report whether there was one
+	Accept(ctx context.Context, userId string, invitationId string) (bool, error)
+	Decline(ctx context.Context, userId string, invitationId string) (bool, error)
+}
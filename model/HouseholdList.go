@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Every household has a pantry of what's on hand and a shopping list of what to buy
+const PantryList = "pantry"
+const ShoppingList = "shopping"
+
+var Lists = []string{PantryList, ShoppingList}
+
+func ParseList(s string) (string, error) {
+	for _, list := range Lists {
+		if s == list {
+			return list, nil
+		}
+	}
+	return "", fmt.Errorf("list %q is not one of %s", s, strings.Join(Lists, ", "))
+}
+
+// ListItem is a food on one of a household's lists
+type ListItem struct {
+	Id          string `json:"id"`
+	HouseholdId string `json:"household_id"`
+	List        string `json:"list"`
+	FoodId      string `json:"food_id"`
+	FoodName    string `json:"food_name"`
+	// free text, e.g. "2 cans" or "a bunch"
+	Quantity string `json:"quantity"`
+	Resource
+}
+
+// ListItemRepository only reaches the items of the household it's given, and only when the user is one of its members;
+// otherwise there are no items. Items whose food was deleted are left out.
+type ListItemRepository interface {
+	// GetForHousehold returns the items on one of the household's lists, by food name
+	GetForHousehold(ctx context.Context, householdId string, userId string, list string) ([]ListItem, error)
+	GetById(ctx context.Context, householdId string, userId string, id string) (*ListItem, bool, error)
+	// Create fails when the household or the food doesn't exist
+	Create(ctx context.Context, item ListItem, userId string) (*ListItem, error)
+	// Update changes the item's list and quantity
+	Update(ctx context.Context, item ListItem, userId string) (*ListItem, error)
+	Delete(ctx context.Context, householdId string, userId string, id string) (string, error)
+}
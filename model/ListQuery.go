@@ -0,0 +1,55 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// fields that every list endpoint can sort on; for recipes, name is the title
+var SortableFields = []string{"name", "created", "modified"}
+
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// ListQuery narrows down and orders a list of resources. Ties are always broken by id, so paging through the list is stable.
+type ListQuery struct {
+	Sort          []SortField
+	CreatedAfter  *time.Time
+	ModifiedSince *time.Time
+	NamePrefix    string
+	// leaves out private resources the viewer can't see
+	Viewer Viewer
+	PageRequest
+}
+
+// ParseSort reads a comma separated list of fields, where a leading - sorts that field in descending order, e.g. "name,-created"
+func ParseSort(s string) ([]SortField, error) {
+	sort := []SortField{}
+	if strings.TrimSpace(s) == "" {
+		return sort, nil
+	}
+
+	seen := map[string]bool{}
+	for _, raw := range strings.Split(s, ",") {
+		raw = strings.TrimSpace(raw)
+		field := SortField{Field: strings.TrimPrefix(raw, "-"), Descending: strings.HasPrefix(raw, "-")}
+
+		known := false
+		for _, sortable := range SortableFields {
+			known = known || sortable == field.Field
+		}
+		if !known {
+			return nil, fmt.Errorf("cannot sort by %q", field.Field)
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("cannot sort by %q more than once", field.Field)
+		}
+		seen[field.Field] = true
+
+		sort = append(sort, field)
+	}
+	return sort, nil
+}
@@ -1 +1,195 @@
-package model
\ No newline at end of file
+package model
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type Dimension string
+
+const (
+	Mass   Dimension = "mass"
+	Volume Dimension = "volume"
+	Count  Dimension = "count"
+	// portion units (e.g. a clove of garlic) only mean something for a particular food, so they can only be converted using that food's portions
+	FoodPortion Dimension = "portion"
+)
+
+type MeasuringSystem string
+
+const (
+	Metric      MeasuringSystem = "metric"
+	UsCustomary MeasuringSystem = "us_customary"
+	// count units are the same everywhere
+	NoSystem MeasuringSystem = ""
+)
+
+type MeasuringUnit struct {
+	Name      string          `json:"name"`
+	Dimension Dimension       `json:"dimension"`
+	System    MeasuringSystem `json:"system"`
+	// how many of the dimension's base unit (g, ml or each) make up one of this unit
+	BaseAmount float64  `json:"base_amount"`
+	Aliases    []string `json:"aliases"`
+	// preferred units are the ones picked when choosing a unit automatically, e.g. when converting between systems
+	Preferred bool `json:"preferred"`
+}
+
+var MeasuringUnits = []MeasuringUnit{
+	{Name: "mg", Dimension: Mass, System: Metric, BaseAmount: 0.001, Aliases: []string{"milligram", "milligrams"}},
+	{Name: "g", Dimension: Mass, System: Metric, BaseAmount: 1, Aliases: []string{"gram", "grams", "gr"}, Preferred: true},
+	{Name: "kg", Dimension: Mass, System: Metric, BaseAmount: 1000, Aliases: []string{"kilogram", "kilograms", "kilo", "kilos"}, Preferred: true},
+	{Name: "oz", Dimension: Mass, System: UsCustomary, BaseAmount: 28.349523125, Aliases: []string{"ounce", "ounces"}, Preferred: true},
+	{Name: "lb", Dimension: Mass, System: UsCustomary, BaseAmount: 453.59237, Aliases: []string{"lbs", "pound", "pounds"}, Preferred: true},
+
+	{Name: "ml", Dimension: Volume, System: Metric, BaseAmount: 1, Aliases: []string{"milliliter", "milliliters", "millilitre", "millilitres", "cc"}, Preferred: true},
+	{Name: "l", Dimension: Volume, System: Metric, BaseAmount: 1000, Aliases: []string{"liter", "liters", "litre", "litres"}, Preferred: true},
+	{Name: "pinch", Dimension: Volume, System: UsCustomary, BaseAmount: 0.308057599609375, Aliases: []string{"pinches"}},
+	{Name: "dash", Dimension: Volume, System: UsCustomary, BaseAmount: 0.61611519921875, Aliases: []string{"dashes"}},
+	{Name: "tsp", Dimension: Volume, System: UsCustomary, BaseAmount: 4.92892159375, Aliases: []string{"t", "teaspoon", "teaspoons"}, Preferred: true},
+	{Name: "tbsp", Dimension: Volume, System: UsCustomary, BaseAmount: 14.78676478125, Aliases: []string{"T", "tbs", "tbl", "tablespoon", "tablespoons"}, Preferred: true},
+	{Name: "fl oz", Dimension: Volume, System: UsCustomary, BaseAmount: 29.5735295625, Aliases: []string{"floz", "fluid ounce", "fluid ounces"}},
+	{Name: "cup", Dimension: Volume, System: UsCustomary, BaseAmount: 236.5882365, Aliases: []string{"c", "cups"}, Preferred: true},
+	{Name: "pint", Dimension: Volume, System: UsCustomary, BaseAmount: 473.176473, Aliases: []string{"pt", "pints"}},
+	{Name: "quart", Dimension: Volume, System: UsCustomary, BaseAmount: 946.352946, Aliases: []string{"qt", "quarts"}},
+	{Name: "gallon", Dimension: Volume, System: UsCustomary, BaseAmount: 3785.411784, Aliases: []string{"gal", "gallons"}},
+
+	{Name: "each", Dimension: Count, System: NoSystem, BaseAmount: 1, Aliases: []string{"ea", "whole", "piece", "pieces", "pc", "pcs"}, Preferred: true},
+	{Name: "dozen", Dimension: Count, System: NoSystem, BaseAmount: 12, Aliases: []string{"doz", "dozens"}},
+
+	{Name: "clove", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"cloves"}},
+	{Name: "slice", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"slices"}},
+	{Name: "stick", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"sticks"}},
+	{Name: "can", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"cans"}},
+	{Name: "package", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"packages", "pkg"}},
+	{Name: "sprig", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"sprigs"}},
+	{Name: "leaf", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"leaves"}},
+	{Name: "stalk", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"stalks"}},
+	{Name: "head", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"heads"}},
+	{Name: "bunch", Dimension: FoodPortion, System: NoSystem, BaseAmount: 1, Aliases: []string{"bunches"}},
+}
+
+var measuringUnitsByName = indexMeasuringUnits(MeasuringUnits)
+
+func indexMeasuringUnits(units []MeasuringUnit) map[string]MeasuringUnit {
+	index := map[string]MeasuringUnit{}
+	for _, unit := range units {
+		index[unit.Name] = unit
+		for _, alias := range unit.Aliases {
+			index[alias] = unit
+		}
+	}
+	return index
+}
+
+// LookupUnit finds a unit by its name or one of its aliases.
+// Matching ignores case, except where the case is the only difference between two units (e.g. "t" and "T").
+func LookupUnit(name string) (MeasuringUnit, bool) {
+	name = strings.Join(strings.Fields(name), " ")
+	if unit, found := measuringUnitsByName[name]; found {
+		return unit, true
+	}
+	unit, found := measuringUnitsByName[strings.ToLower(name)]
+	return unit, found
+}
+
+func Convert(amount float64, from MeasuringUnit, to MeasuringUnit) (float64, error) {
+	if from.Dimension != to.Dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from.Name, from.Dimension, to.Name, to.Dimension)
+	}
+	if from.Dimension == FoodPortion && from.Name != to.Name {
+		return 0, fmt.Errorf("cannot convert %s to %s without knowing the food", from.Name, to.Name)
+	}
+	return amount * from.BaseAmount / to.BaseAmount, nil
+}
+
+func ConvertUnits(amount float64, from string, to string) (float64, error) {
+	fromUnit, found := LookupUnit(from)
+	if !found {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	toUnit, found := LookupUnit(to)
+	if !found {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	return Convert(amount, fromUnit, toUnit)
+}
+
+// ConvertToSystem picks the largest preferred unit of the target system that keeps the amount at 1 or more
+func ConvertToSystem(amount float64, from MeasuringUnit, system MeasuringSystem) (float64, MeasuringUnit, error) {
+	if from.Dimension == FoodPortion {
+		return amount, from, nil
+	}
+	if from.Dimension == Count {
+		system = NoSystem
+	}
+
+	candidates := []MeasuringUnit{}
+	for _, unit := range MeasuringUnits {
+		if unit.Dimension == from.Dimension && unit.System == system && unit.Preferred {
+			candidates = append(candidates, unit)
+		}
+	}
+	if len(candidates) == 0 {
+		return 0, MeasuringUnit{}, fmt.Errorf("no %s units in the %q system", from.Dimension, system)
+	}
+
+	baseAmount := amount * from.BaseAmount
+	// MeasuringUnits is sorted smallest to largest within each dimension and system
+	best := candidates[0]
+	for _, unit := range candidates[1:] {
+		if baseAmount/unit.BaseAmount >= 1 {
+			best = unit
+		}
+	}
+
+	return baseAmount / best.BaseAmount, best, nil
+}
+
+// PromoteUnit rewrites an amount in the most natural unit of the same system, e.g. 48 tsp becomes 1 cup and 1/8 cup becomes 2 tbsp
+func PromoteUnit(amount Amount, unitName string) (Amount, string) {
+	unit, found := LookupUnit(unitName)
+	if !found || unit.Dimension == FoodPortion {
+		return amount, unitName
+	}
+
+	// the original unit is always a candidate, so e.g. dozens aren't turned into 24 each
+	candidates := []MeasuringUnit{}
+	for _, candidate := range MeasuringUnits {
+		if candidate.Dimension == unit.Dimension && candidate.System == unit.System && (candidate.Preferred || candidate.Name == unit.Name) {
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	baseAmount := amount.Min * unit.BaseAmount
+	best := candidates[0]
+	for _, candidate := range candidates[1:] {
+		quantity := baseAmount / candidate.BaseAmount
+		if quantity >= 1 {
+			best = candidate
+		} else if unit.System == UsCustomary && quantity >= 0.25 && isCommonFraction(quantity) && baseAmount/best.BaseAmount >= 4 {
+			// 1/3 cup reads better than 5 1/3 tbsp, but 2 tsp reads better than 2/3 tbsp
+			best = candidate
+		}
+	}
+
+	return amount.Scale(unit.BaseAmount / best.BaseAmount), best.Name
+}
+
+func isCommonFraction(quantity float64) bool {
+	for _, denominator := range []float64{2, 3, 4} {
+		if math.Abs(quantity*denominator-math.Round(quantity*denominator)) < 0.01 {
+			return true
+		}
+	}
+	return false
+}
+
+func ToGrams(amount float64, unit string) (float64, bool) {
+	grams, err := ConvertUnits(amount, unit, "g")
+	if err != nil {
+		return 0, false
+	}
+	return grams, true
+}
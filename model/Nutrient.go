@@ -0,0 +1,7 @@
+package model
+
+type Nutrient struct {
+	Id       string `json:"id"`
+	Name     string `json:"name"`
+	UnitName string `json:"unit_name"`
+}
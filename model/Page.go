@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+type PageRequest struct {
+	Limit int
+	// nil for the first page
+	Cursor *Cursor
+}
+
+// Cursor marks the last item of a page; the next page starts right after it
+type Cursor struct {
+	Id string `json:"id"`
+	// the last item's values for each sort field, in order
+	Values []string `json:"values,omitempty"`
+}
+
+type Page[T any] struct {
+	Items []T
+	// nil on the last page
+	NextCursor *Cursor
+}
+
+// Encode makes the cursor safe to use in a url. Clients should treat it as opaque.
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errors.New("malformed cursor")
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Id == "" {
+		return nil, errors.New("malformed cursor")
+	}
+	return &cursor, nil
+}
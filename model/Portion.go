@@ -0,0 +1,22 @@
+package model
+
+import "strings"
+
+// Portion is a household measure of a food and its weight, e.g. 1 cup, chopped = 160 g
+type Portion struct {
+	Amount      float64 `json:"amount"`
+	Unit        string  `json:"unit"`
+	Description string  `json:"description"`
+	Modifier    string  `json:"modifier"`
+	GramWeight  float64 `json:"gram_weight"`
+}
+
+// matches compares units by their canonical name when both are known, so a "tablespoon" portion matches "tbsp"
+func (p Portion) matches(unit string) bool {
+	portionUnit, portionUnitFound := LookupUnit(p.Unit)
+	otherUnit, otherUnitFound := LookupUnit(unit)
+	if portionUnitFound && otherUnitFound {
+		return portionUnit.Name == otherUnit.Name
+	}
+	return strings.EqualFold(strings.TrimSpace(p.Unit), strings.TrimSpace(unit))
+}
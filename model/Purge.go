@@ -0,0 +1,17 @@
+package model
+
+import (
+	"context"
+	"time"
+)
+
+// PurgeResult counts what was, or with a dry run would be, permanently removed
+type PurgeResult struct {
+	Nodes         int64 `json:"nodes"`
+	Relationships int64 `json:"relationships"`
+}
+
+type PurgeRepository interface {
+	// Purge permanently removes resources and relationships that were deleted before the given time
+	Purge(ctx context.Context, deletedBefore time.Time, dryRun bool) (PurgeResult, error)
+}
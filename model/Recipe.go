@@ -1,6 +1,11 @@
 package model
 
-import "context"
+import (
+	"context"
+	"math"
+
+	"github.com/ThomasMatlak/food/util"
+)
 
 type Recipe struct {
 	Id          string               `json:"id"`
@@ -8,15 +13,77 @@ type Recipe struct {
 	Description *string              `json:"description"`
 	Ingredients []ContainsIngredient `json:"ingredients"`
 	Steps       []string             `json:"steps"` // TODO step templates? (e.g. preheat oven to {x} degress, bake for {y} time) // TODO reusable (linkable) steps?
+	Servings    *int64               `json:"servings"`
+	// the user who wrote the recipe; empty for recipes from before there were users
+	AuthorId string `json:"author_id"`
+	// public or private; empty is public
+	Visibility string `json:"visibility"`
+	// a recipe in a household is shared with its members, even when it's private
+	HouseholdId string `json:"household_id"`
 	// TODO categories
 	// TODO images
 	Resource
 }
 
 type RecipeRepository interface {
-	GetAll(ctx context.Context) ([]Recipe, error)
+	GetAll(ctx context.Context, query ListQuery) (Page[Recipe], error)
 	GetById(ctx context.Context, id string) (*Recipe, bool, error)
+	// Create keeps the recipe's id if it has one, as an import does, and makes one up otherwise
 	Create(ctx context.Context, recipe Recipe) (*Recipe, error)
 	Update(ctx context.Context, recipe Recipe) (*Recipe, error)
 	Delete(ctx context.Context, id string) (string, error)
+	GetNutrition(ctx context.Context, id string) (*RecipeNutrition, bool, error)
+	Search(ctx context.Context, search RecipeSearch) ([]RecipeSearchResult, error)
+}
+
+// RecipeSearch combines optional free text with filters on a recipe's ingredients
+type RecipeSearch struct {
+	Text string
+	// food ids that every result has to contain
+	IncludeFoodIds []string
+	// food ids that no result may contain
+	ExcludeFoodIds []string
+	MaxIngredients *int
+	Viewer         Viewer
+	Skip           int
+	Limit          int
+}
+
+func (r Recipe) VisibleTo(viewer Viewer) bool {
+	return viewer.CanSee(r.Visibility, r.AuthorId, r.HouseholdId)
+}
+
+type RecipeSearchResult struct {
+	Recipe Recipe `json:"recipe"`
+	// always 0 when searching without text
+	Score float64 `json:"score"`
+}
+
+// Scale multiplies every ingredient amount by factor, converting to more natural units where the result calls for it
+func (r Recipe) Scale(factor float64) Recipe {
+	r.Ingredients = util.MapArray(r.Ingredients, func(ci ContainsIngredient) ContainsIngredient {
+		ci.Amount, ci.Unit = PromoteUnit(ci.Amount.Scale(factor), ci.Unit)
+		return ci
+	})
+
+	if r.Servings != nil {
+		servings := int64(math.Max(1, math.Round(float64(*r.Servings)*factor)))
+		r.Servings = &servings
+	}
+
+	return r
+}
+
+type RecipeNutrition struct {
+	RecipeId  string        `json:"recipe_id"`
+	Servings  *int64        `json:"servings"`
+	Nutrients []HasNutrient `json:"nutrients"`
+	// ingredients whose amounts could not be converted to grams are left out of the totals
+	UnconvertedIngredientIds []string `json:"unconverted_ingredient_ids"`
+}
+
+func (n *RecipeNutrition) PerServing(servings int64) []HasNutrient {
+	return util.MapArray(n.Nutrients, func(hn HasNutrient) HasNutrient {
+		return HasNutrient{Amount: hn.Amount / float64(servings), Nutrient: hn.Nutrient}
+	})
 }
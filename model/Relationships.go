@@ -2,10 +2,18 @@ package model
 
 type ContainsIngredient struct {
 	Unit         string `json:"unit"`
-	Amount       int64  `json:"amount"`
+	Amount       Amount `json:"amount"`
 	IngredientId string `json:"ingredient_id"`
+	// filled in when reading a recipe; ignored when saving one
+	IngredientName string `json:"ingredient_name"`
 	// TODO order
 	Resource
 }
 
 func ExtractIngredientId(ci ContainsIngredient) string { return ci.IngredientId }
+
+// HasNutrient amounts are per 100 g of the food, matching USDA FoodData Central
+type HasNutrient struct {
+	Amount   float64  `json:"amount"`
+	Nutrient Nutrient `json:"nutrient"`
+}
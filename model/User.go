@@ -0,0 +1,131 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+type User struct {
+	Id       string `json:"id"`
+	Username string `json:"username"`
+	// a bcrypt hash; never sent to clients
+	PasswordHash string `json:"-"`
+	Role         string `json:"role"`
+	// the households the user is a member of
+	HouseholdIds []string `json:"household_ids"`
+	Resource
+}
+
+// Each role can do everything the ones before it can. Everyone who signs up is a cook, except the first user, who is an admin.
+const ViewerRole = "viewer"   // can only read
+const CookRole = "cook"       // writes their own recipes and private foods
+const CuratorRole = "curator" // looks after the shared food catalog
+const AdminRole = "admin"     // can change anything
+
+var roles = []string{ViewerRole, CookRole, CuratorRole, AdminRole}
+
+func ParseRole(s string) (string, error) {
+	for _, role := range roles {
+		if s == role {
+			return role, nil
+		}
+	}
+	return "", fmt.Errorf("role %q is not one of %s", s, strings.Join(roles, ", "))
+}
+
+func roleRank(role string) int {
+	for i, r := range roles {
+		if role == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// HasRole reports whether the user has the role or one above it. A nil user has no role.
+func (u *User) HasRole(role string) bool {
+	return u != nil && roleRank(role) >= 0 && roleRank(u.Role) >= roleRank(role)
+}
+
+// CanEdit reports whether the user may change or delete something written by authorId.
+// Only admins can change what has no author. A nil user can't edit anything.
+func (u *User) CanEdit(authorId string) bool {
+	if u == nil {
+		return false
+	}
+	return u.HasRole(AdminRole) || (authorId != "" && u.Id == authorId && u.HasRole(CookRole))
+}
+
+func (u *User) InHousehold(householdId string) bool {
+	if u == nil || householdId == "" {
+		return false
+	}
+	for _, id := range u.HouseholdIds {
+		if id == householdId {
+			return true
+		}
+	}
+	return false
+}
+
+// CanEditRecipe reports whether the user may change or delete the recipe: its author, any cook in its household, or an admin
+func (u *User) CanEditRecipe(recipe Recipe) bool {
+	return u.CanEdit(recipe.AuthorId) || (u.InHousehold(recipe.HouseholdId) && u.HasRole(CookRole))
+}
+
+// CanEditFood reports whether the user may change or delete the food. Catalog foods are the curators';
+// private foods are their author's.
+func (u *User) CanEditFood(food Food) bool {
+	if food.InCatalog() {
+		return u.HasRole(CuratorRole)
+	}
+	return u.CanEdit(food.AuthorId)
+}
+
+// Viewer is what the user may see. Admins see everything, so they can look after any recipe.
+func (u *User) Viewer() Viewer {
+	if u == nil {
+		return Viewer{}
+	}
+	return Viewer{UserId: u.Id, HouseholdIds: u.HouseholdIds, All: u.HasRole(AdminRole)}
+}
+
+// ApiKey authenticates API requests as its user. Only a hash of the key is stored, so the key itself is shown once, when it's created.
+type ApiKey struct {
+	Id       string     `json:"id"`
+	Name     string     `json:"name"`
+	LastUsed *time.Time `json:"last_used"`
+	Resource
+}
+
+var ErrUsernameTaken = errors.New("username is taken")
+
+// UserRepository stores users along with the sessions and API keys they sign in with.
+// Session tokens and API keys are looked up by a hash, never by the token itself.
+type UserRepository interface {
+	GetById(ctx context.Context, id string) (*User, bool, error)
+	GetByUsername(ctx context.Context, username string) (*User, bool, error)
+	// Create returns ErrUsernameTaken when another user already has the username
+	Create(ctx context.Context, user User) (*User, error)
+	HasUsers(ctx context.Context) (bool, error)
+	// CreateFirst creates the user only if there are no users yet, checking in the same transaction, and reports whether it did.
+	// Of two signups at once, only one can be first.
+	CreateFirst(ctx context.Context, user User) (*User, bool, error)
+	// SetRole reports whether there was a user with the username
+	SetRole(ctx context.Context, username string, role string) (*User, bool, error)
+
+	CreateSession(ctx context.Context, userId string, tokenHash string, expires time.Time) error
+	// GetSessionUser returns the user of a session that hasn't expired
+	GetSessionUser(ctx context.Context, tokenHash string) (*User, bool, error)
+	DeleteSession(ctx context.Context, tokenHash string) error
+
+	CreateApiKey(ctx context.Context, userId string, name string, tokenHash string) (*ApiKey, error)
+	GetApiKeys(ctx context.Context, userId string) ([]ApiKey, error)
+	// GetApiKeyUser returns the user of a key that hasn't been deleted, and records that the key was used
+	GetApiKeyUser(ctx context.Context, tokenHash string) (*User, bool, error)
+	// DeleteApiKey only deletes the user's own keys, and reports whether there was one to delete
+	DeleteApiKey(ctx context.Context, userId string, id string) (bool, error)
+}
@@ -0,0 +1,39 @@
+package model
+
+import "fmt"
+
+const PublicVisibility = "public"
+const PrivateVisibility = "private"
+
+func ParseVisibility(s string) (string, error) {
+	switch s {
+	case PublicVisibility, PrivateVisibility:
+		return s, nil
+	default:
+		return "", fmt.Errorf("visibility %q is not one of %s or %s", s, PublicVisibility, PrivateVisibility)
+	}
+}
+
+// Viewer is who a query runs for. The zero value is an anonymous visitor, who only sees public resources.
+type Viewer struct {
+	UserId string
+	// sees what belongs to these households
+	HouseholdIds []string
+	// sees everything regardless of visibility, for admins and maintenance like exports
+	All bool
+}
+
+// CanSee reports whether the viewer may see a resource with the given visibility, author and household,
+// where householdId is empty for resources that don't belong to one.
+// Anything without a visibility is public, like recipes from before there were users.
+func (v Viewer) CanSee(visibility string, authorId string, householdId string) bool {
+	if v.All || visibility != PrivateVisibility || (v.UserId != "" && v.UserId == authorId) {
+		return true
+	}
+	for _, id := range v.HouseholdIds {
+		if householdId != "" && id == householdId {
+			return true
+		}
+	}
+	return false
+}
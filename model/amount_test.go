@@ -0,0 +1,105 @@
+package model_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAmount(t *testing.T) {
+	type testCase struct {
+		name        string
+		input       string
+		shouldError bool
+		expected    model.Amount
+	}
+
+	testCases := []testCase{
+		{name: "Integer", input: "2", expected: model.ExactAmount(2)},
+		{name: "Decimal", input: "1.5", expected: model.ExactAmount(1.5)},
+		{name: "Fraction", input: "1/2", expected: model.ExactAmount(0.5)},
+		{name: "Mixed number", input: "1 1/2", expected: model.ExactAmount(1.5)},
+		{name: "Unicode fraction", input: "1½", expected: model.ExactAmount(1.5)},
+		{name: "Range with a hyphen", input: "2-3", expected: model.AmountRange(2, 3)},
+		{name: "Range with an en dash", input: "2–3", expected: model.AmountRange(2, 3)},
+		{name: "Range with words", input: "1/2 to 3/4", expected: model.AmountRange(0.5, 0.75)},
+		{name: "Not a number", input: "some", shouldError: true},
+		{name: "Fraction before a whole number", input: "1/2 1", shouldError: true},
+		{name: "Zero denominator", input: "1/0", shouldError: true},
+		{name: "Empty string", input: "", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := model.ParseAmount(tc.input)
+			if tc.shouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestAmountString(t *testing.T) {
+	type testCase struct {
+		name     string
+		amount   model.Amount
+		expected string
+	}
+
+	testCases := []testCase{
+		{name: "Whole number", amount: model.ExactAmount(2), expected: "2"},
+		{name: "Half", amount: model.ExactAmount(0.5), expected: "1/2"},
+		{name: "Mixed number", amount: model.ExactAmount(1.75), expected: "1 3/4"},
+		{name: "Thirds", amount: model.ExactAmount(1.0 / 3), expected: "1/3"},
+		{name: "Almost whole", amount: model.ExactAmount(2.999), expected: "3"},
+		{name: "Not a nice fraction", amount: model.ExactAmount(1.3), expected: "1.3"},
+		{name: "Range", amount: model.AmountRange(2, 3), expected: "2–3"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.amount.String())
+		})
+	}
+}
+
+func TestAmountJson(t *testing.T) {
+	type testCase struct {
+		name         string
+		input        string
+		shouldError  bool
+		expected     model.Amount
+		expectedJson string
+	}
+
+	testCases := []testCase{
+		{name: "Number", input: "1.5", expected: model.ExactAmount(1.5), expectedJson: "1.5"},
+		{name: "String", input: `"1 1/2"`, expected: model.ExactAmount(1.5), expectedJson: "1.5"},
+		{name: "Range object", input: `{"min": 2, "max": 3}`, expected: model.AmountRange(2, 3), expectedJson: `{"min":2,"max":3}`},
+		{name: "Range string", input: `"2-3"`, expected: model.AmountRange(2, 3), expectedJson: `{"min":2,"max":3}`},
+		{name: "Invalid", input: `true`, shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual model.Amount
+			err := json.Unmarshal([]byte(tc.input), &actual)
+			if tc.shouldError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+
+			marshalled, err := json.Marshal(actual)
+			assert.NoError(t, err)
+			assert.JSONEq(t, tc.expectedJson, string(marshalled))
+		})
+	}
+}
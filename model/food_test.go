@@ -0,0 +1,125 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoodToGrams(t *testing.T) {
+	density := 0.5
+
+	type testCase struct {
+		name       string
+		food       model.Food
+		amount     float64
+		unit       string
+		shouldFind bool
+		expected   float64
+	}
+
+	testCases := []testCase{
+		{
+			name:       "Mass units don't need anything from the food",
+			food:       model.Food{},
+			amount:     2,
+			unit:       "kg",
+			shouldFind: true,
+			expected:   2000,
+		},
+		{
+			name:       "Volume with a density",
+			food:       model.Food{Density: &density},
+			amount:     100,
+			unit:       "ml",
+			shouldFind: true,
+			expected:   50,
+		},
+		{
+			name:       "Volume with a matching portion",
+			food:       model.Food{Density: &density, Portions: []model.Portion{{Amount: 1, Unit: "cup", GramWeight: 125}}},
+			amount:     2,
+			unit:       "cups",
+			shouldFind: true,
+			expected:   250,
+		},
+		{
+			name:       "Portion units match by canonical name",
+			food:       model.Food{Portions: []model.Portion{{Amount: 1, Unit: "tablespoon", GramWeight: 8}}},
+			amount:     3,
+			unit:       "tbsp",
+			shouldFind: true,
+			expected:   24,
+		},
+		{
+			name:       "Density from a portion in a different volume unit",
+			food:       model.Food{Portions: []model.Portion{{Amount: 1, Unit: "tablespoon", GramWeight: 14.78676478125}}},
+			amount:     1,
+			unit:       "cup",
+			shouldFind: true,
+			expected:   236.5882365,
+		},
+		{
+			name:       "Food specific units",
+			food:       model.Food{Portions: []model.Portion{{Amount: 1, Unit: "clove", GramWeight: 3}}},
+			amount:     4,
+			unit:       "cloves",
+			shouldFind: true,
+			expected:   12,
+		},
+		{
+			name:       "Units that aren't in the registry",
+			food:       model.Food{Portions: []model.Portion{{Amount: 1, Unit: "medium", GramWeight: 182}}},
+			amount:     2,
+			unit:       "Medium",
+			shouldFind: true,
+			expected:   364,
+		},
+		{
+			name:       "Volume without a density or portions",
+			food:       model.Food{},
+			amount:     1,
+			unit:       "cup",
+			shouldFind: false,
+		},
+		{
+			name:       "Counts without a portion",
+			food:       model.Food{Density: &density},
+			amount:     1,
+			unit:       "each",
+			shouldFind: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			grams, found := tc.food.ToGrams(tc.amount, tc.unit)
+			assert.Equal(t, tc.shouldFind, found)
+			assert.InDelta(t, tc.expected, grams, 1e-9)
+		})
+	}
+}
+
+func TestFoodMeasures(t *testing.T) {
+	potato := model.Food{Portions: []model.Portion{{Amount: 1, Unit: "medium", GramWeight: 213}}}
+
+	type testCase struct {
+		name     string
+		unit     string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{name: "Registered unit", unit: "cups", expected: true},
+		{name: "Portion", unit: "medium", expected: true},
+		{name: "Portion in another case", unit: " Medium ", expected: true},
+		{name: "Neither", unit: "large", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, potato.Measures(tc.unit))
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	type testCase struct {
+		name        string
+		input       string
+		shouldError bool
+		expected    []model.SortField
+	}
+
+	testCases := []testCase{
+		{name: "Empty", input: "", expected: []model.SortField{}},
+		{name: "Ascending", input: "name", expected: []model.SortField{{Field: "name"}}},
+		{name: "Descending", input: "-created", expected: []model.SortField{{Field: "created", Descending: true}}},
+		{name: "Multiple fields", input: "name, -modified", expected: []model.SortField{{Field: "name"}, {Field: "modified", Descending: true}}},
+		{name: "Unknown field", input: "calories", shouldError: true},
+		{name: "Repeated field", input: "name,-name", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := model.ParseSort(tc.input)
+			if tc.shouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
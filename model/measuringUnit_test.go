@@ -0,0 +1,139 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookupUnit(t *testing.T) {
+	type testCase struct {
+		name         string
+		input        string
+		shouldFind   bool
+		expectedName string
+	}
+
+	testCases := []testCase{
+		{name: "Canonical name", input: "cup", shouldFind: true, expectedName: "cup"},
+		{name: "Alias", input: "cups", shouldFind: true, expectedName: "cup"},
+		{name: "Short alias", input: "c", shouldFind: true, expectedName: "cup"},
+		{name: "Case insensitive", input: "Tablespoons", shouldFind: true, expectedName: "tbsp"},
+		{name: "Case sensitive when ambiguous (tsp)", input: "t", shouldFind: true, expectedName: "tsp"},
+		{name: "Case sensitive when ambiguous (tbsp)", input: "T", shouldFind: true, expectedName: "tbsp"},
+		{name: "Extra whitespace", input: "  fl   oz ", shouldFind: true, expectedName: "fl oz"},
+		{name: "Unknown unit", input: "smidgen", shouldFind: false},
+		{name: "Empty string", input: "", shouldFind: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			unit, found := model.LookupUnit(tc.input)
+			assert.Equal(t, tc.shouldFind, found)
+			assert.Equal(t, tc.expectedName, unit.Name)
+		})
+	}
+}
+
+func TestConvertUnits(t *testing.T) {
+	type testCase struct {
+		name        string
+		amount      float64
+		from        string
+		to          string
+		shouldError bool
+		expected    float64
+	}
+
+	testCases := []testCase{
+		{name: "Same unit", amount: 3, from: "g", to: "g", expected: 3},
+		{name: "Metric mass", amount: 1.5, from: "kg", to: "g", expected: 1500},
+		{name: "US customary to metric mass", amount: 1, from: "lb", to: "g", expected: 453.59237},
+		{name: "US customary volume", amount: 1, from: "cup", to: "tbsp", expected: 16},
+		{name: "Metric to US customary volume", amount: 236.5882365, from: "ml", to: "cups", expected: 1},
+		{name: "Count", amount: 2, from: "dozen", to: "each", expected: 24},
+		{name: "Different dimensions", amount: 1, from: "cup", to: "g", shouldError: true},
+		{name: "Unknown unit", amount: 1, from: "smidgen", to: "g", shouldError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := model.ConvertUnits(tc.amount, tc.from, tc.to)
+			if tc.shouldError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.InDelta(t, tc.expected, actual, 1e-9)
+			}
+		})
+	}
+}
+
+func TestConvertToSystem(t *testing.T) {
+	type testCase struct {
+		name         string
+		amount       float64
+		from         string
+		system       model.MeasuringSystem
+		expected     float64
+		expectedUnit string
+	}
+
+	testCases := []testCase{
+		{name: "Grams to ounces", amount: 56.69904625, from: "g", system: model.UsCustomary, expected: 2, expectedUnit: "oz"},
+		{name: "Grams to pounds", amount: 907.18474, from: "g", system: model.UsCustomary, expected: 2, expectedUnit: "lb"},
+		{name: "Cups to liters", amount: 8, from: "cup", system: model.Metric, expected: 1.892705892, expectedUnit: "l"},
+		{name: "Small amounts use the smallest unit", amount: 2, from: "ml", system: model.UsCustomary, expected: 2 / 4.92892159375, expectedUnit: "tsp"},
+		{name: "Within a system", amount: 48, from: "tsp", system: model.UsCustomary, expected: 1, expectedUnit: "cup"},
+		{name: "Counts stay counts", amount: 2, from: "dozen", system: model.Metric, expected: 24, expectedUnit: "each"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			from, found := model.LookupUnit(tc.from)
+			assert.True(t, found)
+
+			actual, unit, err := model.ConvertToSystem(tc.amount, from, tc.system)
+			assert.NoError(t, err)
+			assert.InDelta(t, tc.expected, actual, 1e-9)
+			assert.Equal(t, tc.expectedUnit, unit.Name)
+		})
+	}
+}
+
+func TestPromoteUnit(t *testing.T) {
+	type testCase struct {
+		name           string
+		amount         model.Amount
+		unit           string
+		expectedAmount model.Amount
+		expectedUnit   string
+	}
+
+	testCases := []testCase{
+		{name: "Teaspoons to cups", amount: model.ExactAmount(48), unit: "tsp", expectedAmount: model.ExactAmount(1), expectedUnit: "cup"},
+		{name: "Teaspoons to tablespoons", amount: model.ExactAmount(6), unit: "tsp", expectedAmount: model.ExactAmount(2), expectedUnit: "tbsp"},
+		{name: "Common fractions of a cup stay in cups", amount: model.ExactAmount(0.25), unit: "cup", expectedAmount: model.ExactAmount(0.25), expectedUnit: "cup"},
+		{name: "Small amounts move down", amount: model.ExactAmount(0.125), unit: "cup", expectedAmount: model.ExactAmount(2), expectedUnit: "tbsp"},
+		{name: "Grams to kilograms", amount: model.ExactAmount(1500), unit: "g", expectedAmount: model.ExactAmount(1.5), expectedUnit: "kg"},
+		{name: "Stays in the same system", amount: model.ExactAmount(32), unit: "oz", expectedAmount: model.ExactAmount(2), expectedUnit: "lb"},
+		{name: "Ranges use the same unit for both ends", amount: model.AmountRange(3, 6), unit: "tsp", expectedAmount: model.AmountRange(1, 2), expectedUnit: "tbsp"},
+		{name: "Original unit is kept when it fits", amount: model.ExactAmount(2), unit: "dozen", expectedAmount: model.ExactAmount(2), expectedUnit: "dozen"},
+		{name: "Food specific units are left alone", amount: model.ExactAmount(12), unit: "cloves", expectedAmount: model.ExactAmount(12), expectedUnit: "cloves"},
+		{name: "Unknown units are left alone", amount: model.ExactAmount(3), unit: "smidgen", expectedAmount: model.ExactAmount(3), expectedUnit: "smidgen"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			amount, unit := model.PromoteUnit(tc.amount, tc.unit)
+			assert.Equal(t, tc.expectedUnit, unit)
+			assert.InDelta(t, tc.expectedAmount.Min, amount.Min, 1e-9)
+			if tc.expectedAmount.Max == nil {
+				assert.Nil(t, amount.Max)
+			} else {
+				assert.InDelta(t, *tc.expectedAmount.Max, *amount.Max, 1e-9)
+			}
+		})
+	}
+}
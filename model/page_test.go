@@ -0,0 +1,26 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := model.Cursor{Id: "grn:tm-food:food:resource:123"}
+
+	decoded, err := model.DecodeCursor(cursor.Encode())
+
+	assert.NoError(t, err)
+	assert.Equal(t, cursor, *decoded)
+}
+
+func TestDecodeCursorMalformed(t *testing.T) {
+	for _, input := range []string{"not base64!", "bm90IGpzb24", "e30"} {
+		t.Run(input, func(t *testing.T) {
+			_, err := model.DecodeCursor(input)
+			assert.Error(t, err)
+		})
+	}
+}
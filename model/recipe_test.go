@@ -0,0 +1,51 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecipeScale(t *testing.T) {
+	servings := int64(4)
+	recipe := model.Recipe{
+		Title: "test recipe",
+		Ingredients: []model.ContainsIngredient{
+			{Unit: "tsp", Amount: model.ExactAmount(1), IngredientId: "salt"},
+			{Unit: "cup", Amount: model.ExactAmount(0.5), IngredientId: "flour"},
+			{Unit: "clove", Amount: model.AmountRange(2, 3), IngredientId: "garlic"},
+		},
+		Servings: &servings,
+	}
+
+	scaled := recipe.Scale(2)
+
+	assert := assert.New(t)
+	assert.Equal(int64(8), *scaled.Servings)
+	assert.Equal([]model.ContainsIngredient{
+		{Unit: "tsp", Amount: model.ExactAmount(2), IngredientId: "salt"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "flour"},
+		{Unit: "clove", Amount: model.AmountRange(4, 6), IngredientId: "garlic"},
+	}, scaled.Ingredients)
+	// the original recipe is left alone
+	assert.Equal(int64(4), *recipe.Servings)
+	assert.Equal(model.ExactAmount(1), recipe.Ingredients[0].Amount)
+}
+
+func TestRecipeScaleWithoutServings(t *testing.T) {
+	recipe := model.Recipe{
+		Title: "test recipe",
+		Ingredients: []model.ContainsIngredient{
+			{Unit: "tbsp", Amount: model.ExactAmount(1), IngredientId: "butter"},
+		},
+	}
+
+	scaled := recipe.Scale(0.5)
+
+	assert := assert.New(t)
+	assert.Nil(scaled.Servings)
+	assert.Equal([]model.ContainsIngredient{
+		{Unit: "tsp", Amount: model.ExactAmount(1.5), IngredientId: "butter"},
+	}, scaled.Ingredients)
+}
@@ -0,0 +1,93 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserHasRole(t *testing.T) {
+	type testCase struct {
+		name     string
+		user     *model.User
+		role     string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{name: "Same role", user: &model.User{Role: model.CookRole}, role: model.CookRole, expected: true},
+		{name: "Higher role", user: &model.User{Role: model.AdminRole}, role: model.CuratorRole, expected: true},
+		{name: "Lower role", user: &model.User{Role: model.ViewerRole}, role: model.CookRole, expected: false},
+		{name: "Unknown role", user: &model.User{Role: "chef"}, role: model.ViewerRole, expected: false},
+		{name: "Anonymous", user: nil, role: model.ViewerRole, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.user.HasRole(tc.role))
+		})
+	}
+}
+
+func TestUserCanEditFood(t *testing.T) {
+	catalog := model.Food{Id: "1", Visibility: model.PublicVisibility}
+	custom := model.Food{Id: "2", AuthorId: "cook", Visibility: model.PrivateVisibility}
+
+	type testCase struct {
+		name     string
+		user     *model.User
+		food     model.Food
+		expected bool
+	}
+
+	testCases := []testCase{
+		{name: "Cook, catalog food", user: &model.User{Id: "cook", Role: model.CookRole}, food: catalog, expected: false},
+		{name: "Curator, catalog food", user: &model.User{Id: "curator", Role: model.CuratorRole}, food: catalog, expected: true},
+		{name: "Author, custom food", user: &model.User{Id: "cook", Role: model.CookRole}, food: custom, expected: true},
+		{name: "Author demoted to viewer, custom food", user: &model.User{Id: "cook", Role: model.ViewerRole}, food: custom, expected: false},
+		{name: "Curator, someone's custom food", user: &model.User{Id: "curator", Role: model.CuratorRole}, food: custom, expected: false},
+		{name: "Admin, someone's custom food", user: &model.User{Id: "admin", Role: model.AdminRole}, food: custom, expected: true},
+		{name: "Anonymous, catalog food", user: nil, food: catalog, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.user.CanEditFood(tc.food))
+		})
+	}
+}
+
+func TestUserCanEditRecipe(t *testing.T) {
+	recipe := model.Recipe{Id: "1", AuthorId: "cook", Visibility: model.PrivateVisibility, HouseholdId: "home"}
+
+	type testCase struct {
+		name     string
+		user     *model.User
+		expected bool
+	}
+
+	testCases := []testCase{
+		{name: "Author", user: &model.User{Id: "cook", Role: model.CookRole}, expected: true},
+		{name: "Household member", user: &model.User{Id: "member", Role: model.CookRole, HouseholdIds: []string{"home"}}, expected: true},
+		{name: "Household member who is a viewer", user: &model.User{Id: "member", Role: model.ViewerRole, HouseholdIds: []string{"home"}}, expected: false},
+		{name: "Member of another household", user: &model.User{Id: "member", Role: model.CookRole, HouseholdIds: []string{"away"}}, expected: false},
+		{name: "Admin", user: &model.User{Id: "admin", Role: model.AdminRole}, expected: true},
+		{name: "Anonymous", user: nil, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.user.CanEditRecipe(recipe))
+		})
+	}
+}
+
+func TestParseRole(t *testing.T) {
+	role, err := model.ParseRole("curator")
+	assert.NoError(t, err)
+	assert.Equal(t, model.CuratorRole, role)
+
+	_, err = model.ParseRole("chef")
+	assert.Error(t, err)
+}
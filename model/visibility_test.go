@@ -0,0 +1,47 @@
+package model_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewerCanSee(t *testing.T) {
+	type testCase struct {
+		name       string
+		viewer     model.Viewer
+		visibility string
+		authorId   string
+		household  string
+		expected   bool
+	}
+
+	testCases := []testCase{
+		{name: "Anonymous, public", viewer: model.Viewer{}, visibility: model.PublicVisibility, authorId: "1", expected: true},
+		{name: "Anonymous, private", viewer: model.Viewer{}, visibility: model.PrivateVisibility, authorId: "1", expected: false},
+		{name: "Anonymous, no visibility", viewer: model.Viewer{}, visibility: "", expected: true},
+		{name: "Author, private", viewer: model.Viewer{UserId: "1"}, visibility: model.PrivateVisibility, authorId: "1", expected: true},
+		{name: "Someone else, private", viewer: model.Viewer{UserId: "2"}, visibility: model.PrivateVisibility, authorId: "1", expected: false},
+		{name: "Anonymous, private without an author", viewer: model.Viewer{}, visibility: model.PrivateVisibility, authorId: "", expected: false},
+		{name: "Everything, private", viewer: model.Viewer{All: true}, visibility: model.PrivateVisibility, authorId: "1", expected: true},
+		{name: "Household member, private", viewer: model.Viewer{UserId: "2", HouseholdIds: []string{"h"}}, visibility: model.PrivateVisibility, authorId: "1", household: "h", expected: true},
+		{name: "Other household, private", viewer: model.Viewer{UserId: "2", HouseholdIds: []string{"g"}}, visibility: model.PrivateVisibility, authorId: "1", household: "h", expected: false},
+		{name: "Household member, private without a household", viewer: model.Viewer{UserId: "2", HouseholdIds: []string{"h"}}, visibility: model.PrivateVisibility, authorId: "1", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.viewer.CanSee(tc.visibility, tc.authorId, tc.household))
+		})
+	}
+}
+
+func TestParseVisibility(t *testing.T) {
+	visibility, err := model.ParseVisibility("private")
+	assert.NoError(t, err)
+	assert.Equal(t, model.PrivateVisibility, visibility)
+
+	_, err = model.ParseVisibility("friends")
+	assert.Error(t, err)
+}
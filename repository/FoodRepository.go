@@ -8,34 +8,42 @@ import (
 	"time"
 
 	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/db"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
 )
 
 type FoodRepository struct {
-	driver neo4j.DriverWithContext // TODO *neo4j.DriverWithContext?
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext // TODO *neo4j.DriverWithContext?
 }
 
-func NewFoodRepository(driver neo4j.DriverWithContext) *FoodRepository {
-	return &FoodRepository{driver: driver}
+func NewFoodRepository(driver neo4j.DriverWithContext, database string) *FoodRepository {
+	return &FoodRepository{driver: driver, database: database}
 }
 
-func (r *FoodRepository) GetAll(ctx context.Context) ([]model.Food, error) {
-	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.Food, error) {
-		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.Food, error) {
-			*query = fmt.Sprintf("MATCH (i:`%s`) WHERE i.deleted IS NULL\n"+
-				"RETURN i",
-				FoodLabel)
+func (r *FoodRepository) GetAll(ctx context.Context, listQuery model.ListQuery) (model.Page[model.Food], error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (model.Page[model.Food], error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (model.Page[model.Food], error) {
 			params = map[string]any{}
+			where, orderBy, err := listClauses("i", foodListFields, listQuery, params)
+			if err != nil {
+				return model.Page[model.Food]{}, err
+			}
+			*query = fmt.Sprintf("MATCH (i:`%s`) WHERE %s\n"+
+				"RETURN i, %s ORDER BY %s LIMIT $limit",
+				FoodLabel, where, authorIdColumn("i"), orderBy)
 
 			result, err := tx.Run(ctx, *query, params)
 			if err != nil {
-				return nil, err
+				return model.Page[model.Food]{}, err
 			}
 
 			records, err := result.Collect(ctx)
 			if err != nil {
-				return nil, err
+				return model.Page[model.Food]{}, err
 			}
 
 			foods := make([]model.Food, len(records))
@@ -49,25 +57,27 @@ func (r *FoodRepository) GetAll(ctx context.Context) ([]model.Food, error) {
 
 				food, err := ParseFoodNode(node)
 				if err != nil {
-					return nil, err
+					return model.Page[model.Food]{}, err
 				}
+				food.AuthorId = authorId(records[i])
 
 				foods[i] = *food
 			}
 
-			return foods, nil
+			return toPage(foods, listQuery, foodListFields, func(f model.Food) string { return f.Id }), nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "get all foods", neo4j.AccessModeRead, work)
+	return RunQuery(ctx, r.driver, r.database, "get all foods", neo4j.AccessModeRead, work)
 }
 
 func (r *FoodRepository) GetById(ctx context.Context, id string) (*model.Food, bool, error) {
 	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Food, error) {
 		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Food, error) {
 			*query = fmt.Sprintf("%s WHERE i.deleted IS NULL\n"+
-				"RETURN i",
-				MatchNodeById("i", []string{FoodLabel}))
+				"RETURN i, [(i)-[hn:`%s`]->(n:`%s`) | {nutrient: n, rel: hn}] AS nutrients, [(i)-[:`%s`]->(p:`%s`) | p] AS portions, %s",
+				MatchNodeById("i", []string{FoodLabel}),
+				HasNutrientLabel, NutrientLabel, HasPortionLabel, PortionLabel, authorIdColumn("i"))
 			params = map[string]any{
 				"iId": id,
 			}
@@ -77,16 +87,11 @@ func (r *FoodRepository) GetById(ctx context.Context, id string) (*model.Food, b
 				return nil, err
 			}
 
-			node, found := TypedGet[neo4j.Node](record, "i")
-			if !found {
-				return nil, fmt.Errorf("could not find column i")
-			}
-
-			return ParseFoodNode(node)
+			return parseFoodRecord(record)
 		})
 	}
 
-	food, err := RunQuery(ctx, r.driver, "get food", neo4j.AccessModeRead, work)
+	food, err := RunQuery(ctx, r.driver, r.database, "get food", neo4j.AccessModeRead, work)
 
 	if err != nil && err.Error() == "Result contains no more records" {
 		return nil, false, nil
@@ -102,18 +107,25 @@ func (r *FoodRepository) Create(ctx context.Context, food model.Food) (*model.Fo
 	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Food, error) {
 		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Food, error) {
 			labels := []string{FoodLabel, ResourceLabel}
-			id, err := model.ResourceId(labels)
+			id, err := newId(food.Id, labels)
 			if err != nil {
 				return nil, err
 			}
 
-			*query = fmt.Sprintf("CREATE (i:`%s`) SET i = {id: $id, name: $name, created: $created}\n"+
-				"RETURN i",
-				strings.Join(labels, "`:`"))
+			*query = fmt.Sprintf("CREATE (i:`%s`) SET i = {id: $id, name: $name, density: $density, visibility: $visibility, created: $created}\n"+
+				"WITH i OPTIONAL MATCH (a:`%s` {id: $authorId})\n"+
+				"FOREACH (author IN CASE WHEN a IS NULL THEN [] ELSE [a] END | CREATE (i)-[:`%s` {created: $created}]->(author))\n"+
+				"RETURN i, %s",
+				strings.Join(labels, "`:`"),
+				UserLabel, AuthoredByLabel,
+				authorIdColumn("i"))
 			params = map[string]any{
-				"id":      id,
-				"name":    food.Name,
-				"created": neo4j.LocalDateTime(time.Now()),
+				"id":         id,
+				"name":       food.Name,
+				"density":    food.Density,
+				"visibility": storedVisibility(food.Visibility),
+				"authorId":   food.AuthorId,
+				"created":    neo4j.LocalDateTime(time.Now()),
 			}
 
 			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
@@ -126,22 +138,30 @@ func (r *FoodRepository) Create(ctx context.Context, food model.Food) (*model.Fo
 				return nil, fmt.Errorf("could not find column i")
 			}
 
-			return ParseFoodNode(node)
+			food, err := ParseFoodNode(node)
+			if err != nil {
+				return nil, err
+			}
+			food.AuthorId = authorId(record)
+			return food, nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "create food", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "create food", neo4j.AccessModeWrite, work)
 }
 
 func (r *FoodRepository) Update(ctx context.Context, food model.Food) (*model.Food, error) {
 	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Food, error) {
 		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Food, error) {
-			*query = fmt.Sprintf("%s SET i += {name: $name, lastModified: $lastModified}\n"+
-				"RETURN i",
-				MatchNodeById("i", []string{FoodLabel}))
+			*query = fmt.Sprintf("%s WHERE i.deleted IS NULL\n"+
+				"SET i += {name: $name, density: $density, lastModified: $lastModified}\n"+
+				"RETURN i, [(i)-[hn:`%s`]->(n:`%s`) | {nutrient: n, rel: hn}] AS nutrients, [(i)-[:`%s`]->(p:`%s`) | p] AS portions, %s",
+				MatchNodeById("i", []string{FoodLabel}),
+				HasNutrientLabel, NutrientLabel, HasPortionLabel, PortionLabel, authorIdColumn("i"))
 			params = map[string]any{
 				"iId":          food.Id,
 				"name":         food.Name,
+				"density":      food.Density,
 				"lastModified": neo4j.LocalDateTime(time.Now()),
 			}
 
@@ -150,16 +170,15 @@ func (r *FoodRepository) Update(ctx context.Context, food model.Food) (*model.Fo
 				return nil, err
 			}
 
-			node, found := TypedGet[neo4j.Node](record, "i")
-			if !found {
-				return nil, fmt.Errorf("could not find column i")
+			if err := refreshIngredientsText(ctx, tx, food.Id); err != nil {
+				return nil, err
 			}
 
-			return ParseFoodNode(node)
+			return parseFoodRecord(record)
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "update food", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "update food", neo4j.AccessModeWrite, work)
 }
 
 func (r *FoodRepository) Delete(ctx context.Context, id string) (string, error) {
@@ -184,11 +203,78 @@ func (r *FoodRepository) Delete(ctx context.Context, id string) (string, error)
 				return "", errors.New("could not find column id")
 			}
 
+			if err := refreshIngredientsText(ctx, tx, deletedId); err != nil {
+				return "", err
+			}
+
 			return deletedId, nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "delete food", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "delete food", neo4j.AccessModeWrite, work)
+}
+
+func (r *FoodRepository) Search(ctx context.Context, search model.FoodSearch) ([]model.FoodSearchResult, error) {
+	fulltextQuery := FulltextQuery(search.Text)
+	if fulltextQuery == "" {
+		return []model.FoodSearchResult{}, nil
+	}
+
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.FoodSearchResult, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.FoodSearchResult, error) {
+			params = map[string]any{
+				"index":  FoodNameSearchIndex,
+				"search": fulltextQuery,
+				"skip":   search.Skip,
+				"limit":  search.Limit,
+			}
+			visible := "true"
+			if condition := visibleCondition("i", search.Viewer, params); condition != "" {
+				visible = condition
+			}
+			*query = fmt.Sprintf("CALL db.index.fulltext.queryNodes($index, $search) YIELD node AS i, score\n"+
+				"WHERE i:`%s` AND i.deleted IS NULL AND %s\n"+
+				"RETURN i, score, %s ORDER BY score DESC, i.id SKIP $skip LIMIT $limit",
+				FoodLabel, visible, authorIdColumn("i"))
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			results := make([]model.FoodSearchResult, len(records))
+
+			for i := 0; i < len(records); i++ {
+				node, found := TypedGet[neo4j.Node](records[i], "i")
+				if !found {
+					return nil, errors.New("could not find column i")
+				}
+
+				food, err := ParseFoodNode(node)
+				if err != nil {
+					return nil, err
+				}
+
+				score, found := TypedGet[float64](records[i], "score")
+				if !found {
+					return nil, errors.New("could not find column score")
+				}
+
+				food.AuthorId = authorId(records[i])
+
+				results[i] = model.FoodSearchResult{Food: *food, Score: score}
+			}
+
+			return results, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "search foods", neo4j.AccessModeRead, work)
 }
 
 func ParseFoodNode(node dbtype.Node) (*model.Food, error) {
@@ -202,10 +288,93 @@ func ParseFoodNode(node dbtype.Node) (*model.Food, error) {
 		return nil, err
 	}
 
+	density := new(float64)
+	rawDensity, err := neo4j.GetProperty[float64](node, "density")
+	if err != nil {
+		density = nil
+	} else {
+		*density = rawDensity
+	}
+
+	// the USDA import doesn't set a visibility, since everything it brings in is in the catalog
+	visibility, err := neo4j.GetProperty[string](node, "visibility")
+	if err != nil {
+		visibility = model.PublicVisibility
+	}
+
 	resource, err := ParseResourceEntity(node)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Food{Id: id, Name: name, Resource: *resource}, nil
+	return &model.Food{Id: id, Name: name, Density: density, Visibility: visibility, Resource: *resource}, nil
+}
+
+func parseFoodRecord(record *db.Record) (*model.Food, error) {
+	node, found := TypedGet[neo4j.Node](record, "i")
+	if !found {
+		return nil, errors.New("could not find column i")
+	}
+
+	food, err := ParseFoodNode(node)
+	if err != nil {
+		return nil, err
+	}
+	food.AuthorId = authorId(record)
+
+	rawNutrients, found := TypedGet[[]any](record, "nutrients")
+	if !found {
+		return nil, errors.New("could not find column nutrients")
+	}
+	nutrients := util.UnpackArray[map[string]any](rawNutrients)
+
+	err = setNutrients(food, nutrients)
+	if err != nil {
+		return nil, err
+	}
+
+	rawPortions, found := TypedGet[[]any](record, "portions")
+	if !found {
+		return nil, errors.New("could not find column portions")
+	}
+	portions := util.UnpackArray[neo4j.Node](rawPortions)
+
+	err = setPortions(food, portions)
+	if err != nil {
+		return nil, err
+	}
+	return food, nil
+}
+
+func setNutrients(food *model.Food, nutrients []map[string]any) error {
+	foodNutrients := []model.HasNutrient{}
+	for i := range nutrients {
+		nutrient := nutrients[i]["nutrient"].(neo4j.Node)
+		hasNutrientRel := nutrients[i]["rel"].(neo4j.Relationship)
+
+		hasNutrient, err := ParseHasNutrientRelationship(&nutrient, &hasNutrientRel)
+		if err != nil {
+			return err
+		}
+
+		foodNutrients = append(foodNutrients, *hasNutrient)
+	}
+
+	food.Nutrients = foodNutrients
+	return nil
+}
+
+func setPortions(food *model.Food, portions []neo4j.Node) error {
+	foodPortions := []model.Portion{}
+	for i := range portions {
+		portion, err := ParsePortionNode(portions[i])
+		if err != nil {
+			return err
+		}
+
+		foodPortions = append(foodPortions, *portion)
+	}
+
+	food.Portions = foodPortions
+	return nil
 }
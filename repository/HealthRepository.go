@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type HealthRepository struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewHealthRepository(driver neo4j.DriverWithContext, database string) *HealthRepository {
+	return &HealthRepository{driver: driver, database: database}
+}
+
+// Ready checks that the database is reachable and has the Resource id uniqueness constraint every repository relies on
+func (r *HealthRepository) Ready(ctx context.Context) error {
+	if err := r.driver.VerifyConnectivity(ctx); err != nil {
+		return fmt.Errorf("could not connect to neo4j: %w", err)
+	}
+
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (int64, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (int64, error) {
+			// the constraint type was renamed in later 5.x versions
+			*query = "SHOW CONSTRAINTS YIELD type, labelsOrTypes, properties\n" +
+				"WHERE type IN ['UNIQUENESS', 'NODE_PROPERTY_UNIQUENESS'] AND labelsOrTypes = [$label] AND properties = ['id']\n" +
+				"RETURN count(*) AS c"
+			params = map[string]any{"label": ResourceLabel}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return 0, err
+			}
+
+			count, found := TypedGet[int64](record, "c")
+			if !found {
+				return 0, errors.New("could not find column c")
+			}
+			return count, nil
+		})
+	}
+
+	count, err := RunQuery(ctx, r.driver, r.database, "check constraints", neo4j.AccessModeRead, work)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("missing uniqueness constraint on :%s(id)", ResourceLabel)
+	}
+	return nil
+}
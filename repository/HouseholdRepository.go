@@ -0,0 +1,366 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+type HouseholdRepository struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewHouseholdRepository(driver neo4j.DriverWithContext, database string) *HouseholdRepository {
+	return &HouseholdRepository{driver: driver, database: database}
+}
+
+// matchMemberHousehold matches the live household with id $hId as h, only when the user with id $uId is one of its members
+func matchMemberHousehold() string {
+	return fmt.Sprintf("%s-[member:`%s`]->(h:`%s` {id: $hId})\n"+
+		"WHERE u.deleted IS NULL AND member.deleted IS NULL AND h.deleted IS NULL\n",
+		MatchNodeById("u", []string{UserLabel}), MemberOfLabel, HouseholdLabel)
+}
+
+// membersColumn returns the members of the household bound to h as members
+func membersColumn(h string) string {
+	return fmt.Sprintf("[(m:`%s`)-[rel:`%s`]->(%s) WHERE rel.deleted IS NULL AND m.deleted IS NULL | {userId: m.id, username: m.username, joined: rel.created}] AS members",
+		UserLabel, MemberOfLabel, h)
+}
+
+// parseHouseholdRecord reads a household from the columns h and members
+func parseHouseholdRecord(record *neo4j.Record) (*model.Household, error) {
+	householdNode, found := TypedGet[neo4j.Node](record, "h")
+	if !found {
+		return nil, errors.New("could not find column h")
+	}
+	household, err := ParseHouseholdNode(householdNode)
+	if err != nil {
+		return nil, err
+	}
+
+	rawMembers, found := TypedGet[[]any](record, "members")
+	if !found {
+		return nil, errors.New("could not find column members")
+	}
+	for _, rawMember := range util.UnpackArray[map[string]any](rawMembers) {
+		joined := rawMember["joined"].(neo4j.LocalDateTime).Time()
+		household.Members = append(household.Members, model.HouseholdMember{
+			UserId:   rawMember["userId"].(string),
+			Username: rawMember["username"].(string),
+			Joined:   &joined,
+		})
+	}
+	sortMembers(household.Members)
+	return household, nil
+}
+
+func (r *HouseholdRepository) Create(ctx context.Context, household model.Household, userId string) (*model.Household, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Household, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Household, error) {
+			labels := []string{HouseholdLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			*query = fmt.Sprintf("%s WHERE u.deleted IS NULL\n"+
+				"CREATE (u)-[:`%s` {created: $created}]->(h:`%s`) SET h = {id: $id, name: $name, created: $created}\n"+
+				"RETURN h, %s",
+				MatchNodeById("u", []string{UserLabel}), MemberOfLabel, strings.Join(labels, "`:`"), membersColumn("h"))
+			params = map[string]any{
+				"uId":     userId,
+				"id":      id,
+				"name":    household.Name,
+				"created": neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return parseHouseholdRecord(record)
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "create household", neo4j.AccessModeWrite, work)
+}
+
+func (r *HouseholdRepository) GetById(ctx context.Context, id string, userId string) (*model.Household, bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Household, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Household, error) {
+			*query = matchMemberHousehold() + "RETURN h, " + membersColumn("h")
+			params = map[string]any{"hId": id, "uId": userId}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return parseHouseholdRecord(record)
+		})
+	}
+
+	household, err := RunQuery(ctx, r.driver, r.database, "get household", neo4j.AccessModeRead, work)
+
+	if err != nil && err.Error() == "Result contains no more records" {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return household, true, nil
+}
+
+func (r *HouseholdRepository) GetForUser(ctx context.Context, userId string) ([]model.Household, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.Household, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.Household, error) {
+			*query = fmt.Sprintf("%s-[rel:`%s`]->(h:`%s`) WHERE rel.deleted IS NULL AND h.deleted IS NULL\n"+
+				"RETURN h, %s ORDER BY h.name, h.id",
+				MatchNodeById("u", []string{UserLabel}), MemberOfLabel, HouseholdLabel, membersColumn("h"))
+			params = map[string]any{"uId": userId}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			households := []model.Household{}
+			for _, record := range records {
+				household, err := parseHouseholdRecord(record)
+				if err != nil {
+					return nil, err
+				}
+				households = append(households, *household)
+			}
+			return households, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "get households", neo4j.AccessModeRead, work)
+}
+
+// Leave ends the user's membership. When nobody is left, the household, its pending invitations and its lists are deleted;
+// its recipes stay with their authors.
+func (r *HouseholdRepository) Leave(ctx context.Context, householdId string, userId string) (bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (bool, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (bool, error) {
+			*query = fmt.Sprintf("%s-[rel:`%s`]->(h:`%s` {id: $householdId}) WHERE rel.deleted IS NULL AND h.deleted IS NULL\n"+
+				"SET rel.deleted = $deleted, h.lastModified = $deleted\n"+
+				"WITH h, count(rel) AS left\n"+
+				"CALL {\n"+
+				"  WITH h WITH h WHERE NOT exists { (m:`%s`)-[rel:`%s`]->(h) WHERE rel.deleted IS NULL AND m.deleted IS NULL }\n"+
+				"  SET h.deleted = $deleted\n"+
+				"  WITH h OPTIONAL MATCH (h)-[hi:`%s`]->(i:`%s`) WHERE i.deleted IS NULL\n"+
+				"  SET hi.deleted = $deleted, i.deleted = $deleted\n"+
+				"  WITH DISTINCT h OPTIONAL MATCH (h)-[hl:`%s`]->(l:`%s`) WHERE l.deleted IS NULL\n"+
+				"  SET hl.deleted = $deleted, l.deleted = $deleted\n"+
+				"}\n"+
+				"RETURN left",
+				MatchNodeById("u", []string{UserLabel}), MemberOfLabel, HouseholdLabel,
+				UserLabel, MemberOfLabel,
+				HasInvitationLabel, InvitationLabel,
+				HasItemLabel, ListItemLabel)
+			params = map[string]any{
+				"uId":         userId,
+				"householdId": householdId,
+				"deleted":     neo4j.LocalDateTime(time.Now()),
+			}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return false, err
+			}
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return false, err
+			}
+			return len(records) > 0, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "leave household", neo4j.AccessModeWrite, work)
+}
+
+func (r *HouseholdRepository) Invite(ctx context.Context, householdId string, userId string, invitedBy string) error {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (any, error) {
+			labels := []string{InvitationLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			*query = fmt.Sprintf("%s WHERE h.deleted IS NULL\n"+
+				"MATCH (u:`%[2]s` {id: $userId}) WHERE u.deleted IS NULL\n"+
+				"MATCH (inviter:`%[2]s` {id: $invitedBy})-[inviterRel:`%[3]s`]->(h) WHERE inviterRel.deleted IS NULL AND inviter.deleted IS NULL\n"+
+				"RETURN h, u, exists { (u)-[rel:`%[3]s`]->(h) WHERE rel.deleted IS NULL } OR exists { (h)-[:`%[4]s`]->(i:`%[5]s`)-[:`%[6]s`]->(u) WHERE i.deleted IS NULL } AS invited",
+				MatchNodeById("h", []string{HouseholdLabel}), UserLabel, MemberOfLabel, HasInvitationLabel, InvitationLabel, InvitesLabel)
+			params = map[string]any{
+				"hId":       householdId,
+				"userId":    userId,
+				"invitedBy": invitedBy,
+				"id":        id,
+				"created":   neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil && err.Error() == "Result contains no more records" {
+				return nil, fmt.Errorf("household %s with member %s, or user %s, not found", householdId, invitedBy, userId)
+			} else if err != nil {
+				return nil, err
+			}
+			if invited, _ := TypedGet[bool](record, "invited"); invited {
+				return nil, nil
+			}
+
+			*query = fmt.Sprintf("%s\n"+
+				"MATCH (u:`%[2]s` {id: $userId})\n"+
+				"MATCH (inviter:`%[2]s` {id: $invitedBy})\n"+
+				"CREATE (h)-[:`%[3]s` {created: $created}]->(i:`%[4]s`)-[:`%[5]s` {created: $created}]->(u)\n"+
+				"SET i = {id: $id, created: $created}\n"+
+				"CREATE (i)-[:`%[6]s` {created: $created}]->(inviter)",
+				MatchNodeById("h", []string{HouseholdLabel}), UserLabel, HasInvitationLabel, strings.Join(labels, "`:`"), InvitesLabel, AuthoredByLabel)
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	}
+
+	_, err := RunQuery(ctx, r.driver, r.database, "invite to household", neo4j.AccessModeWrite, work)
+	return err
+}
+
+func (r *HouseholdRepository) GetInvitations(ctx context.Context, userId string) ([]model.Invitation, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.Invitation, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.Invitation, error) {
+			*query = fmt.Sprintf("MATCH (h:`%s`)-[:`%s`]->(i:`%s`)-[:`%s`]->(:`%s` {id: $userId})\n"+
+				"WHERE i.deleted IS NULL AND h.deleted IS NULL\n"+
+				"RETURN i, h.id AS householdId, h.name AS householdName, head([(i)-[:`%s`]->(inviter:`%s`) | inviter.username]) AS invitedBy\n"+
+				"ORDER BY i.created, i.id",
+				HouseholdLabel, HasInvitationLabel, InvitationLabel, InvitesLabel, UserLabel,
+				AuthoredByLabel, UserLabel)
+			params = map[string]any{"userId": userId}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			invitations := []model.Invitation{}
+			for _, record := range records {
+				invitationNode, found := TypedGet[neo4j.Node](record, "i")
+				if !found {
+					return nil, errors.New("could not find column i")
+				}
+				invitation, err := ParseInvitationNode(invitationNode)
+				if err != nil {
+					return nil, err
+				}
+				invitation.HouseholdId, _ = TypedGet[string](record, "householdId")
+				invitation.HouseholdName, _ = TypedGet[string](record, "householdName")
+				if invitedBy, found := record.Get("invitedBy"); found && invitedBy != nil {
+					invitation.InvitedBy = invitedBy.(string)
+				}
+				invitations = append(invitations, *invitation)
+			}
+			return invitations, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "get invitations", neo4j.AccessModeRead, work)
+}
+
+func (r *HouseholdRepository) Accept(ctx context.Context, userId string, invitationId string) (bool, error) {
+	// an invitation is only pending while its user isn't a member, so accepting it can't make a second membership
+	join := fmt.Sprintf("CREATE (u)-[:`%s` {created: $resolved}]->(h)\n", MemberOfLabel)
+	return r.resolve(ctx, "accept invitation", userId, invitationId, join)
+}
+
+func (r *HouseholdRepository) Decline(ctx context.Context, userId string, invitationId string) (bool, error) {
+	return r.resolve(ctx, "decline invitation", userId, invitationId, "")
+}
+
+// resolve deletes one of the user's pending invitations, running statement, with u, h and i in scope, before it's deleted
+func (r *HouseholdRepository) resolve(ctx context.Context, action string, userId string, invitationId string, statement string) (bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (bool, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (bool, error) {
+			*query = fmt.Sprintf("MATCH (h:`%s`)-[hi:`%s`]->(i:`%s` {id: $id})-[iu:`%s`]->(u:`%s` {id: $userId})\n"+
+				"WHERE i.deleted IS NULL AND h.deleted IS NULL\n"+
+				"%s"+
+				"SET i.deleted = $resolved, hi.deleted = $resolved, iu.deleted = $resolved\n"+
+				"RETURN count(i) AS resolved",
+				HouseholdLabel, HasInvitationLabel, InvitationLabel, InvitesLabel, UserLabel,
+				statement)
+			params = map[string]any{
+				"id":       invitationId,
+				"userId":   userId,
+				"resolved": neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return false, err
+			}
+
+			resolved, found := TypedGet[int64](record, "resolved")
+			if !found {
+				return false, errors.New("could not find column resolved")
+			}
+			return resolved > 0, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, action, neo4j.AccessModeWrite, work)
+}
+
+func ParseHouseholdNode(node dbtype.Node) (*model.Household, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := neo4j.GetProperty[string](node, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := ParseResourceEntity(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Household{Id: id, Name: name, Members: []model.HouseholdMember{}, Resource: *resource}, nil
+}
+
+func ParseInvitationNode(node dbtype.Node) (*model.Invitation, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := ParseResourceEntity(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Invitation{Id: id, Resource: *resource}, nil
+}
@@ -3,4 +3,27 @@ package repository
 var ResourceLabel string = "Resource"
 var FoodLabel string = "Food"
 var RecipeLabel string = "Recipe"
+var NutrientLabel string = "Nutrient"
+var PortionLabel string = "Portion"
+var UserLabel string = "User"
+var SessionLabel string = "Session"
+var ApiKeyLabel string = "ApiKey"
+var HouseholdLabel string = "Household"
+var InvitationLabel string = "Invitation"
+var ListItemLabel string = "ListItem"
+var LockLabel string = "Lock"
 var ContainsIngredientLabel string = "CONTAINS_INGREDIENT"
+var HasNutrientLabel string = "HAS_NUTRIENT"
+var HasPortionLabel string = "HAS_PORTION"
+var HasSessionLabel string = "HAS_SESSION"
+var HasApiKeyLabel string = "HAS_API_KEY"
+var AuthoredByLabel string = "AUTHORED_BY"
+var MemberOfLabel string = "MEMBER_OF"
+var BelongsToLabel string = "BELONGS_TO"
+var HasInvitationLabel string = "HAS_INVITATION"
+var InvitesLabel string = "INVITES"
+var HasItemLabel string = "HAS_ITEM"
+var OfFoodLabel string = "OF_FOOD"
+
+var FoodNameSearchIndex string = "food_name_search_idx"
+var RecipeSearchIndex string = "recipe_search_idx"
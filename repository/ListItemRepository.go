@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+type ListItemRepository struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewListItemRepository(driver neo4j.DriverWithContext, database string) *ListItemRepository {
+	return &ListItemRepository{driver: driver, database: database}
+}
+
+// matchListItems matches the live items of the household with id $hId as item, with hi the relationship to them and f their food,
+// when the user with id $uId is one of the household's members
+func matchListItems() string {
+	return matchMemberHousehold() + fmt.Sprintf("MATCH (h)-[hi:`%s`]->(item:`%s`)-[:`%s`]->(f:`%s`)\n"+
+		"WHERE hi.deleted IS NULL AND item.deleted IS NULL AND f.deleted IS NULL\n",
+		HasItemLabel, ListItemLabel, OfFoodLabel, FoodLabel)
+}
+
+// listItemColumns are read by parseListItemRecord
+var listItemColumns = "item, h.id AS householdId, f.id AS foodId, f.name AS foodName"
+
+func parseListItemRecord(record *neo4j.Record) (*model.ListItem, error) {
+	itemNode, found := TypedGet[neo4j.Node](record, "item")
+	if !found {
+		return nil, errors.New("could not find column item")
+	}
+	item, err := ParseListItemNode(itemNode)
+	if err != nil {
+		return nil, err
+	}
+	item.HouseholdId, _ = TypedGet[string](record, "householdId")
+	item.FoodId, _ = TypedGet[string](record, "foodId")
+	item.FoodName, _ = TypedGet[string](record, "foodName")
+	return item, nil
+}
+
+func (r *ListItemRepository) GetForHousehold(ctx context.Context, householdId string, userId string, list string) ([]model.ListItem, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.ListItem, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.ListItem, error) {
+			*query = matchListItems() + "AND item.list = $list\n" +
+				"RETURN " + listItemColumns + " ORDER BY f.name, item.id"
+			params = map[string]any{"hId": householdId, "uId": userId, "list": list}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			items := []model.ListItem{}
+			for _, record := range records {
+				item, err := parseListItemRecord(record)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, *item)
+			}
+			return items, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "get list items", neo4j.AccessModeRead, work)
+}
+
+func (r *ListItemRepository) GetById(ctx context.Context, householdId string, userId string, id string) (*model.ListItem, bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.ListItem, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (*model.ListItem, error) {
+			*query = matchListItems() + "AND item.id = $id\n" +
+				"RETURN " + listItemColumns
+			params = map[string]any{"hId": householdId, "uId": userId, "id": id}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return parseListItemRecord(record)
+		})
+	}
+
+	item, err := RunQuery(ctx, r.driver, r.database, "get list item", neo4j.AccessModeRead, work)
+
+	if err != nil && err.Error() == "Result contains no more records" {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return item, true, nil
+}
+
+func (r *ListItemRepository) Create(ctx context.Context, item model.ListItem, userId string) (*model.ListItem, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.ListItem, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.ListItem, error) {
+			labels := []string{ListItemLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			*query = matchMemberHousehold() + fmt.Sprintf("MATCH (f:`%s` {id: $foodId}) WHERE f.deleted IS NULL\n"+
+				"CREATE (h)-[:`%s` {created: $created}]->(item:`%s`)-[:`%s` {created: $created}]->(f)\n"+
+				"SET item = {id: $id, list: $list, quantity: $quantity, created: $created}\n"+
+				"RETURN %s",
+				FoodLabel,
+				HasItemLabel, strings.Join(labels, "`:`"), OfFoodLabel,
+				listItemColumns)
+			params = map[string]any{
+				"hId":      item.HouseholdId,
+				"uId":      userId,
+				"foodId":   item.FoodId,
+				"id":       id,
+				"list":     item.List,
+				"quantity": item.Quantity,
+				"created":  neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil && err.Error() == "Result contains no more records" {
+				return nil, fmt.Errorf("household %s or food %s not found", item.HouseholdId, item.FoodId)
+			} else if err != nil {
+				return nil, err
+			}
+			return parseListItemRecord(record)
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "create list item", neo4j.AccessModeWrite, work)
+}
+
+func (r *ListItemRepository) Update(ctx context.Context, item model.ListItem, userId string) (*model.ListItem, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.ListItem, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.ListItem, error) {
+			*query = matchListItems() + "AND item.id = $id\n" +
+				"SET item += {list: $list, quantity: $quantity, lastModified: $lastModified}\n" +
+				"RETURN " + listItemColumns
+			params = map[string]any{
+				"hId":          item.HouseholdId,
+				"uId":          userId,
+				"id":           item.Id,
+				"list":         item.List,
+				"quantity":     item.Quantity,
+				"lastModified": neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return parseListItemRecord(record)
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "update list item", neo4j.AccessModeWrite, work)
+}
+
+func (r *ListItemRepository) Delete(ctx context.Context, householdId string, userId string, id string) (string, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (string, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (string, error) {
+			*query = matchListItems() + "AND item.id = $id\n" +
+				"SET item.deleted = $deleted, hi.deleted = $deleted\n" +
+				"RETURN item.id AS id"
+			params = map[string]any{
+				"hId":     householdId,
+				"uId":     userId,
+				"id":      id,
+				"deleted": neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return "", err
+			}
+
+			deletedId, found := TypedGet[string](record, "id")
+			if !found {
+				return "", errors.New("could not find column id")
+			}
+			return deletedId, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "delete list item", neo4j.AccessModeWrite, work)
+}
+
+func ParseListItemNode(node dbtype.Node) (*model.ListItem, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := neo4j.GetProperty[string](node, "list")
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := neo4j.GetProperty[string](node, "quantity")
+	if err != nil {
+		return nil, err
+	}
+
+	resource, err := ParseResourceEntity(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ListItem{Id: id, List: list, Quantity: quantity, Resource: *resource}, nil
+}
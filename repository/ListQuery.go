@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+// times in cursors keep the wall clock of the stored LocalDateTime, without a time zone
+const cursorTimeLayout = "2006-01-02T15:04:05.999999999"
+
+// rows missing a time, like foods from before it was recorded, sort as if they were from year 1, so they still round-trip through a cursor
+const missingCursorTime = "0001-01-01T00:00:00"
+const missingTime = "localdatetime('" + missingCursorTime + "')"
+
+type listField[T any] struct {
+	// Cypher expression for the field, where %[1]s is the node variable
+	expression string
+	isTime     bool
+	// the field's value for a cursor
+	value func(T) string
+}
+
+func formatCursorTime(t *time.Time) string {
+	if t == nil {
+		return missingCursorTime
+	}
+	return t.Format(cursorTimeLayout)
+}
+
+func modifiedTime(resource model.Resource) *time.Time {
+	if resource.LastModified != nil {
+		return resource.LastModified
+	}
+	return resource.Created
+}
+
+var foodListFields = map[string]listField[model.Food]{
+	"name":     {expression: "%[1]s.name", value: func(f model.Food) string { return f.Name }},
+	"created":  {expression: "coalesce(%[1]s.created, " + missingTime + ")", isTime: true, value: func(f model.Food) string { return formatCursorTime(f.Created) }},
+	"modified": {expression: "coalesce(%[1]s.lastModified, %[1]s.created, " + missingTime + ")", isTime: true, value: func(f model.Food) string { return formatCursorTime(modifiedTime(f.Resource)) }},
+}
+
+var recipeListFields = map[string]listField[model.Recipe]{
+	"name":     {expression: "%[1]s.title", value: func(r model.Recipe) string { return r.Title }},
+	"created":  {expression: "coalesce(%[1]s.created, " + missingTime + ")", isTime: true, value: func(r model.Recipe) string { return formatCursorTime(r.Created) }},
+	"modified": {expression: "coalesce(%[1]s.lastModified, %[1]s.created, " + missingTime + ")", isTime: true, value: func(r model.Recipe) string { return formatCursorTime(modifiedTime(r.Resource)) }},
+}
+
+// visibleCondition limits the node bound to v to what the viewer can see, the same as model.Viewer.CanSee.
+// Nodes without a visibility are public. Returns "" when there's nothing to leave out.
+func visibleCondition(v string, viewer model.Viewer, params map[string]any) string {
+	if viewer.All {
+		return ""
+	}
+	condition := fmt.Sprintf("(coalesce(%[1]s.visibility, '%[2]s') <> '%[3]s'", v, model.PublicVisibility, model.PrivateVisibility)
+	if viewer.UserId != "" {
+		condition += fmt.Sprintf(" OR exists { (%s)-[:`%s`]->(:`%s` {id: $viewerId}) }", v, AuthoredByLabel, UserLabel)
+		params["viewerId"] = viewer.UserId
+	}
+	if len(viewer.HouseholdIds) > 0 {
+		condition += fmt.Sprintf(" OR exists { (%s)-[rel:`%s`]->(h:`%s`) WHERE rel.deleted IS NULL AND h.id IN $viewerHouseholdIds }", v, BelongsToLabel, HouseholdLabel)
+		params["viewerHouseholdIds"] = viewer.HouseholdIds
+	}
+	return condition + ")"
+}
+
+// authorIdColumn returns the id of the user who authored the node bound to v as authorId, or null if there isn't one
+func authorIdColumn(v string) string {
+	return fmt.Sprintf("head([(%s)-[:`%s`]->(author:`%s`) | author.id]) AS authorId", v, AuthoredByLabel, UserLabel)
+}
+
+// authorId reads the column made by authorIdColumn
+func authorId(record *neo4j.Record) string {
+	if authorId, found := record.Get("authorId"); found && authorId != nil {
+		return authorId.(string)
+	}
+	return ""
+}
+
+// householdIdColumn returns the id of the household the node bound to v belongs to as householdId, or null if there isn't one
+func householdIdColumn(v string) string {
+	return fmt.Sprintf("head([(%s)-[rel:`%s`]->(household:`%s`) WHERE rel.deleted IS NULL AND household.deleted IS NULL | household.id]) AS householdId",
+		v, BelongsToLabel, HouseholdLabel)
+}
+
+// householdId reads the column made by householdIdColumn
+func householdId(record *neo4j.Record) string {
+	if householdId, found := record.Get("householdId"); found && householdId != nil {
+		return householdId.(string)
+	}
+	return ""
+}
+
+// optionalId is the parameter for an id that may be empty, which matches nothing
+func optionalId(id string) any {
+	if id == "" {
+		return nil
+	}
+	return id
+}
+
+// storedVisibility is what's stored for a resource's visibility, where unset is public
+func storedVisibility(visibility string) string {
+	if visibility == "" {
+		return model.PublicVisibility
+	}
+	return visibility
+}
+
+// listClauses translates a list query into the WHERE conditions and ORDER BY items for the node bound to v.
+// It asks for one row more than the page holds, so toPage can tell whether there is another page.
+func listClauses[T any](v string, fields map[string]listField[T], query model.ListQuery, params map[string]any) (string, string, error) {
+	expression := func(field string) string { return fmt.Sprintf(fields[field].expression, v) }
+
+	conditions := []string{fmt.Sprintf("%s.deleted IS NULL", v)}
+	if condition := visibleCondition(v, query.Viewer, params); condition != "" {
+		conditions = append(conditions, condition)
+	}
+	if query.NamePrefix != "" {
+		conditions = append(conditions, expression("name")+" STARTS WITH $namePrefix")
+		params["namePrefix"] = query.NamePrefix
+	}
+	if query.CreatedAfter != nil {
+		conditions = append(conditions, expression("created")+" > $createdAfter")
+		params["createdAfter"] = neo4j.LocalDateTime(query.CreatedAfter.In(time.Local))
+	}
+	if query.ModifiedSince != nil {
+		conditions = append(conditions, expression("modified")+" >= $modifiedSince")
+		params["modifiedSince"] = neo4j.LocalDateTime(query.ModifiedSince.In(time.Local))
+	}
+
+	keys := []string{}
+	descending := []bool{}
+	for _, sort := range query.Sort {
+		if _, found := fields[sort.Field]; !found {
+			return "", "", fmt.Errorf("cannot sort by %q", sort.Field)
+		}
+		keys = append(keys, expression(sort.Field))
+		descending = append(descending, sort.Descending)
+	}
+	keys = append(keys, v+".id")
+	descending = append(descending, false)
+
+	orderBy := make([]string, len(keys))
+	for i, key := range keys {
+		orderBy[i] = key
+		if descending[i] {
+			orderBy[i] += " DESC"
+		}
+	}
+
+	if query.Cursor != nil {
+		if len(query.Cursor.Values) != len(query.Sort) {
+			return "", "", errors.New("cursor does not match the sort order")
+		}
+
+		cursorValues := make([]any, len(keys))
+		for i, sort := range query.Sort {
+			cursorValues[i] = query.Cursor.Values[i]
+			if fields[sort.Field].isTime {
+				t, err := time.ParseInLocation(cursorTimeLayout, query.Cursor.Values[i], time.Local)
+				if err != nil {
+					return "", "", errors.New("malformed cursor")
+				}
+				cursorValues[i] = neo4j.LocalDateTime(t)
+			}
+		}
+		cursorValues[len(keys)-1] = query.Cursor.Id
+
+		// a row comes after the cursor when it is past the cursor on some key and level with it on every key before that one
+		alternatives := make([]string, len(keys))
+		for i := range keys {
+			parts := []string{}
+			for j := 0; j < i; j++ {
+				parts = append(parts, fmt.Sprintf("%s = $cursor%d", keys[j], j))
+			}
+			operator := ">"
+			if descending[i] {
+				operator = "<"
+			}
+			parts = append(parts, fmt.Sprintf("%s %s $cursor%d", keys[i], operator, i))
+			alternatives[i] = "(" + strings.Join(parts, " AND ") + ")"
+			params[fmt.Sprintf("cursor%d", i)] = cursorValues[i]
+		}
+		conditions = append(conditions, "("+strings.Join(alternatives, " OR ")+")")
+	}
+
+	params["limit"] = query.Limit + 1
+	return strings.Join(conditions, " AND "), strings.Join(orderBy, ", "), nil
+}
+
+func toPage[T any](items []T, query model.ListQuery, fields map[string]listField[T], id func(T) string) model.Page[T] {
+	if len(items) <= query.Limit {
+		return model.Page[T]{Items: items}
+	}
+
+	items = items[:query.Limit]
+	last := items[len(items)-1]
+	cursor := model.Cursor{Id: id(last)}
+	for _, sort := range query.Sort {
+		cursor.Values = append(cursor.Values, fields[sort.Field].value(last))
+	}
+	return model.Page[T]{Items: items, NextCursor: &cursor}
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+type MemoryFoodRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryFoodRepository(store *MemoryStore) *MemoryFoodRepository {
+	return &MemoryFoodRepository{store: store}
+}
+
+// like the Neo4j repository, lists leave out nutrients and portions
+func (r *MemoryFoodRepository) GetAll(ctx context.Context, query model.ListQuery) (model.Page[model.Food], error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	foods := []model.Food{}
+	for _, food := range r.store.foods {
+		if food.Deleted == nil && food.VisibleTo(query.Viewer) {
+			food = copyFood(food)
+			food.Nutrients, food.Portions = nil, nil
+			foods = append(foods, food)
+		}
+	}
+	return memoryList(foods, foodListFields, query, func(f model.Food) string { return f.Id })
+}
+
+func (r *MemoryFoodRepository) GetById(ctx context.Context, id string) (*model.Food, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	food, found := r.store.foods[id]
+	if !found || food.Deleted != nil {
+		return nil, false, nil
+	}
+	food = copyFood(food)
+	return &food, true, nil
+}
+
+func (r *MemoryFoodRepository) Create(ctx context.Context, food model.Food) (*model.Food, error) {
+	id, err := newId(food.Id, []string{FoodLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// like the id constraint in Neo4j, deleted foods keep their ids
+	if _, found := r.store.foods[id]; found {
+		return nil, fmt.Errorf("food %s already exists", id)
+	}
+
+	created := model.Food{Id: id, Name: food.Name, Density: copyPointer(food.Density), Visibility: storedVisibility(food.Visibility), Resource: model.Resource{Created: now()}}
+	// like Neo4j, only an existing user can be the author
+	if user, found := r.store.users[food.AuthorId]; found && user.Deleted == nil {
+		created.AuthorId = food.AuthorId
+	}
+	r.store.foods[id] = created
+	created = copyFood(created)
+	created.Nutrients, created.Portions = nil, nil
+	return &created, nil
+}
+
+// Update only changes the name and density, since nutrients and portions come from the USDA import
+func (r *MemoryFoodRepository) Update(ctx context.Context, food model.Food) (*model.Food, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, found := r.store.foods[food.Id]
+	if !found || existing.Deleted != nil {
+		return nil, fmt.Errorf("food %s not found", food.Id)
+	}
+
+	existing.Name = food.Name
+	existing.Density = copyPointer(food.Density)
+	existing.LastModified = now()
+	r.store.foods[food.Id] = existing
+
+	updated := copyFood(existing)
+	return &updated, nil
+}
+
+// Delete marks the food deleted, along with every recipe's use of it
+func (r *MemoryFoodRepository) Delete(ctx context.Context, id string) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	food, found := r.store.foods[id]
+	if !found {
+		return "", fmt.Errorf("food %s not found", id)
+	}
+
+	deleted := now()
+	food.Deleted = deleted
+	r.store.foods[id] = food
+
+	for recipeId, recipe := range r.store.recipes {
+		for i, ci := range recipe.Ingredients {
+			if ci.IngredientId == id && ci.Deleted == nil {
+				recipe.Ingredients[i].Deleted = deleted
+			}
+		}
+		r.store.recipes[recipeId] = recipe
+	}
+
+	return id, nil
+}
+
+func (r *MemoryFoodRepository) Search(ctx context.Context, search model.FoodSearch) ([]model.FoodSearchResult, error) {
+	if strings.TrimSpace(search.Text) == "" {
+		return []model.FoodSearchResult{}, nil
+	}
+
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	results := []model.FoodSearchResult{}
+	for _, food := range r.store.foods {
+		if food.Deleted != nil || !food.VisibleTo(search.Viewer) {
+			continue
+		}
+		if score := textScore(search.Text, textTokens(food.Name)); score > 0 {
+			food = copyFood(food)
+			food.Nutrients, food.Portions = nil, nil
+			results = append(results, model.FoodSearchResult{Food: food, Score: score})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Food.Id < results[j].Food.Id
+	})
+	return paginate(results, search.Skip, search.Limit), nil
+}
+
+func paginate[T any](items []T, skip int, limit int) []T {
+	if skip >= len(items) {
+		return []T{}
+	}
+	items = items[skip:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
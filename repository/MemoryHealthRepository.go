@@ -0,0 +1,14 @@
+package repository
+
+import "context"
+
+type MemoryHealthRepository struct{}
+
+func NewMemoryHealthRepository() *MemoryHealthRepository {
+	return &MemoryHealthRepository{}
+}
+
+// Ready always succeeds, since there's no database to wait for
+func (r *MemoryHealthRepository) Ready(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+type MemoryHouseholdRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryHouseholdRepository(store *MemoryStore) *MemoryHouseholdRepository {
+	return &MemoryHouseholdRepository{store: store}
+}
+
+// householdIds returns the ids of the households the user is a member of. The caller has to hold the store's lock.
+func (s *MemoryStore) householdIds(userId string) []string {
+	ids := []string{}
+	for id, household := range s.households {
+		if household.Deleted != nil {
+			continue
+		}
+		for _, member := range household.Members {
+			if member.UserId == userId {
+				ids = append(ids, id)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// view returns the household with its members' current usernames, leaving out members who were deleted.
+// The caller has to hold the store's lock.
+func (r *MemoryHouseholdRepository) view(household model.Household) model.Household {
+	members := []model.HouseholdMember{}
+	for _, member := range household.Members {
+		user, found := r.store.users[member.UserId]
+		if !found || user.Deleted != nil {
+			continue
+		}
+		member.Username = user.Username
+		member.Joined = copyPointer(member.Joined)
+		members = append(members, member)
+	}
+	sortMembers(members)
+	household.Members = members
+	household.Resource = copyResource(household.Resource)
+	return household
+}
+
+// isMember reports whether the user is a member of the household, and neither has been deleted. The caller has to hold the lock.
+func (s *MemoryStore) isMember(householdId string, userId string) bool {
+	if user, found := s.users[userId]; !found || user.Deleted != nil {
+		return false
+	}
+	for _, id := range s.householdIds(userId) {
+		if id == householdId {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *MemoryHouseholdRepository) Create(ctx context.Context, household model.Household, userId string) (*model.Household, error) {
+	id, err := model.ResourceId([]string{HouseholdLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if user, found := r.store.users[userId]; !found || user.Deleted != nil {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+
+	created := now()
+	household = model.Household{Id: id, Name: household.Name, Members: []model.HouseholdMember{{UserId: userId, Joined: created}}, Resource: model.Resource{Created: created}}
+	r.store.households[id] = household
+
+	household = r.view(household)
+	return &household, nil
+}
+
+func (r *MemoryHouseholdRepository) GetById(ctx context.Context, id string, userId string) (*model.Household, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	if !r.store.isMember(id, userId) {
+		return nil, false, nil
+	}
+	household := r.view(r.store.households[id])
+	return &household, true, nil
+}
+
+func (r *MemoryHouseholdRepository) GetForUser(ctx context.Context, userId string) ([]model.Household, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	households := []model.Household{}
+	for _, id := range r.store.householdIds(userId) {
+		households = append(households, r.view(r.store.households[id]))
+	}
+	sort.Slice(households, func(i, j int) bool {
+		if households[i].Name != households[j].Name {
+			return households[i].Name < households[j].Name
+		}
+		return households[i].Id < households[j].Id
+	})
+	return households, nil
+}
+
+func (r *MemoryHouseholdRepository) Leave(ctx context.Context, householdId string, userId string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if !r.store.isMember(householdId, userId) {
+		return false, nil
+	}
+
+	household := r.store.households[householdId]
+	members := []model.HouseholdMember{}
+	for _, member := range household.Members {
+		if member.UserId != userId {
+			members = append(members, member)
+		}
+	}
+	household.Members = members
+	household.LastModified = now()
+
+	if len(r.view(household).Members) == 0 {
+		household.Deleted = household.LastModified
+		for id, invitation := range r.store.invitations {
+			if invitation.invitation.HouseholdId == householdId && invitation.invitation.Deleted == nil {
+				invitation.invitation.Deleted = household.Deleted
+				r.store.invitations[id] = invitation
+			}
+		}
+		for id, item := range r.store.listItems {
+			if item.HouseholdId == householdId && item.Deleted == nil {
+				item.Deleted = household.Deleted
+				r.store.listItems[id] = item
+			}
+		}
+	}
+	r.store.households[householdId] = household
+	return true, nil
+}
+
+func (r *MemoryHouseholdRepository) Invite(ctx context.Context, householdId string, userId string, invitedBy string) error {
+	id, err := model.ResourceId([]string{InvitationLabel, ResourceLabel})
+	if err != nil {
+		return err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if !r.store.isMember(householdId, invitedBy) {
+		return fmt.Errorf("household %s with member %s not found", householdId, invitedBy)
+	}
+	if user, found := r.store.users[userId]; !found || user.Deleted != nil {
+		return fmt.Errorf("user %s not found", userId)
+	}
+	if r.store.isMember(householdId, userId) {
+		return nil
+	}
+	for _, invitation := range r.store.invitations {
+		if invitation.userId == userId && invitation.invitation.HouseholdId == householdId && invitation.invitation.Deleted == nil {
+			return nil
+		}
+	}
+
+	r.store.invitations[id] = memoryInvitation{
+		userId:      userId,
+		invitedById: invitedBy,
+		invitation:  model.Invitation{Id: id, HouseholdId: householdId, Resource: model.Resource{Created: now()}},
+	}
+	return nil
+}
+
+func (r *MemoryHouseholdRepository) GetInvitations(ctx context.Context, userId string) ([]model.Invitation, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	invitations := []model.Invitation{}
+	for _, pending := range r.store.invitations {
+		invitation := pending.invitation
+		if pending.userId != userId || invitation.Deleted != nil || !r.store.householdExists(invitation.HouseholdId) {
+			continue
+		}
+		invitation.HouseholdName = r.store.households[invitation.HouseholdId].Name
+		if inviter, found := r.store.users[pending.invitedById]; found {
+			invitation.InvitedBy = inviter.Username
+		}
+		invitation.Resource = copyResource(invitation.Resource)
+		invitations = append(invitations, invitation)
+	}
+
+	sort.Slice(invitations, func(i, j int) bool {
+		if !invitations[i].Created.Equal(*invitations[j].Created) {
+			return invitations[i].Created.Before(*invitations[j].Created)
+		}
+		return invitations[i].Id < invitations[j].Id
+	})
+	return invitations, nil
+}
+
+func (r *MemoryHouseholdRepository) Accept(ctx context.Context, userId string, invitationId string) (bool, error) {
+	return r.resolve(userId, invitationId, true)
+}
+
+func (r *MemoryHouseholdRepository) Decline(ctx context.Context, userId string, invitationId string) (bool, error) {
+	return r.resolve(userId, invitationId, false)
+}
+
+// resolve deletes one of the user's pending invitations, first adding them to the household if they accept it
+func (r *MemoryHouseholdRepository) resolve(userId string, invitationId string, accept bool) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	pending, found := r.store.invitations[invitationId]
+	if !found || pending.userId != userId || pending.invitation.Deleted != nil || !r.store.householdExists(pending.invitation.HouseholdId) {
+		return false, nil
+	}
+
+	resolved := now()
+	if accept && !r.store.isMember(pending.invitation.HouseholdId, userId) {
+		household := r.store.households[pending.invitation.HouseholdId]
+		household.Members = append(append([]model.HouseholdMember{}, household.Members...), model.HouseholdMember{UserId: userId, Joined: resolved})
+		r.store.households[household.Id] = household
+	}
+	pending.invitation.Deleted = resolved
+	r.store.invitations[invitationId] = pending
+	return true, nil
+}
+
+// sortMembers orders members by when they joined, the same in every repository
+func sortMembers(members []model.HouseholdMember) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if !members[i].Joined.Equal(*members[j].Joined) {
+			return members[i].Joined.Before(*members[j].Joined)
+		}
+		return members[i].Username < members[j].Username
+	})
+}
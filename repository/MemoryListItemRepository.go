@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+type MemoryListItemRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryListItemRepository(store *MemoryStore) *MemoryListItemRepository {
+	return &MemoryListItemRepository{store: store}
+}
+
+// live returns the household's item with the id and its food's current name, or false when the item, its household or its food is gone,
+// or the user isn't one of the household's members. The caller has to hold the store's lock.
+func (r *MemoryListItemRepository) live(householdId string, userId string, id string) (model.ListItem, bool) {
+	item, found := r.store.listItems[id]
+	if !found || item.Deleted != nil || item.HouseholdId != householdId || !r.store.isMember(householdId, userId) {
+		return model.ListItem{}, false
+	}
+	food, found := r.store.foods[item.FoodId]
+	if !found || food.Deleted != nil {
+		return model.ListItem{}, false
+	}
+	item.FoodName = food.Name
+	item.Resource = copyResource(item.Resource)
+	return item, true
+}
+
+func (r *MemoryListItemRepository) GetForHousehold(ctx context.Context, householdId string, userId string, list string) ([]model.ListItem, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	items := []model.ListItem{}
+	for id := range r.store.listItems {
+		if item, found := r.live(householdId, userId, id); found && item.List == list {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].FoodName != items[j].FoodName {
+			return items[i].FoodName < items[j].FoodName
+		}
+		return items[i].Id < items[j].Id
+	})
+	return items, nil
+}
+
+func (r *MemoryListItemRepository) GetById(ctx context.Context, householdId string, userId string, id string) (*model.ListItem, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	item, found := r.live(householdId, userId, id)
+	if !found {
+		return nil, false, nil
+	}
+	return &item, true, nil
+}
+
+func (r *MemoryListItemRepository) Create(ctx context.Context, item model.ListItem, userId string) (*model.ListItem, error) {
+	id, err := model.ResourceId([]string{ListItemLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if food, found := r.store.foods[item.FoodId]; !r.store.isMember(item.HouseholdId, userId) || !found || food.Deleted != nil {
+		return nil, fmt.Errorf("household %s or food %s not found", item.HouseholdId, item.FoodId)
+	}
+
+	r.store.listItems[id] = model.ListItem{
+		Id:          id,
+		HouseholdId: item.HouseholdId,
+		List:        item.List,
+		FoodId:      item.FoodId,
+		Quantity:    item.Quantity,
+		Resource:    model.Resource{Created: now()},
+	}
+	created, _ := r.live(item.HouseholdId, userId, id)
+	return &created, nil
+}
+
+func (r *MemoryListItemRepository) Update(ctx context.Context, item model.ListItem, userId string) (*model.ListItem, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, found := r.live(item.HouseholdId, userId, item.Id); !found {
+		return nil, fmt.Errorf("list item %s not found", item.Id)
+	}
+
+	existing := r.store.listItems[item.Id]
+	existing.List = item.List
+	existing.Quantity = item.Quantity
+	existing.LastModified = now()
+	r.store.listItems[item.Id] = existing
+
+	updated, _ := r.live(item.HouseholdId, userId, item.Id)
+	return &updated, nil
+}
+
+func (r *MemoryListItemRepository) Delete(ctx context.Context, householdId string, userId string, id string) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, found := r.live(householdId, userId, id); !found {
+		return "", fmt.Errorf("list item %s not found", id)
+	}
+
+	item := r.store.listItems[id]
+	item.Deleted = now()
+	r.store.listItems[id] = item
+	return id, nil
+}
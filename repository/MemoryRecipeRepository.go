@@ -0,0 +1,307 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
+)
+
+type MemoryRecipeRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryRecipeRepository(store *MemoryStore) *MemoryRecipeRepository {
+	return &MemoryRecipeRepository{store: store}
+}
+
+// view returns the recipe the way it's read back: without deleted ingredients or a deleted household, and with each ingredient's name filled in.
+// The caller has to hold the store's lock.
+func (r *MemoryRecipeRepository) view(recipe model.Recipe) model.Recipe {
+	recipe = copyRecipe(recipe)
+	ingredients := []model.ContainsIngredient{}
+	for _, ci := range recipe.Ingredients {
+		food, found := r.store.foods[ci.IngredientId]
+		if ci.Deleted != nil || !found || food.Deleted != nil {
+			continue
+		}
+		ci.IngredientName = food.Name
+		ingredients = append(ingredients, ci)
+	}
+	recipe.Ingredients = ingredients
+	if !r.store.householdExists(recipe.HouseholdId) {
+		recipe.HouseholdId = ""
+	}
+	return recipe
+}
+
+// ingredientsExist checks that every id is a food that hasn't been deleted. The caller has to hold the store's lock.
+func (r *MemoryRecipeRepository) ingredientsExist(ids []string) bool {
+	for _, id := range ids {
+		food, found := r.store.foods[id]
+		if !found || food.Deleted != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *MemoryRecipeRepository) GetAll(ctx context.Context, query model.ListQuery) (model.Page[model.Recipe], error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	recipes := []model.Recipe{}
+	for _, recipe := range r.store.recipes {
+		if recipe.Deleted == nil && recipe.VisibleTo(query.Viewer) {
+			recipes = append(recipes, r.view(recipe))
+		}
+	}
+	return memoryList(recipes, recipeListFields, query, func(r model.Recipe) string { return r.Id })
+}
+
+func (r *MemoryRecipeRepository) GetById(ctx context.Context, id string) (*model.Recipe, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	recipe, found := r.store.recipes[id]
+	if !found || recipe.Deleted != nil {
+		return nil, false, nil
+	}
+	recipe = r.view(recipe)
+	return &recipe, true, nil
+}
+
+func (r *MemoryRecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*model.Recipe, error) {
+	id, err := newId(recipe.Id, []string{RecipeLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	// like the id constraint in Neo4j, deleted recipes keep their ids
+	if _, found := r.store.recipes[id]; found {
+		return nil, fmt.Errorf("recipe %s already exists", id)
+	}
+
+	if !r.ingredientsExist(util.MapArray(recipe.Ingredients, model.ExtractIngredientId)) {
+		return nil, errors.New("tried to create a recipe with non-existent ingredient(s)")
+	}
+
+	created := now()
+	recipe = copyRecipe(recipe)
+	recipe.Id = id
+	recipe.Visibility = storedVisibility(recipe.Visibility)
+	// like Neo4j, only an existing user can be the author
+	if user, found := r.store.users[recipe.AuthorId]; !found || user.Deleted != nil {
+		recipe.AuthorId = ""
+	}
+	if !r.store.householdExists(recipe.HouseholdId) {
+		recipe.HouseholdId = ""
+	}
+	recipe.Resource = model.Resource{Created: created}
+	for i := range recipe.Ingredients {
+		recipe.Ingredients[i].IngredientName = ""
+		recipe.Ingredients[i].Resource = model.Resource{Created: created}
+	}
+	r.store.recipes[id] = recipe
+
+	recipe = r.view(recipe)
+	return &recipe, nil
+}
+
+// Update replaces the recipe's fields and ingredients. Removed ingredients are marked deleted rather than dropped,
+// and one that's added back gets a new relationship, the same as in Neo4j.
+func (r *MemoryRecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*model.Recipe, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	existing, found := r.store.recipes[recipe.Id]
+	if !found || existing.Deleted != nil {
+		return nil, errors.New("recipe disappeared :(")
+	}
+
+	newIngredients := map[string]model.ContainsIngredient{}
+	for _, ci := range recipe.Ingredients {
+		newIngredients[ci.IngredientId] = ci
+	}
+	added := []string{}
+	for id := range newIngredients {
+		kept := false
+		for _, ci := range existing.Ingredients {
+			kept = kept || (ci.IngredientId == id && ci.Deleted == nil)
+		}
+		if !kept {
+			added = append(added, id)
+		}
+	}
+	if !r.ingredientsExist(added) {
+		return nil, errors.New("tried to create a recipe with non-existent ingredient(s)")
+	}
+
+	modified := now()
+	existing = copyRecipe(existing)
+	for i, ci := range existing.Ingredients {
+		if ci.Deleted != nil {
+			continue
+		}
+		if updated, found := newIngredients[ci.IngredientId]; found {
+			existing.Ingredients[i].Unit = updated.Unit
+			existing.Ingredients[i].Amount = updated.Amount
+			existing.Ingredients[i].LastModified = modified
+		} else {
+			existing.Ingredients[i].Deleted = modified
+		}
+	}
+	// in the order they were given, so reading the recipe back is predictable
+	for _, ci := range recipe.Ingredients {
+		for _, id := range added {
+			if ci.IngredientId == id {
+				existing.Ingredients = append(existing.Ingredients, model.ContainsIngredient{
+					Unit:         ci.Unit,
+					Amount:       ci.Amount,
+					IngredientId: ci.IngredientId,
+					Resource:     model.Resource{Created: modified},
+				})
+			}
+		}
+	}
+
+	existing.Title = recipe.Title
+	existing.Description = copyPointer(recipe.Description)
+	existing.Steps = append([]string{}, recipe.Steps...)
+	existing.Servings = copyPointer(recipe.Servings)
+	existing.Visibility = storedVisibility(recipe.Visibility)
+	existing.HouseholdId = ""
+	if r.store.householdExists(recipe.HouseholdId) {
+		existing.HouseholdId = recipe.HouseholdId
+	}
+	existing.LastModified = modified
+	r.store.recipes[recipe.Id] = existing
+
+	updated := r.view(existing)
+	return &updated, nil
+}
+
+// Delete marks the recipe and its ingredients deleted
+func (r *MemoryRecipeRepository) Delete(ctx context.Context, id string) (string, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	recipe, found := r.store.recipes[id]
+	if !found || recipe.Deleted != nil {
+		return "", fmt.Errorf("recipe %s not found", id)
+	}
+
+	deleted := now()
+	recipe = copyRecipe(recipe)
+	recipe.Deleted = deleted
+	for i, ci := range recipe.Ingredients {
+		if ci.Deleted == nil {
+			recipe.Ingredients[i].Deleted = deleted
+		}
+	}
+	r.store.recipes[id] = recipe
+
+	return id, nil
+}
+
+func (r *MemoryRecipeRepository) Search(ctx context.Context, search model.RecipeSearch) ([]model.RecipeSearchResult, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	hasText := strings.TrimSpace(search.Text) != ""
+	results := []model.RecipeSearchResult{}
+	for _, recipe := range r.store.recipes {
+		if recipe.Deleted != nil || !recipe.VisibleTo(search.Viewer) {
+			continue
+		}
+		recipe = r.view(recipe)
+
+		ingredientIds := util.ArrayToSet(util.MapArray(recipe.Ingredients, model.ExtractIngredientId))
+		if len(util.Difference(util.ArrayToSet(search.IncludeFoodIds), ingredientIds)) > 0 {
+			continue
+		}
+		if len(util.Intersection(util.ArrayToSet(search.ExcludeFoodIds), ingredientIds)) > 0 {
+			continue
+		}
+		if search.MaxIngredients != nil && len(recipe.Ingredients) > *search.MaxIngredients {
+			continue
+		}
+
+		score := 0.0
+		if hasText {
+			texts := []string{recipe.Title}
+			if recipe.Description != nil {
+				texts = append(texts, *recipe.Description)
+			}
+			texts = append(texts, recipe.Steps...)
+			texts = append(texts, util.MapArray(recipe.Ingredients, func(ci model.ContainsIngredient) string { return ci.IngredientName })...)
+
+			score = textScore(search.Text, textTokens(texts...))
+			if score == 0 {
+				continue
+			}
+		}
+
+		results = append(results, model.RecipeSearchResult{Recipe: recipe, Score: score})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Recipe.Id < results[j].Recipe.Id
+	})
+	return paginate(results, search.Skip, search.Limit), nil
+}
+
+// GetNutrition totals each nutrient over the ingredients that can be converted to grams, the same as the Neo4j repository
+func (r *MemoryRecipeRepository) GetNutrition(ctx context.Context, id string) (*model.RecipeNutrition, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	recipe, found := r.store.recipes[id]
+	if !found || recipe.Deleted != nil {
+		return nil, false, nil
+	}
+	recipe = r.view(recipe)
+
+	totals := map[string]*model.HasNutrient{}
+	unconvertedIngredientIds := []string{}
+	for _, ci := range recipe.Ingredients {
+		food := r.store.foods[ci.IngredientId]
+		grams, ok := food.ToGrams(ci.Amount.Mean(), ci.Unit)
+		if !ok {
+			unconvertedIngredientIds = append(unconvertedIngredientIds, food.Id)
+			continue
+		}
+
+		for _, hn := range food.Nutrients {
+			total, found := totals[hn.Nutrient.Id]
+			if !found {
+				total = &model.HasNutrient{Nutrient: hn.Nutrient}
+				totals[hn.Nutrient.Id] = total
+			}
+			total.Amount += grams * hn.Amount / 100.0
+		}
+	}
+
+	nutrients := []model.HasNutrient{}
+	for _, total := range totals {
+		nutrients = append(nutrients, *total)
+	}
+	sort.Slice(nutrients, func(i, j int) bool { return nutrients[i].Nutrient.Name < nutrients[j].Nutrient.Name })
+
+	return &model.RecipeNutrition{
+		RecipeId:                 id,
+		Servings:                 recipe.Servings,
+		Nutrients:                nutrients,
+		UnconvertedIngredientIds: unconvertedIngredientIds,
+	}, true, nil
+}
@@ -0,0 +1,241 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+// MemoryStore holds everything the in-memory repositories save, so that recipes can see the foods they use.
+// It's for running without Neo4j, e.g. for frontend development and demos; nothing outlives the process.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	foods   map[string]model.Food
+	recipes map[string]model.Recipe
+	users   map[string]model.User
+	// keyed by token hash
+	sessions    map[string]memorySession
+	apiKeys     map[string]memoryApiKey
+	households  map[string]model.Household
+	invitations map[string]memoryInvitation
+	listItems   map[string]model.ListItem
+}
+
+type memorySession struct {
+	userId  string
+	expires time.Time
+}
+
+type memoryInvitation struct {
+	userId      string
+	invitedById string
+	invitation  model.Invitation
+}
+
+type memoryApiKey struct {
+	userId    string
+	tokenHash string
+	key       model.ApiKey
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		foods:       map[string]model.Food{},
+		recipes:     map[string]model.Recipe{},
+		users:       map[string]model.User{},
+		sessions:    map[string]memorySession{},
+		apiKeys:     map[string]memoryApiKey{},
+		households:  map[string]model.Household{},
+		invitations: map[string]memoryInvitation{},
+		listItems:   map[string]model.ListItem{},
+	}
+}
+
+// AddFood saves a food as is, including its nutrients and portions, which the repositories can't set. Meant for seeding data.
+func (s *MemoryStore) AddFood(food model.Food) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if food.Created == nil {
+		food.Created = now()
+	}
+	s.foods[food.Id] = copyFood(food)
+}
+
+// householdExists reports whether id is a household that hasn't been deleted. The caller has to hold the lock.
+func (s *MemoryStore) householdExists(id string) bool {
+	household, found := s.households[id]
+	return found && household.Deleted == nil
+}
+
+// now drops the monotonic clock reading, which a time read back from Neo4j wouldn't have either
+func now() *time.Time {
+	t := time.Now().Round(0)
+	return &t
+}
+
+func copyPointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	c := *p
+	return &c
+}
+
+func copyResource(r model.Resource) model.Resource {
+	return model.Resource{Created: copyPointer(r.Created), LastModified: copyPointer(r.LastModified), Deleted: copyPointer(r.Deleted)}
+}
+
+func copyFood(f model.Food) model.Food {
+	f.Density = copyPointer(f.Density)
+	f.Portions = append([]model.Portion{}, f.Portions...)
+	f.Nutrients = append([]model.HasNutrient{}, f.Nutrients...)
+	f.Resource = copyResource(f.Resource)
+	return f
+}
+
+func copyRecipe(r model.Recipe) model.Recipe {
+	r.Description = copyPointer(r.Description)
+	r.Servings = copyPointer(r.Servings)
+	r.Steps = append([]string{}, r.Steps...)
+	ingredients := make([]model.ContainsIngredient, len(r.Ingredients))
+	for i, ci := range r.Ingredients {
+		ci.Resource = copyResource(ci.Resource)
+		ingredients[i] = ci
+	}
+	r.Ingredients = ingredients
+	r.Resource = copyResource(r.Resource)
+	return r
+}
+
+// memoryList applies a list query the way listClauses and toPage do in Cypher, working from the same field definitions
+func memoryList[T any](items []T, fields map[string]listField[T], query model.ListQuery, id func(T) string) (model.Page[T], error) {
+	compare := func(field string, a string, b string) int {
+		if !fields[field].isTime {
+			return strings.Compare(a, b)
+		}
+		at, _ := time.ParseInLocation(cursorTimeLayout, a, time.Local)
+		bt, _ := time.ParseInLocation(cursorTimeLayout, b, time.Local)
+		return at.Compare(bt)
+	}
+	for _, sort := range query.Sort {
+		if _, found := fields[sort.Field]; !found {
+			return model.Page[T]{}, errors.New("cannot sort by " + sort.Field)
+		}
+	}
+	if query.Cursor != nil && len(query.Cursor.Values) != len(query.Sort) {
+		return model.Page[T]{}, errors.New("cursor does not match the sort order")
+	}
+
+	// compareItems orders two items by the sort fields and then by id, given each one's sort values
+	compareItems := func(aValues []string, aId string, bValues []string, bId string) int {
+		for i, sort := range query.Sort {
+			c := compare(sort.Field, aValues[i], bValues[i])
+			if sort.Descending {
+				c = -c
+			}
+			if c != 0 {
+				return c
+			}
+		}
+		return strings.Compare(aId, bId)
+	}
+	values := func(item T) []string {
+		v := make([]string, len(query.Sort))
+		for i, sort := range query.Sort {
+			v[i] = fields[sort.Field].value(item)
+		}
+		return v
+	}
+
+	filtered := []T{}
+	for _, item := range items {
+		if query.NamePrefix != "" && !strings.HasPrefix(fields["name"].value(item), query.NamePrefix) {
+			continue
+		}
+		if query.CreatedAfter != nil && compare("created", fields["created"].value(item), query.CreatedAfter.In(time.Local).Format(cursorTimeLayout)) <= 0 {
+			continue
+		}
+		if query.ModifiedSince != nil && compare("modified", fields["modified"].value(item), query.ModifiedSince.In(time.Local).Format(cursorTimeLayout)) < 0 {
+			continue
+		}
+		if query.Cursor != nil && compareItems(values(item), id(item), query.Cursor.Values, query.Cursor.Id) <= 0 {
+			continue
+		}
+		filtered = append(filtered, item)
+	}
+
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return compareItems(values(filtered[i]), id(filtered[i]), values(filtered[j]), id(filtered[j])) < 0
+	})
+	if len(filtered) > query.Limit+1 {
+		filtered = filtered[:query.Limit+1]
+	}
+	return toPage(filtered, query, fields, id), nil
+}
+
+func textTokens(texts ...string) []string {
+	tokens := []string{}
+	for _, text := range texts {
+		tokens = append(tokens, strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+		})...)
+	}
+	return tokens
+}
+
+// textScore approximates the fulltext queries built by FulltextQuery: every word has to match a token, either as a prefix
+// or, for words of 4 or more characters, within 2 edits. Like Lucene, matches in shorter texts score higher. 0 means no match.
+func textScore(text string, tokens []string) float64 {
+	words := textTokens(text)
+	if len(words) == 0 || len(tokens) == 0 {
+		return 0
+	}
+
+	score := 0.0
+	for _, word := range words {
+		best := 0.0
+		for _, token := range tokens {
+			switch {
+			case token == word:
+				best = math.Max(best, 1)
+			case strings.HasPrefix(token, word):
+				best = math.Max(best, 0.75)
+			case len([]rune(word)) >= 4 && editDistance(word, token) <= 2:
+				best = math.Max(best, 0.5)
+			}
+		}
+		if best == 0 {
+			return 0
+		}
+		score += best
+	}
+	return score / math.Sqrt(float64(len(tokens)))
+}
+
+func editDistance(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		current := make([]int, len(br)+1)
+		current[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(br)]
+}
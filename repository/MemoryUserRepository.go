@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+)
+
+type MemoryUserRepository struct {
+	store *MemoryStore
+}
+
+func NewMemoryUserRepository(store *MemoryStore) *MemoryUserRepository {
+	return &MemoryUserRepository{store: store}
+}
+
+// liveUser returns a user that hasn't been deleted. The caller has to hold the store's lock.
+func (r *MemoryUserRepository) liveUser(id string) (*model.User, bool) {
+	user, found := r.store.users[id]
+	if !found || user.Deleted != nil {
+		return nil, false
+	}
+	user.HouseholdIds = r.store.householdIds(id)
+	user.Resource = copyResource(user.Resource)
+	return &user, true
+}
+
+func (r *MemoryUserRepository) GetById(ctx context.Context, id string) (*model.User, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	user, found := r.liveUser(id)
+	return user, found, nil
+}
+
+func (r *MemoryUserRepository) GetByUsername(ctx context.Context, username string) (*model.User, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for id, user := range r.store.users {
+		if user.Username == username {
+			user, found := r.liveUser(id)
+			return user, found, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (r *MemoryUserRepository) SetRole(ctx context.Context, username string, role string) (*model.User, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, user := range r.store.users {
+		if user.Username == username && user.Deleted == nil {
+			user.Role = role
+			user.LastModified = now()
+			r.store.users[id] = user
+			updated, found := r.liveUser(id)
+			return updated, found, nil
+		}
+	}
+	return nil, false, nil
+}
+
+func (r *MemoryUserRepository) Create(ctx context.Context, user model.User) (*model.User, error) {
+	id, err := model.ResourceId([]string{UserLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.users {
+		if existing.Username == user.Username {
+			return nil, model.ErrUsernameTaken
+		}
+	}
+
+	created := model.User{Id: id, Username: user.Username, PasswordHash: user.PasswordHash, Role: userRole(user.Role), HouseholdIds: []string{}, Resource: model.Resource{Created: now()}}
+	r.store.users[id] = created
+	created.Resource = copyResource(created.Resource)
+	return &created, nil
+}
+
+func (r *MemoryUserRepository) CreateFirst(ctx context.Context, user model.User) (*model.User, bool, error) {
+	id, err := model.ResourceId([]string{UserLabel, ResourceLabel})
+	if err != nil {
+		return nil, false, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for _, existing := range r.store.users {
+		if existing.Deleted == nil {
+			return nil, false, nil
+		}
+	}
+	// a deleted user still holds their username
+	for _, existing := range r.store.users {
+		if existing.Username == user.Username {
+			return nil, false, model.ErrUsernameTaken
+		}
+	}
+
+	created := model.User{Id: id, Username: user.Username, PasswordHash: user.PasswordHash, Role: userRole(user.Role), HouseholdIds: []string{}, Resource: model.Resource{Created: now()}}
+	r.store.users[id] = created
+	created.Resource = copyResource(created.Resource)
+	return &created, true, nil
+}
+
+func (r *MemoryUserRepository) HasUsers(ctx context.Context) (bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	for _, user := range r.store.users {
+		if user.Deleted == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *MemoryUserRepository) CreateSession(ctx context.Context, userId string, tokenHash string, expires time.Time) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, found := r.liveUser(userId); !found {
+		return fmt.Errorf("user %s not found", userId)
+	}
+	r.store.sessions[tokenHash] = memorySession{userId: userId, expires: expires}
+	return nil
+}
+
+func (r *MemoryUserRepository) GetSessionUser(ctx context.Context, tokenHash string) (*model.User, bool, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	session, found := r.store.sessions[tokenHash]
+	if !found || !session.expires.After(time.Now()) {
+		return nil, false, nil
+	}
+	user, found := r.liveUser(session.userId)
+	return user, found, nil
+}
+
+func (r *MemoryUserRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	delete(r.store.sessions, tokenHash)
+	return nil
+}
+
+func (r *MemoryUserRepository) CreateApiKey(ctx context.Context, userId string, name string, tokenHash string) (*model.ApiKey, error) {
+	id, err := model.ResourceId([]string{ApiKeyLabel, ResourceLabel})
+	if err != nil {
+		return nil, err
+	}
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	if _, found := r.liveUser(userId); !found {
+		return nil, fmt.Errorf("user %s not found", userId)
+	}
+
+	key := model.ApiKey{Id: id, Name: name, Resource: model.Resource{Created: now()}}
+	r.store.apiKeys[id] = memoryApiKey{userId: userId, tokenHash: tokenHash, key: key}
+	key.Resource = copyResource(key.Resource)
+	return &key, nil
+}
+
+func (r *MemoryUserRepository) GetApiKeys(ctx context.Context, userId string) ([]model.ApiKey, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+
+	keys := []model.ApiKey{}
+	for _, apiKey := range r.store.apiKeys {
+		if apiKey.userId == userId && apiKey.key.Deleted == nil {
+			key := apiKey.key
+			key.LastUsed = copyPointer(key.LastUsed)
+			key.Resource = copyResource(key.Resource)
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].Created.Equal(*keys[j].Created) {
+			return keys[i].Created.Before(*keys[j].Created)
+		}
+		return keys[i].Id < keys[j].Id
+	})
+	return keys, nil
+}
+
+func (r *MemoryUserRepository) GetApiKeyUser(ctx context.Context, tokenHash string) (*model.User, bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	for id, apiKey := range r.store.apiKeys {
+		if apiKey.tokenHash != tokenHash || apiKey.key.Deleted != nil {
+			continue
+		}
+		user, found := r.liveUser(apiKey.userId)
+		if found {
+			apiKey.key.LastUsed = now()
+			r.store.apiKeys[id] = apiKey
+		}
+		return user, found, nil
+	}
+	return nil, false, nil
+}
+
+func (r *MemoryUserRepository) DeleteApiKey(ctx context.Context, userId string, id string) (bool, error) {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+
+	apiKey, found := r.store.apiKeys[id]
+	if !found || apiKey.userId != userId || apiKey.key.Deleted != nil {
+		return false, nil
+	}
+	apiKey.key.Deleted = now()
+	r.store.apiKeys[id] = apiKey
+	return true, nil
+}
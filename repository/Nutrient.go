@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"github.com/ThomasMatlak/food/model"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+// nutrients are only ever created by the USDA import, so they don't carry the usual Resource timestamps
+func ParseNutrientNode(node dbtype.Node) (*model.Nutrient, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := neo4j.GetProperty[string](node, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	unitName, err := neo4j.GetProperty[string](node, "unit_name")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.Nutrient{Id: id, Name: name, UnitName: unitName}, nil
+}
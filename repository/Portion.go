@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"github.com/ThomasMatlak/food/model"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+// like nutrients, portions only come from the USDA import
+func ParsePortionNode(node dbtype.Node) (*model.Portion, error) {
+	amount, err := neo4j.GetProperty[float64](node, "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	unit, err := neo4j.GetProperty[string](node, "unit")
+	if err != nil {
+		return nil, err
+	}
+
+	gramWeight, err := neo4j.GetProperty[float64](node, "gramWeight")
+	if err != nil {
+		return nil, err
+	}
+
+	// descriptions and modifiers are blank for a lot of portions
+	description, _ := neo4j.GetProperty[string](node, "description")
+	modifier, _ := neo4j.GetProperty[string](node, "modifier")
+
+	return &model.Portion{Amount: amount, Unit: unit, Description: description, Modifier: modifier, GramWeight: gramWeight}, nil
+}
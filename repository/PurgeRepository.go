@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+)
+
+type PurgeRepository struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewPurgeRepository(driver neo4j.DriverWithContext, database string) *PurgeRepository {
+	return &PurgeRepository{driver: driver, database: database}
+}
+
+// Purge removes soft deleted relationships first, then soft deleted resources along with any portions they leave behind,
+// and sessions that expired before deletedBefore. Each step deletes in batches of its own transactions, so a big purge
+// doesn't have to fit in memory; one that fails partway leaves what it already removed removed.
+func (r *PurgeRepository) Purge(ctx context.Context, deletedBefore time.Time, dryRun bool) (model.PurgeResult, error) {
+	deleteRelationships, deleteNodes, deletePortions, deleteSessions := "CALL { WITH rel DELETE rel } IN TRANSACTIONS\n",
+		"CALL { WITH n DETACH DELETE n } IN TRANSACTIONS\n",
+		"CALL { WITH p DELETE p } IN TRANSACTIONS\n",
+		"CALL { WITH s DETACH DELETE s } IN TRANSACTIONS\n"
+	if dryRun {
+		deleteRelationships, deleteNodes, deletePortions, deleteSessions = "", "", "", ""
+	}
+
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (model.PurgeResult, error) {
+		params = map[string]any{"deletedBefore": neo4j.LocalDateTime(deletedBefore), "dryRun": dryRun}
+
+		// CALL { ... } IN TRANSACTIONS only works in an auto-commit transaction
+		count := func(statement string) (int64, error) {
+			*query = statement
+			result, err := session.Run(ctx, *query, params)
+			if err != nil {
+				return 0, err
+			}
+			record, err := result.Single(ctx)
+			if err != nil {
+				return 0, err
+			}
+			count, found := TypedGet[int64](record, "count")
+			if !found {
+				return 0, errors.New("could not find column count")
+			}
+			return count, nil
+		}
+
+		relationships, err := count("MATCH ()-[rel]->() WHERE rel.deleted < $deletedBefore\n" +
+			deleteRelationships +
+			"RETURN count(*) AS count")
+		if err != nil {
+			return model.PurgeResult{}, err
+		}
+
+		// a dry run leaves the resources' portions in place, so they're counted with the resources instead of afterwards
+		nodes, err := count(fmt.Sprintf("MATCH (n:`%[1]s`) WHERE n.deleted < $deletedBefore\n"+
+			"OPTIONAL MATCH (n)-[:`%[2]s`]->(p:`%[3]s`)\n"+
+			"WITH n, count(p) AS portions\n"+
+			deleteNodes+
+			"RETURN count(*) + sum(CASE WHEN $dryRun THEN portions ELSE 0 END) AS count",
+			ResourceLabel, HasPortionLabel, PortionLabel))
+		if err != nil {
+			return model.PurgeResult{}, err
+		}
+
+		portions, err := count(fmt.Sprintf("MATCH (p:`%s`) WHERE NOT exists { ()-[:`%s`]->(p) }\n"+
+			deletePortions+
+			"RETURN count(*) AS count",
+			PortionLabel, HasPortionLabel))
+		if err != nil {
+			return model.PurgeResult{}, err
+		}
+
+		sessions, err := count(fmt.Sprintf("MATCH (s:`%s`) WHERE s.expires < $deletedBefore\n"+
+			deleteSessions+
+			"RETURN count(*) AS count",
+			SessionLabel))
+		if err != nil {
+			return model.PurgeResult{}, err
+		}
+
+		return model.PurgeResult{Nodes: nodes + portions + sessions, Relationships: relationships}, nil
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "purge deleted resources", neo4j.AccessModeWrite, work)
+}
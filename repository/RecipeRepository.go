@@ -14,31 +14,37 @@ import (
 )
 
 type RecipeRepository struct {
-	driver neo4j.DriverWithContext
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
 }
 
-func NewRecipeRepository(driver neo4j.DriverWithContext) *RecipeRepository {
-	return &RecipeRepository{driver: driver}
+func NewRecipeRepository(driver neo4j.DriverWithContext, database string) *RecipeRepository {
+	return &RecipeRepository{driver: driver, database: database}
 }
 
-func (r *RecipeRepository) GetAll(ctx context.Context) ([]model.Recipe, error) {
-	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.Recipe, error) {
-		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.Recipe, error) {
-			*query = fmt.Sprintf("MATCH (r:`%s`) WHERE r.deleted IS NULL\n"+
-				"MATCH (r)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL\n"+
-				"RETURN r AS recipe, collect({ingredient: i, rel: ci}) AS ingredients",
-				RecipeLabel,
-				ContainsIngredientLabel, FoodLabel)
+func (r *RecipeRepository) GetAll(ctx context.Context, listQuery model.ListQuery) (model.Page[model.Recipe], error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (model.Page[model.Recipe], error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (model.Page[model.Recipe], error) {
 			params = map[string]any{}
+			where, orderBy, err := listClauses("r", recipeListFields, listQuery, params)
+			if err != nil {
+				return model.Page[model.Recipe]{}, err
+			}
+			*query = fmt.Sprintf("MATCH (r:`%s`) WHERE %s\n"+
+				"WITH r ORDER BY %s LIMIT $limit\n"+
+				"RETURN r AS recipe, %s, %s, %s ORDER BY %s",
+				RecipeLabel, where, orderBy,
+				ingredientsColumn("r"), authorIdColumn("r"), householdIdColumn("r"), orderBy)
 
 			result, err := tx.Run(ctx, *query, params)
 			if err != nil {
-				return nil, err
+				return model.Page[model.Recipe]{}, err
 			}
 
 			records, err := result.Collect(ctx)
 			if err != nil {
-				return nil, err
+				return model.Page[model.Recipe]{}, err
 			}
 
 			recipes := make([]model.Recipe, len(records))
@@ -52,7 +58,7 @@ func (r *RecipeRepository) GetAll(ctx context.Context) ([]model.Recipe, error) {
 
 				recipe, err := ParseRecipeNode(recipeNode)
 				if err != nil {
-					return nil, err
+					return model.Page[model.Recipe]{}, err
 				}
 
 				rawIngredients, found := TypedGet[[]any](records[i], "ingredients")
@@ -64,27 +70,28 @@ func (r *RecipeRepository) GetAll(ctx context.Context) ([]model.Recipe, error) {
 
 				err = setIngredients(recipe, ingredients)
 				if err != nil {
-					return nil, err
+					return model.Page[model.Recipe]{}, err
 				}
+				recipe.AuthorId = authorId(records[i])
+				recipe.HouseholdId = householdId(records[i])
 
 				recipes[i] = *recipe
 			}
 
-			return recipes, nil
+			return toPage(recipes, listQuery, recipeListFields, func(r model.Recipe) string { return r.Id }), nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "get all recipes", neo4j.AccessModeRead, work)
+	return RunQuery(ctx, r.driver, r.database, "get all recipes", neo4j.AccessModeRead, work)
 }
 
 func (r *RecipeRepository) GetById(ctx context.Context, id string) (*model.Recipe, bool, error) {
 	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Recipe, error) {
 		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Recipe, error) {
 			*query = fmt.Sprintf("%s WHERE r.deleted IS NULL\n"+
-				"MATCH (r)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL\n"+
-				"RETURN r AS recipe, collect({ingredient: i, rel: ci}) AS ingredients",
+				"RETURN r AS recipe, %s, %s, %s",
 				MatchNodeById("r", []string{RecipeLabel}),
-				ContainsIngredientLabel, FoodLabel)
+				ingredientsColumn("r"), authorIdColumn("r"), householdIdColumn("r"))
 			params = map[string]any{
 				"rId": id,
 			}
@@ -114,11 +121,13 @@ func (r *RecipeRepository) GetById(ctx context.Context, id string) (*model.Recip
 			if err != nil {
 				return nil, err
 			}
+			recipe.AuthorId = authorId(record)
+			recipe.HouseholdId = householdId(record)
 			return recipe, nil
 		})
 	}
 
-	recipe, err := RunQuery(ctx, r.driver, "get recipe", neo4j.AccessModeRead, work)
+	recipe, err := RunQuery(ctx, r.driver, r.database, "get recipe", neo4j.AccessModeRead, work)
 
 	if err != nil && err.Error() == "Result contains no more records" {
 		return nil, false, nil
@@ -153,7 +162,7 @@ func (r *RecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*mo
 	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.Recipe, error) {
 		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.Recipe, error) {
 			labels := []string{RecipeLabel, ResourceLabel}
-			id, err := model.ResourceId(labels)
+			id, err := newId(recipe.Id, labels)
 			if err != nil {
 				return nil, err
 			}
@@ -169,14 +178,25 @@ func (r *RecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*mo
 			}
 
 			// TODO fail the query if any 1 of the ingredients is not found
-			*query = fmt.Sprintf("CREATE (r:`%s`) SET r = {id: $id, title: $title, description: $description, steps: $steps, created: $created}\n"+
+			*query = fmt.Sprintf("CREATE (r:`%s`) SET r = {id: $id, title: $title, description: $description, steps: $steps, servings: $servings, visibility: $visibility, created: $created}\n"+
+				// recipes only have an author if the user exists, e.g. an import may come from another database
+				"WITH r OPTIONAL MATCH (a:`%s` {id: $authorId})\n"+
+				"FOREACH (author IN CASE WHEN a IS NULL THEN [] ELSE [a] END | CREATE (r)-[:`%s` {created: $created}]->(author))\n"+
+				"WITH r OPTIONAL MATCH (h:`%s` {id: $householdId}) WHERE h.deleted IS NULL\n"+
+				"FOREACH (household IN CASE WHEN h IS NULL THEN [] ELSE [h] END | CREATE (r)-[:`%s` {created: $created}]->(household))\n"+
 				"WITH r UNWIND $ingredients AS ingredient\n"+
 				"MATCH (i:`%s` {id: ingredient.id}) WHERE i.deleted IS NULL\n"+
-				"CREATE (r)-[ci:`%s` {unit: ingredient.unit, amount: ingredient.amount, created: $created}]->(i)\n"+
-				"RETURN r AS recipe, collect({ingredient: i, rel: ci}) AS ingredients",
+				"CREATE (r)-[ci:`%s` {unit: ingredient.unit, amount: ingredient.amount, amountMax: ingredient.amountMax, created: $created}]->(i)\n"+
+				"WITH r, collect({ingredient: i, rel: ci}) AS ingredients\n"+
+				"%s\n"+
+				"RETURN r AS recipe, ingredients, %s, %s",
 				strings.Join(labels, "`:`"),
+				UserLabel, AuthoredByLabel,
+				HouseholdLabel, BelongsToLabel,
 				FoodLabel,
 				ContainsIngredientLabel,
+				setSearchTextStatement,
+				authorIdColumn("r"), householdIdColumn("r"),
 			)
 
 			ingredientParams := []map[string]any{}
@@ -184,7 +204,7 @@ func (r *RecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*mo
 				ingredient := map[string]any{}
 				ingredient["id"] = ci.IngredientId
 				ingredient["unit"] = ci.Unit
-				ingredient["amount"] = ci.Amount
+				ingredient = amountParams(ingredient, ci.Amount)
 				ingredientParams = append(ingredientParams, ingredient)
 			}
 			params = map[string]any{
@@ -192,6 +212,10 @@ func (r *RecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*mo
 				"title":       recipe.Title,
 				"description": recipe.Description,
 				"steps":       recipe.Steps,
+				"servings":    recipe.Servings,
+				"visibility":  storedVisibility(recipe.Visibility),
+				"authorId":    recipe.AuthorId,
+				"householdId": optionalId(recipe.HouseholdId),
 				"ingredients": ingredientParams,
 				"created":     neo4j.LocalDateTime(time.Now()),
 			}
@@ -221,11 +245,13 @@ func (r *RecipeRepository) Create(ctx context.Context, recipe model.Recipe) (*mo
 			if err != nil {
 				return nil, err
 			}
+			recipe.AuthorId = authorId(record)
+			recipe.HouseholdId = householdId(record)
 			return recipe, nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "create recipe", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "create recipe", neo4j.AccessModeWrite, work)
 }
 
 func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*model.Recipe, error) {
@@ -272,12 +298,12 @@ func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*mo
 			}
 			addedIngredientParams := []map[string]any{}
 			for _, ingredientId := range util.SetToArray(addedIngredientIds) {
-				ingredient := map[string]any{"id": ingredientId, "unit": newIngredients[ingredientId].Unit, "amount": newIngredients[ingredientId].Amount}
+				ingredient := amountParams(map[string]any{"id": ingredientId, "unit": newIngredients[ingredientId].Unit}, newIngredients[ingredientId].Amount)
 				addedIngredientParams = append(addedIngredientParams, ingredient)
 			}
 			updatedIngredientParams := []map[string]any{}
 			for _, ingredientId := range util.SetToArray(updatedIngredientIds) {
-				ingredient := map[string]any{"id": ingredientId, "unit": newIngredients[ingredientId].Unit, "amount": newIngredients[ingredientId].Amount}
+				ingredient := amountParams(map[string]any{"id": ingredientId, "unit": newIngredients[ingredientId].Unit}, newIngredients[ingredientId].Amount)
 				updatedIngredientParams = append(updatedIngredientParams, ingredient)
 			}
 
@@ -290,17 +316,25 @@ func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*mo
 			)
 			addIngredientsStatement := fmt.Sprintf("WITH r UNWIND $addedIngredients AS ingredient\n"+
 				"MATCH (i:`%s` {id: ingredient.id})\n"+
-				"CREATE (r)-[:`%s` {unit: ingredient.unit, amount: ingredient.amount, created: $lastModified}]->(i)\n",
+				"CREATE (r)-[:`%s` {unit: ingredient.unit, amount: ingredient.amount, amountMax: ingredient.amountMax, created: $lastModified}]->(i)\n",
 				FoodLabel,
 				ContainsIngredientLabel,
 			)
 			updateIngredientsStatement := fmt.Sprintf("WITH r UNWIND $updatedIngredients AS ingredient\n"+
-				"MATCH (r)-[ci:`%s`]->(:`%s` {id: ingredient.id}) SET ci += {unit: ingredient.unit, amount: ingredient.amount, lastModified: $lastModified}\n",
+				"MATCH (r)-[ci:`%s`]->(:`%s` {id: ingredient.id}) SET ci += {unit: ingredient.unit, amount: ingredient.amount, amountMax: ingredient.amountMax, lastModified: $lastModified}\n",
 				ContainsIngredientLabel, FoodLabel,
 			)
 
-			*query = fmt.Sprintf("MATCH (r:`%s` {id: $id}) SET r += {title: $title, description: $description, steps: $steps, lastModified: $lastModified}\n",
+			*query = fmt.Sprintf("MATCH (r:`%s` {id: $id}) SET r += {title: $title, description: $description, steps: $steps, servings: $servings, visibility: $visibility, lastModified: $lastModified}\n"+
+				// moving the recipe to another household, or out of one, ends its old relationship
+				"WITH r OPTIONAL MATCH (r)-[old:`%s`]->(oldHousehold:`%s`) WHERE old.deleted IS NULL AND oldHousehold.id <> coalesce($householdId, '')\n"+
+				"SET old.deleted = $lastModified\n"+
+				"WITH DISTINCT r OPTIONAL MATCH (h:`%s` {id: $householdId}) WHERE h.deleted IS NULL AND NOT exists { (r)-[rel:`%s`]->(h) WHERE rel.deleted IS NULL }\n"+
+				"FOREACH (household IN CASE WHEN h IS NULL THEN [] ELSE [h] END | CREATE (r)-[:`%s` {created: $lastModified}]->(household))\n",
 				RecipeLabel,
+				BelongsToLabel, HouseholdLabel,
+				HouseholdLabel, BelongsToLabel,
+				BelongsToLabel,
 			)
 			if len(removedIngredientParams) > 0 {
 				*query = *query + removeIngredientsStatement
@@ -312,8 +346,12 @@ func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*mo
 				*query = *query + updateIngredientsStatement
 			}
 			*query = *query + fmt.Sprintf("WITH r MATCH (r)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL\n"+
-				"RETURN r AS recipe, collect({ingredient: i, rel: ci}) AS ingredients",
+				"WITH r, collect({ingredient: i, rel: ci}) AS ingredients\n"+
+				"%s\n"+
+				"RETURN r AS recipe, ingredients, %s, %s",
 				ContainsIngredientLabel, FoodLabel,
+				setSearchTextStatement,
+				authorIdColumn("r"), householdIdColumn("r"),
 			)
 
 			params = map[string]any{
@@ -321,6 +359,9 @@ func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*mo
 				"description":        recipe.Description,
 				"title":              recipe.Title,
 				"steps":              recipe.Steps,
+				"servings":           recipe.Servings,
+				"visibility":         storedVisibility(recipe.Visibility),
+				"householdId":        optionalId(recipe.HouseholdId),
 				"removedIngredients": removedIngredientParams,
 				"addedIngredients":   addedIngredientParams,
 				"updatedIngredients": updatedIngredientParams,
@@ -352,11 +393,13 @@ func (r *RecipeRepository) Update(ctx context.Context, recipe model.Recipe) (*mo
 			if err != nil {
 				return nil, err
 			}
+			recipe.AuthorId = authorId(record)
+			recipe.HouseholdId = householdId(record)
 			return recipe, nil
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "update recipe", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "update recipe", neo4j.AccessModeWrite, work)
 }
 
 // TODO return *string?
@@ -387,7 +430,288 @@ func (r *RecipeRepository) Delete(ctx context.Context, id string) (string, error
 		})
 	}
 
-	return RunQuery(ctx, r.driver, "delete recipe", neo4j.AccessModeWrite, work)
+	return RunQuery(ctx, r.driver, r.database, "delete recipe", neo4j.AccessModeWrite, work)
+}
+
+// ingredientsColumn returns the live ingredients of the recipe bound to v as ingredients, a list of {ingredient, rel} maps.
+// A recipe whose foods were all deleted still has a row, with no ingredients.
+func ingredientsColumn(v string) string {
+	return fmt.Sprintf("[(%s)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL | {ingredient: i, rel: ci}] AS ingredients",
+		v, ContainsIngredientLabel, FoodLabel)
+}
+
+// fulltext indexes can't look into lists or across relationships, so recipes keep searchable copies of their steps and ingredient names.
+// Expects r and ingredients (a list of {ingredient, rel} maps) to be in scope.
+var setSearchTextStatement = "SET r.stepsText = reduce(text = '', step IN coalesce(r.steps, []) | text + step + '\\n'),\n" +
+	"r.ingredientsText = reduce(text = '', ingredient IN ingredients | text + ingredient.ingredient.name + '\\n')"
+
+// refreshIngredientsText rewrites the ingredientsText of every recipe that uses the food, after its name changed or it was deleted
+func refreshIngredientsText(ctx context.Context, tx neo4j.ManagedTransaction, foodId string) error {
+	query := fmt.Sprintf("MATCH (r:`%[1]s`)-[:`%[2]s`]->(:`%[3]s` {id: $foodId})\n"+
+		"WITH DISTINCT r\n"+
+		"SET r.ingredientsText = reduce(text = '', name IN [(r)-[ci:`%[2]s`]->(i:`%[3]s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL | i.name] | text + name + '\\n')",
+		RecipeLabel, ContainsIngredientLabel, FoodLabel)
+	_, err := tx.Run(ctx, query, map[string]any{"foodId": foodId})
+	return err
+}
+
+func (r *RecipeRepository) Search(ctx context.Context, search model.RecipeSearch) ([]model.RecipeSearchResult, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.RecipeSearchResult, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.RecipeSearchResult, error) {
+			fulltextQuery := FulltextQuery(search.Text)
+
+			var matchStatement string
+			if fulltextQuery == "" {
+				matchStatement = fmt.Sprintf("MATCH (r:`%s`) WITH r, 0.0 AS score\n", RecipeLabel)
+			} else {
+				matchStatement = fmt.Sprintf("CALL db.index.fulltext.queryNodes($index, $search) YIELD node AS r, score\n"+
+					"WITH r, score WHERE r:`%s`\n", RecipeLabel)
+			}
+
+			params = map[string]any{}
+			visible := "true"
+			if condition := visibleCondition("r", search.Viewer, params); condition != "" {
+				visible = condition
+			}
+			*query = matchStatement + fmt.Sprintf("WITH r, score WHERE r.deleted IS NULL AND %s\n"+
+				"AND all(foodId IN $includeFoodIds WHERE exists { (r)-[ci:`%s`]->(:`%s` {id: foodId}) WHERE ci.deleted IS NULL })\n"+
+				"AND none(foodId IN $excludeFoodIds WHERE exists { (r)-[ci:`%s`]->(:`%s` {id: foodId}) WHERE ci.deleted IS NULL })\n"+
+				"AND ($maxIngredients IS NULL OR count { (r)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL } <= $maxIngredients)\n"+
+				"WITH r, score ORDER BY score DESC, r.id SKIP $skip LIMIT $limit\n"+
+				"RETURN r AS recipe, score, %s, %s, %s ORDER BY score DESC, r.id",
+				visible,
+				ContainsIngredientLabel, FoodLabel,
+				ContainsIngredientLabel, FoodLabel,
+				ContainsIngredientLabel, FoodLabel,
+				ingredientsColumn("r"), authorIdColumn("r"), householdIdColumn("r"),
+			)
+			// a nil slice would be sent as null, which fails every all() and none() check
+			params["index"] = RecipeSearchIndex
+			params["search"] = fulltextQuery
+			params["includeFoodIds"] = append([]string{}, search.IncludeFoodIds...)
+			params["excludeFoodIds"] = append([]string{}, search.ExcludeFoodIds...)
+			params["maxIngredients"] = search.MaxIngredients
+			params["skip"] = search.Skip
+			params["limit"] = search.Limit
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			results := make([]model.RecipeSearchResult, len(records))
+
+			for i := 0; i < len(records); i++ {
+				recipeNode, found := TypedGet[neo4j.Node](records[i], "recipe")
+				if !found {
+					return nil, errors.New("could not find column recipe")
+				}
+
+				recipe, err := ParseRecipeNode(recipeNode)
+				if err != nil {
+					return nil, err
+				}
+
+				rawIngredients, found := TypedGet[[]any](records[i], "ingredients")
+				if !found {
+					return nil, errors.New("could not find column ingredients")
+				}
+				err = setIngredients(recipe, util.UnpackArray[map[string]any](rawIngredients))
+				if err != nil {
+					return nil, err
+				}
+
+				score, found := TypedGet[float64](records[i], "score")
+				if !found {
+					return nil, errors.New("could not find column score")
+				}
+
+				recipe.AuthorId = authorId(records[i])
+				recipe.HouseholdId = householdId(records[i])
+
+				results[i] = model.RecipeSearchResult{Recipe: *recipe, Score: score}
+			}
+
+			return results, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "search recipes", neo4j.AccessModeRead, work)
+}
+
+type ingredientAmount struct {
+	food   *model.Food
+	unit   string
+	amount model.Amount
+}
+
+// getRecipeServings returns the servings of a recipe that hasn't been deleted, and whether there is one
+func getRecipeServings(ctx context.Context, tx neo4j.ManagedTransaction, recipeId string) (*int64, bool, error) {
+	query := fmt.Sprintf("%s WHERE r.deleted IS NULL\n"+
+		"RETURN r.servings AS servings",
+		MatchNodeById("r", []string{RecipeLabel}),
+	)
+	params := map[string]any{"rId": recipeId}
+
+	record, err := RunAndReturnSingleRecord(ctx, tx, query, params)
+	if err != nil && err.Error() == "Result contains no more records" {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	var servings *int64
+	if rawServings, found := record.Get("servings"); found && rawServings != nil {
+		servings = new(int64)
+		*servings = rawServings.(int64)
+	}
+	return servings, true, nil
+}
+
+// getIngredientAmounts returns each ingredient's portions and density too, so that the amounts can be converted to grams
+func getIngredientAmounts(ctx context.Context, tx neo4j.ManagedTransaction, recipeId string) ([]ingredientAmount, error) {
+	query := fmt.Sprintf("%s WHERE r.deleted IS NULL\n"+
+		"MATCH (r)-[ci:`%s`]->(i:`%s`) WHERE ci.deleted IS NULL AND i.deleted IS NULL\n"+
+		"RETURN i, [(i)-[:`%s`]->(p:`%s`) | p] AS portions, ci",
+		MatchNodeById("r", []string{RecipeLabel}),
+		ContainsIngredientLabel, FoodLabel,
+		HasPortionLabel, PortionLabel,
+	)
+	params := map[string]any{"rId": recipeId}
+
+	result, err := tx.Run(ctx, query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := result.Collect(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := make([]ingredientAmount, len(records))
+	for i, record := range records {
+		foodNode, found := TypedGet[neo4j.Node](record, "i")
+		if !found {
+			return nil, errors.New("could not find column i")
+		}
+		food, err := ParseFoodNode(foodNode)
+		if err != nil {
+			return nil, err
+		}
+
+		rawPortions, found := TypedGet[[]any](record, "portions")
+		if !found {
+			return nil, errors.New("could not find column portions")
+		}
+		err = setPortions(food, util.UnpackArray[neo4j.Node](rawPortions))
+		if err != nil {
+			return nil, err
+		}
+
+		containsIngredientRel, found := TypedGet[neo4j.Relationship](record, "ci")
+		if !found {
+			return nil, errors.New("could not find column ci")
+		}
+		unit, err := neo4j.GetProperty[string](containsIngredientRel, "unit")
+		if err != nil {
+			return nil, err
+		}
+		amount, err := ParseAmountProperties(containsIngredientRel)
+		if err != nil {
+			return nil, err
+		}
+		amounts[i] = ingredientAmount{food: food, unit: unit, amount: amount}
+	}
+
+	return amounts, nil
+}
+
+func (r *RecipeRepository) GetNutrition(ctx context.Context, id string) (*model.RecipeNutrition, bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.RecipeNutrition, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (*model.RecipeNutrition, error) {
+			servings, found, err := getRecipeServings(ctx, tx, id)
+			if err != nil || !found {
+				return nil, err
+			}
+			ingredientAmounts, err := getIngredientAmounts(ctx, tx, id)
+			if err != nil {
+				return nil, err
+			}
+
+			// unit conversion lives in the application, but the nutrient totals are aggregated on the db
+			ingredientParams := []map[string]any{}
+			unconvertedIngredientIds := []string{}
+			for _, ia := range ingredientAmounts {
+				grams, ok := ia.food.ToGrams(ia.amount.Mean(), ia.unit)
+				if !ok {
+					unconvertedIngredientIds = append(unconvertedIngredientIds, ia.food.Id)
+					continue
+				}
+				ingredientParams = append(ingredientParams, map[string]any{"id": ia.food.Id, "grams": grams})
+			}
+
+			*query = fmt.Sprintf("UNWIND $ingredients AS ingredient\n"+
+				"MATCH (:`%s` {id: ingredient.id})-[hn:`%s`]->(n:`%s`) WHERE hn.deleted IS NULL\n"+
+				"WITH n, sum(ingredient.grams * hn.amount / 100.0) AS amount\n"+
+				"RETURN n AS nutrient, amount ORDER BY n.name",
+				FoodLabel, HasNutrientLabel, NutrientLabel,
+			)
+			params = map[string]any{
+				"ingredients": ingredientParams,
+			}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			nutrients := make([]model.HasNutrient, len(records))
+			for i, record := range records {
+				nutrientNode, found := TypedGet[neo4j.Node](record, "nutrient")
+				if !found {
+					return nil, errors.New("could not find column nutrient")
+				}
+				nutrient, err := ParseNutrientNode(nutrientNode)
+				if err != nil {
+					return nil, err
+				}
+
+				amount, found := TypedGet[float64](record, "amount")
+				if !found {
+					return nil, errors.New("could not find column amount")
+				}
+
+				nutrients[i] = model.HasNutrient{Amount: amount, Nutrient: *nutrient}
+			}
+
+			return &model.RecipeNutrition{
+				RecipeId:                 id,
+				Servings:                 servings,
+				Nutrients:                nutrients,
+				UnconvertedIngredientIds: unconvertedIngredientIds,
+			}, nil
+		})
+	}
+
+	nutrition, err := RunQuery(ctx, r.driver, r.database, "get recipe nutrition", neo4j.AccessModeRead, work)
+	if err != nil {
+		return nil, false, err
+	} else if nutrition == nil {
+		return nil, false, nil
+	}
+
+	return nutrition, true, nil
 }
 
 func ParseRecipeNode(node dbtype.Node) (*model.Recipe, error) {
@@ -415,12 +739,26 @@ func ParseRecipeNode(node dbtype.Node) (*model.Recipe, error) {
 	}
 	steps := util.UnpackArray[string](rawSteps)
 
+	servings := new(int64)
+	rawServings, err := neo4j.GetProperty[int64](node, "servings")
+	if err != nil {
+		servings = nil
+	} else {
+		*servings = rawServings
+	}
+
+	// recipes from before there was a visibility are public
+	visibility, err := neo4j.GetProperty[string](node, "visibility")
+	if err != nil {
+		visibility = model.PublicVisibility
+	}
+
 	resource, err := ParseResourceEntity(node)
 	if err != nil {
 		return nil, err
 	}
 
-	return &model.Recipe{Id: id, Title: title, Description: description, Steps: steps, Resource: *resource}, nil
+	return &model.Recipe{Id: id, Title: title, Description: description, Steps: steps, Servings: servings, Visibility: visibility, Resource: *resource}, nil
 }
 
 func setIngredients(recipe *model.Recipe, ingredients []map[string]any) error {
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"fmt"
+
 	"github.com/ThomasMatlak/food/model"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
@@ -12,12 +14,17 @@ func ParseContainsIngredientRelationship(ingredient *dbtype.Node, rel *dbtype.Re
 		return nil, err
 	}
 
+	ingredientName, err := neo4j.GetProperty[string](ingredient, "name")
+	if err != nil {
+		return nil, err
+	}
+
 	unit, err := neo4j.GetProperty[string](rel, "unit")
 	if err != nil {
 		return nil, err
 	}
 
-	amount, err := neo4j.GetProperty[int64](rel, "amount")
+	amount, err := ParseAmountProperties(rel)
 	if err != nil {
 		return nil, err
 	}
@@ -27,5 +34,60 @@ func ParseContainsIngredientRelationship(ingredient *dbtype.Node, rel *dbtype.Re
 		return nil, err
 	}
 
-	return &model.ContainsIngredient{Unit: unit, Amount: amount, IngredientId: ingredientId, Resource: *resource}, nil
+	return &model.ContainsIngredient{Unit: unit, Amount: amount, IngredientId: ingredientId, IngredientName: ingredientName, Resource: *resource}, nil
+}
+
+func ParseHasNutrientRelationship(nutrient *dbtype.Node, rel *dbtype.Relationship) (*model.HasNutrient, error) {
+	parsedNutrient, err := ParseNutrientNode(*nutrient)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, err := neo4j.GetProperty[float64](rel, "amount")
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.HasNutrient{Amount: amount, Nutrient: *parsedNutrient}, nil
+}
+
+// ParseAmountProperties reads the amount and optional amountMax properties.
+// Amounts used to be stored as integers, so those are still accepted.
+func ParseAmountProperties(entity dbtype.Entity) (model.Amount, error) {
+	min, err := parseNumberProperty(entity, "amount")
+	if err != nil {
+		return model.Amount{}, err
+	}
+
+	if _, found := entity.GetProperties()["amountMax"]; !found {
+		return model.ExactAmount(min), nil
+	}
+
+	max, err := parseNumberProperty(entity, "amountMax")
+	if err != nil {
+		return model.Amount{}, err
+	}
+	return model.AmountRange(min, max), nil
+}
+
+func parseNumberProperty(entity dbtype.Entity, key string) (float64, error) {
+	rawValue, found := entity.GetProperties()[key]
+	if !found {
+		return 0, fmt.Errorf("property %s not found", key)
+	}
+
+	switch value := rawValue.(type) {
+	case float64:
+		return value, nil
+	case int64:
+		return float64(value), nil
+	default:
+		return 0, fmt.Errorf("expected property %s to be a number, but it was %T", key, rawValue)
+	}
+}
+
+func amountParams(params map[string]any, amount model.Amount) map[string]any {
+	params["amount"] = amount.Min
+	params["amountMax"] = amount.Max
+	return params
 }
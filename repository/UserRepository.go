@@ -0,0 +1,443 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+)
+
+type UserRepository struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+}
+
+func NewUserRepository(driver neo4j.DriverWithContext, database string) *UserRepository {
+	return &UserRepository{driver: driver, database: database}
+}
+
+// getUser runs a query that returns a single user as u, treating no rows as not found
+func (r *UserRepository) getUser(ctx context.Context, action string, accessMode neo4j.AccessMode, query string, params map[string]any) (*model.User, bool, error) {
+	execute := neo4j.ExecuteRead[*model.User]
+	if accessMode == neo4j.AccessModeWrite {
+		execute = neo4j.ExecuteWrite[*model.User]
+	}
+
+	work := func(ctx context.Context, session neo4j.SessionWithContext, q *string, p map[string]any) (*model.User, error) {
+		return execute(ctx, session, func(tx neo4j.ManagedTransaction) (*model.User, error) {
+			*q = query
+			record, err := RunAndReturnSingleRecord(ctx, tx, query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			userNode, found := TypedGet[neo4j.Node](record, "u")
+			if !found {
+				return nil, errors.New("could not find column u")
+			}
+			user, err := ParseUserNode(userNode)
+			if err != nil {
+				return nil, err
+			}
+
+			user.HouseholdIds, err = getHouseholdIds(ctx, tx, user.Id)
+			return user, err
+		})
+	}
+
+	user, err := RunQuery(ctx, r.driver, r.database, action, accessMode, work)
+
+	if err != nil && err.Error() == "Result contains no more records" {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	return user, true, nil
+}
+
+// getHouseholdIds returns the ids of the households the user is a member of
+func getHouseholdIds(ctx context.Context, tx neo4j.ManagedTransaction, userId string) ([]string, error) {
+	query := fmt.Sprintf("%s-[rel:`%s`]->(h:`%s`) WHERE rel.deleted IS NULL AND h.deleted IS NULL\n"+
+		"WITH h ORDER BY h.id\n"+
+		"RETURN collect(h.id) AS householdIds",
+		MatchNodeById("u", []string{UserLabel}), MemberOfLabel, HouseholdLabel)
+
+	record, err := RunAndReturnSingleRecord(ctx, tx, query, map[string]any{"uId": userId})
+	if err != nil {
+		return nil, err
+	}
+
+	householdIds, found := TypedGet[[]any](record, "householdIds")
+	if !found {
+		return nil, errors.New("could not find column householdIds")
+	}
+	return util.UnpackArray[string](householdIds), nil
+}
+
+func (r *UserRepository) GetById(ctx context.Context, id string) (*model.User, bool, error) {
+	query := fmt.Sprintf("%s WHERE u.deleted IS NULL RETURN u", MatchNodeById("u", []string{UserLabel}))
+	return r.getUser(ctx, "get user", neo4j.AccessModeRead, query, map[string]any{"uId": id})
+}
+
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*model.User, bool, error) {
+	query := fmt.Sprintf("MATCH (u:`%s` {username: $username}) WHERE u.deleted IS NULL RETURN u", UserLabel)
+	return r.getUser(ctx, "get user by username", neo4j.AccessModeRead, query, map[string]any{"username": username})
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, username string, role string) (*model.User, bool, error) {
+	query := fmt.Sprintf("MATCH (u:`%s` {username: $username}) WHERE u.deleted IS NULL\n"+
+		"SET u += {role: $role, lastModified: $lastModified}\n"+
+		"RETURN u", UserLabel)
+	params := map[string]any{"username": username, "role": role, "lastModified": neo4j.LocalDateTime(time.Now())}
+	return r.getUser(ctx, "set user role", neo4j.AccessModeWrite, query, params)
+}
+
+func (r *UserRepository) Create(ctx context.Context, user model.User) (*model.User, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.User, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.User, error) {
+			labels := []string{UserLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			// the username constraint catches concurrent signups; this catches the rest with a clearer error
+			*query = fmt.Sprintf("OPTIONAL MATCH (existing:`%s` {username: $username})\n"+
+				"WITH count(existing) AS existing WHERE existing = 0\n"+
+				"CREATE (u:`%s`) SET u = {id: $id, username: $username, passwordHash: $passwordHash, role: $role, created: $created}\n"+
+				"RETURN u",
+				UserLabel, strings.Join(labels, "`:`"))
+			params = map[string]any{
+				"id":           id,
+				"username":     user.Username,
+				"passwordHash": user.PasswordHash,
+				"role":         userRole(user.Role),
+				"created":      neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil && err.Error() == "Result contains no more records" {
+				return nil, model.ErrUsernameTaken
+			} else if err != nil {
+				return nil, err
+			}
+
+			userNode, found := TypedGet[neo4j.Node](record, "u")
+			if !found {
+				return nil, errors.New("could not find column u")
+			}
+			return ParseUserNode(userNode)
+		})
+	}
+
+	created, err := RunQuery(ctx, r.driver, r.database, "create user", neo4j.AccessModeWrite, work)
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) && neo4jErr.Code == "Neo.ClientError.Schema.ConstraintValidationFailed" {
+		return nil, model.ErrUsernameTaken
+	}
+	return created, err
+}
+
+// CreateFirst takes a write lock on a node that every first signup shares before looking for users, so a concurrent one
+// waits for this one to commit and then sees its user. The lock node's name is unique, see the 0005 migration.
+func (r *UserRepository) CreateFirst(ctx context.Context, user model.User) (*model.User, bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.User, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.User, error) {
+			labels := []string{UserLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			*query = fmt.Sprintf("MERGE (lock:`%s` {name: 'first user'})\n"+
+				"SET lock.taken = $created\n"+
+				"WITH lock WHERE NOT exists { (existing:`%s`) WHERE existing.deleted IS NULL }\n"+
+				"CREATE (u:`%s`) SET u = {id: $id, username: $username, passwordHash: $passwordHash, role: $role, created: $created}\n"+
+				"RETURN u",
+				LockLabel, UserLabel, strings.Join(labels, "`:`"))
+			params = map[string]any{
+				"id":           id,
+				"username":     user.Username,
+				"passwordHash": user.PasswordHash,
+				"role":         userRole(user.Role),
+				"created":      neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil && err.Error() == "Result contains no more records" {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+
+			userNode, found := TypedGet[neo4j.Node](record, "u")
+			if !found {
+				return nil, errors.New("could not find column u")
+			}
+			return ParseUserNode(userNode)
+		})
+	}
+
+	created, err := RunQuery(ctx, r.driver, r.database, "create first user", neo4j.AccessModeWrite, work)
+	var neo4jErr *neo4j.Neo4jError
+	if errors.As(err, &neo4jErr) && neo4jErr.Code == "Neo.ClientError.Schema.ConstraintValidationFailed" {
+		// a deleted user still holds their username
+		return nil, false, model.ErrUsernameTaken
+	} else if err != nil || created == nil {
+		return nil, false, err
+	}
+	return created, true, nil
+}
+
+func (r *UserRepository) HasUsers(ctx context.Context) (bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (bool, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) (bool, error) {
+			*query = fmt.Sprintf("RETURN exists { (u:`%s`) WHERE u.deleted IS NULL } AS hasUsers", UserLabel)
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return false, err
+			}
+
+			hasUsers, found := TypedGet[bool](record, "hasUsers")
+			if !found {
+				return false, errors.New("could not find column hasUsers")
+			}
+			return hasUsers, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "check for users", neo4j.AccessModeRead, work)
+}
+
+func (r *UserRepository) CreateSession(ctx context.Context, userId string, tokenHash string, expires time.Time) error {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (any, error) {
+			*query = fmt.Sprintf("%s WHERE u.deleted IS NULL\n"+
+				"CREATE (u)-[:`%s`]->(s:`%s` {tokenHash: $tokenHash, expires: $expires, created: $created})\n"+
+				"RETURN s",
+				MatchNodeById("u", []string{UserLabel}), HasSessionLabel, SessionLabel)
+			params = map[string]any{
+				"uId":       userId,
+				"tokenHash": tokenHash,
+				"expires":   neo4j.LocalDateTime(expires),
+				"created":   neo4j.LocalDateTime(time.Now()),
+			}
+
+			return RunAndReturnSingleRecord(ctx, tx, *query, params)
+		})
+	}
+
+	_, err := RunQuery(ctx, r.driver, r.database, "create session", neo4j.AccessModeWrite, work)
+	return err
+}
+
+func (r *UserRepository) GetSessionUser(ctx context.Context, tokenHash string) (*model.User, bool, error) {
+	query := fmt.Sprintf("MATCH (u:`%s`)-[:`%s`]->(s:`%s` {tokenHash: $tokenHash})\n"+
+		"WHERE u.deleted IS NULL AND s.expires > $now\n"+
+		"RETURN u",
+		UserLabel, HasSessionLabel, SessionLabel)
+	return r.getUser(ctx, "get session user", neo4j.AccessModeRead, query, map[string]any{"tokenHash": tokenHash, "now": neo4j.LocalDateTime(time.Now())})
+}
+
+// DeleteSession removes the session outright; an ended session isn't worth keeping
+func (r *UserRepository) DeleteSession(ctx context.Context, tokenHash string) error {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (any, error) {
+			*query = fmt.Sprintf("MATCH (s:`%s` {tokenHash: $tokenHash}) DETACH DELETE s", SessionLabel)
+			params = map[string]any{"tokenHash": tokenHash}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	}
+
+	_, err := RunQuery(ctx, r.driver, r.database, "delete session", neo4j.AccessModeWrite, work)
+	return err
+}
+
+func (r *UserRepository) CreateApiKey(ctx context.Context, userId string, name string, tokenHash string) (*model.ApiKey, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (*model.ApiKey, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (*model.ApiKey, error) {
+			labels := []string{ApiKeyLabel, ResourceLabel}
+			id, err := model.ResourceId(labels)
+			if err != nil {
+				return nil, err
+			}
+
+			*query = fmt.Sprintf("%s WHERE u.deleted IS NULL\n"+
+				"CREATE (u)-[:`%s`]->(k:`%s`) SET k = {id: $id, name: $name, tokenHash: $tokenHash, created: $created}\n"+
+				"RETURN k",
+				MatchNodeById("u", []string{UserLabel}), HasApiKeyLabel, strings.Join(labels, "`:`"))
+			params = map[string]any{
+				"uId":       userId,
+				"id":        id,
+				"name":      name,
+				"tokenHash": tokenHash,
+				"created":   neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+
+			keyNode, found := TypedGet[neo4j.Node](record, "k")
+			if !found {
+				return nil, errors.New("could not find column k")
+			}
+			return ParseApiKeyNode(keyNode)
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "create api key", neo4j.AccessModeWrite, work)
+}
+
+func (r *UserRepository) GetApiKeys(ctx context.Context, userId string) ([]model.ApiKey, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) ([]model.ApiKey, error) {
+		return neo4j.ExecuteRead(ctx, session, func(tx neo4j.ManagedTransaction) ([]model.ApiKey, error) {
+			*query = fmt.Sprintf("%s-[:`%s`]->(k:`%s`) WHERE k.deleted IS NULL\n"+
+				"RETURN k ORDER BY k.created, k.id",
+				MatchNodeById("u", []string{UserLabel}), HasApiKeyLabel, ApiKeyLabel)
+			params = map[string]any{"uId": userId}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			records, err := result.Collect(ctx)
+			if err != nil {
+				return nil, err
+			}
+
+			keys := []model.ApiKey{}
+			for _, record := range records {
+				keyNode, found := TypedGet[neo4j.Node](record, "k")
+				if !found {
+					return nil, errors.New("could not find column k")
+				}
+				key, err := ParseApiKeyNode(keyNode)
+				if err != nil {
+					return nil, err
+				}
+				keys = append(keys, *key)
+			}
+			return keys, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "get api keys", neo4j.AccessModeRead, work)
+}
+
+func (r *UserRepository) GetApiKeyUser(ctx context.Context, tokenHash string) (*model.User, bool, error) {
+	query := fmt.Sprintf("MATCH (u:`%s`)-[:`%s`]->(k:`%s` {tokenHash: $tokenHash})\n"+
+		"WHERE u.deleted IS NULL AND k.deleted IS NULL\n"+
+		"SET k.lastUsed = $now\n"+
+		"RETURN u",
+		UserLabel, HasApiKeyLabel, ApiKeyLabel)
+	return r.getUser(ctx, "get api key user", neo4j.AccessModeWrite, query, map[string]any{"tokenHash": tokenHash, "now": neo4j.LocalDateTime(time.Now())})
+}
+
+func (r *UserRepository) DeleteApiKey(ctx context.Context, userId string, id string) (bool, error) {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (bool, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (bool, error) {
+			*query = fmt.Sprintf("%s-[rel:`%s`]->(k:`%s` {id: $id}) WHERE k.deleted IS NULL\n"+
+				"SET k.deleted = $deleted, rel.deleted = $deleted\n"+
+				"RETURN count(k) AS deleted",
+				MatchNodeById("u", []string{UserLabel}), HasApiKeyLabel, ApiKeyLabel)
+			params = map[string]any{
+				"uId":     userId,
+				"id":      id,
+				"deleted": neo4j.LocalDateTime(time.Now()),
+			}
+
+			record, err := RunAndReturnSingleRecord(ctx, tx, *query, params)
+			if err != nil {
+				return false, err
+			}
+
+			deleted, found := TypedGet[int64](record, "deleted")
+			if !found {
+				return false, errors.New("could not find column deleted")
+			}
+			return deleted > 0, nil
+		})
+	}
+
+	return RunQuery(ctx, r.driver, r.database, "delete api key", neo4j.AccessModeWrite, work)
+}
+
+func ParseUserNode(node dbtype.Node) (*model.User, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	username, err := neo4j.GetProperty[string](node, "username")
+	if err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := neo4j.GetProperty[string](node, "passwordHash")
+	if err != nil {
+		return nil, err
+	}
+
+	// users from before there were roles are cooks
+	role, err := neo4j.GetProperty[string](node, "role")
+	if err != nil {
+		role = model.CookRole
+	}
+
+	resource, err := ParseResourceEntity(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.User{Id: id, Username: username, PasswordHash: passwordHash, Role: role, HouseholdIds: []string{}, Resource: *resource}, nil
+}
+
+func userRole(role string) string {
+	if role == "" {
+		return model.CookRole
+	}
+	return role
+}
+
+func ParseApiKeyNode(node dbtype.Node) (*model.ApiKey, error) {
+	id, err := neo4j.GetProperty[string](node, "id")
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := neo4j.GetProperty[string](node, "name")
+	if err != nil {
+		return nil, err
+	}
+
+	lastUsed := new(time.Time)
+	rawLastUsed, err := neo4j.GetProperty[neo4j.LocalDateTime](node, "lastUsed")
+	if err != nil {
+		lastUsed = nil
+	} else {
+		*lastUsed = rawLastUsed.Time()
+	}
+
+	resource, err := ParseResourceEntity(node)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.ApiKey{Id: id, Name: name, LastUsed: lastUsed, Resource: *resource}, nil
+}
@@ -16,11 +16,12 @@ import (
 func RunQuery[T any](
 	ctx context.Context,
 	driver neo4j.DriverWithContext, // TODO *neo4j.DriverWithContext?
+	database string,
 	action string,
 	accessMode neo4j.AccessMode,
 	work func(context.Context, neo4j.SessionWithContext, *string, map[string]any) (T, error),
 ) (T, error) {
-	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: accessMode})
+	session := driver.NewSession(ctx, neo4j.SessionConfig{AccessMode: accessMode, DatabaseName: database})
 	defer session.Close(ctx)
 
 	var query string
@@ -29,6 +30,7 @@ func RunQuery[T any](
 	result, err := work(ctx, session, &query, params)
 
 	log.Debug().
+		Str("database", database).
 		Str("action", action).
 		Str("query", query).
 		Any("params", params).
@@ -43,6 +45,14 @@ func TypedGet[T any](record *db.Record, column string) (T, bool) {
 	return val.(T), found
 }
 
+// newId is the id a resource is created with: the one it already has, e.g. from an export, or else a new one
+func newId(id string, labels []string) (string, error) {
+	if id != "" {
+		return id, nil
+	}
+	return model.ResourceId(labels)
+}
+
 func MatchNodeById(name string, labels []string) string {
 	return fmt.Sprintf("MATCH (`%s`:`%s` {id: $%sId})", name, strings.Join(labels, "`:`"), name)
 }
@@ -79,3 +89,24 @@ func ParseResourceEntity(node dbtype.Entity) (*model.Resource, error) {
 
 	return &model.Resource{Created: created, LastModified: lastModified}, nil
 }
+
+var luceneSpecialCharacters = strings.NewReplacer(
+	`\`, `\\`, `+`, `\+`, `-`, `\-`, `&`, `\&`, `|`, `\|`, `!`, `\!`, `(`, `\(`, `)`, `\)`,
+	`{`, `\{`, `}`, `\}`, `[`, `\[`, `]`, `\]`, `^`, `\^`, `"`, `\"`, `~`, `\~`, `*`, `\*`,
+	`?`, `\?`, `:`, `\:`, `/`, `\/`,
+)
+
+// FulltextQuery turns user input into a Lucene query where every word has to match, either as a prefix or fuzzily.
+// Short words are only matched as prefixes since fuzzy matching them matches almost anything.
+func FulltextQuery(text string) string {
+	terms := []string{}
+	for _, word := range strings.Fields(strings.ToLower(text)) {
+		escaped := luceneSpecialCharacters.Replace(word)
+		if len([]rune(word)) < 4 {
+			terms = append(terms, escaped+"*")
+		} else {
+			terms = append(terms, fmt.Sprintf("(%s* OR %s~)", escaped, escaped))
+		}
+	}
+	return strings.Join(terms, " AND ")
+}
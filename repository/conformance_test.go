@@ -0,0 +1,49 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/ThomasMatlak/food/repository/repositorytest"
+)
+
+func TestNeo4jConformance(t *testing.T) {
+	ctx := context.Background()
+
+	neo4jContainer, err := startNeo4j(ctx, t)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	neo4jDriver, err := neo4jDriver(ctx, t, neo4jContainer)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		return repositorytest.Repositories{
+			Foods:      repository.NewFoodRepository(*neo4jDriver, ""),
+			Recipes:    repository.NewRecipeRepository(*neo4jDriver, ""),
+			Users:      repository.NewUserRepository(*neo4jDriver, ""),
+			Households: repository.NewHouseholdRepository(*neo4jDriver, ""),
+			ListItems:  repository.NewListItemRepository(*neo4jDriver, ""),
+		}
+	})
+}
+
+func TestMemoryConformance(t *testing.T) {
+	repositorytest.Run(t, func(t *testing.T) repositorytest.Repositories {
+		store := repository.NewMemoryStore()
+		return repositorytest.Repositories{
+			Foods:      repository.NewMemoryFoodRepository(store),
+			Recipes:    repository.NewMemoryRecipeRepository(store),
+			Users:      repository.NewMemoryUserRepository(store),
+			Households: repository.NewMemoryHouseholdRepository(store),
+			ListItems:  repository.NewMemoryListItemRepository(store),
+		}
+	})
+}
@@ -30,12 +30,16 @@ func TestFoodRepository(t *testing.T) {
 		t.FailNow()
 	}
 
-	repo := repository.NewFoodRepository(*neo4jDriver)
+	repo := repository.NewFoodRepository(*neo4jDriver, "")
 
 	t.Run("Get One", func(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testGetOneFood(ctx, neo4jDriver, repo, t)
 	})
+	t.Run("Get One (with nutrients)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetOneWithNutrientsFood(ctx, neo4jDriver, repo, t)
+	})
 	t.Run("Get One (does not exist)", func(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testGetOneDoesNotExistFood(ctx, neo4jDriver, repo, t)
@@ -48,6 +52,22 @@ func TestFoodRepository(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testGetAllEmptyFood(ctx, neo4jDriver, repo, t)
 	})
+	t.Run("Get All (paginated)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetAllPaginatedFood(ctx, neo4jDriver, repo, t)
+	})
+	t.Run("Get All (sorted and filtered)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetAllSortedAndFilteredFood(ctx, neo4jDriver, repo, t)
+	})
+	t.Run("Get All (sorted by a missing time)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetAllMissingTimeFood(ctx, neo4jDriver, repo, t)
+	})
+	t.Run("Search", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testSearchFood(ctx, neo4jDriver, repo, t)
+	})
 	t.Run("Create", func(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testCreateFood(ctx, neo4jDriver, repo, t)
@@ -100,6 +120,42 @@ func testGetOneFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, r
 	assert.Nil(food.Deleted)
 }
 
+func testGetOneWithNutrientsFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
+	// seed data
+	id := "123"
+
+	query := "CREATE (f:Food {id: $id, name: $name, created: $created})\n" +
+		"CREATE (f)-[:HAS_NUTRIENT {amount: 3.5}]->(:Nutrient {id: 'protein', name: 'Protein', unit_name: 'G'})\n" +
+		"CREATE (f)-[:HAS_NUTRIENT {amount: 120.0}]->(:Nutrient {id: 'sodium', name: 'Sodium, Na', unit_name: 'MG'})"
+	createdTime := time.Now()
+	params := map[string]any{
+		"id":      id,
+		"name":    "test food",
+		"created": neo4j.LocalDateTime(createdTime),
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	food, found, err := repo.GetById(ctx, id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(id, food.Id)
+	assert.ElementsMatch([]model.HasNutrient{
+		{Amount: 3.5, Nutrient: model.Nutrient{Id: "protein", Name: "Protein", UnitName: "G"}},
+		{Amount: 120.0, Nutrient: model.Nutrient{Id: "sodium", Name: "Sodium, Na", UnitName: "MG"}},
+	}, food.Nutrients)
+}
+
 func testGetOneDoesNotExistFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
 	// no seed data
 
@@ -140,26 +196,199 @@ func testGetAllFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, r
 	}
 
 	// test
-	foods, err := repo.GetAll(ctx)
+	foods, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
 
 	assert := assert.New(t)
 	assert.NoError(err)
+	assert.Nil(foods.NextCursor)
 	// comparing time stamps is tricky
-	foodsWithoutCreated := util.MapArray(foods, func(i model.Food) model.Food {
+	foodsWithoutCreated := util.MapArray(foods.Items, func(i model.Food) model.Food {
 		return model.Food{Id: i.Id, Name: i.Name}
 	})
 	assert.ElementsMatch(seedFoods, foodsWithoutCreated)
 }
 
+func testSearchFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
+	// seed data
+	session := (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// schema changes can't share a transaction with data changes
+	for _, query := range []string{
+		fmt.Sprintf("CREATE FULLTEXT INDEX %s IF NOT EXISTS FOR (n:Food) ON EACH [n.name]", repository.FoodNameSearchIndex),
+		"CALL db.awaitIndexes()",
+	} {
+		_, err := session.Run(ctx, query, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	query := "CREATE (:Food {id: '123', name: 'Tomatoes, red, ripe, raw', created: $created}), " +
+		"(:Food {id: '456', name: 'Tomato soup', created: $created}), " +
+		"(:Food {id: '789', name: 'Potatoes, raw', created: $created}), " +
+		"(:Food {id: '000', name: 'Tomato paste', created: $created, deleted: true})"
+	params := map[string]any{"created": neo4j.LocalDateTime(time.Now())}
+
+	_, err := neo4j.ExecuteWrite(ctx, session,
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	assert := assert.New(t)
+
+	// the fulltext index is updated asynchronously
+	var results []model.FoodSearchResult
+	assert.Eventually(func() bool {
+		results, err = repo.Search(ctx, model.FoodSearch{Text: "tomatos", Limit: 10})
+		return err == nil && len(results) == 2
+	}, 10*time.Second, 100*time.Millisecond)
+	assert.NoError(err)
+	assert.ElementsMatch([]string{"123", "456"}, util.MapArray(results, func(r model.FoodSearchResult) string { return r.Food.Id }))
+	for _, result := range results {
+		assert.Greater(result.Score, 0.0)
+	}
+
+	page, err := repo.Search(ctx, model.FoodSearch{Text: "tomato", Skip: 1, Limit: 10})
+	assert.NoError(err)
+	assert.Len(page, 1)
+
+	empty, err := repo.Search(ctx, model.FoodSearch{Text: "   ", Limit: 10})
+	assert.NoError(err)
+	assert.Empty(empty)
+}
+
 func testGetAllEmptyFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
 	// no seed data
 
 	// test
-	foods, err := repo.GetAll(ctx)
+	foods, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
 
 	assert := assert.New(t)
 	assert.NoError(err)
-	assert.Empty(foods)
+	assert.Empty(foods.Items)
+	assert.Nil(foods.NextCursor)
+}
+
+func testGetAllPaginatedFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
+	// seed data
+	query := "UNWIND $ids AS id CREATE (:Food {id: id, name: 'test food ' + id, created: $created})"
+	params := map[string]any{
+		"ids":     []string{"5", "3", "1", "4", "2"},
+		"created": neo4j.LocalDateTime(time.Now()),
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	assert := assert.New(t)
+	extractId := func(f model.Food) string { return f.Id }
+
+	first, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 2}})
+	assert.NoError(err)
+	assert.Equal([]string{"1", "2"}, util.MapArray(first.Items, extractId))
+	assert.NotNil(first.NextCursor)
+
+	second, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 2, Cursor: first.NextCursor}})
+	assert.NoError(err)
+	assert.Equal([]string{"3", "4"}, util.MapArray(second.Items, extractId))
+	assert.NotNil(second.NextCursor)
+
+	last, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 2, Cursor: second.NextCursor}})
+	assert.NoError(err)
+	assert.Equal([]string{"5"}, util.MapArray(last.Items, extractId))
+	assert.Nil(last.NextCursor)
+}
+
+func testGetAllSortedAndFilteredFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
+	// seed data
+	query := "UNWIND $foods AS f CREATE (:Food {id: f.id, name: f.name, created: f.created})"
+	now := time.Now()
+	params := map[string]any{
+		"foods": []map[string]any{
+			{"id": "1", "name": "apple", "created": neo4j.LocalDateTime(now.Add(-3 * time.Hour))},
+			{"id": "2", "name": "apricot", "created": neo4j.LocalDateTime(now.Add(-2 * time.Hour))},
+			{"id": "3", "name": "banana", "created": neo4j.LocalDateTime(now.Add(-1 * time.Hour))},
+			{"id": "4", "name": "apple", "created": neo4j.LocalDateTime(now)},
+		},
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	assert := assert.New(t)
+	extractId := func(f model.Food) string { return f.Id }
+	sort := []model.SortField{{Field: "name"}, {Field: "created", Descending: true}}
+
+	first, err := repo.GetAll(ctx, model.ListQuery{Sort: sort, PageRequest: model.PageRequest{Limit: 2}})
+	assert.NoError(err)
+	assert.Equal([]string{"4", "1"}, util.MapArray(first.Items, extractId))
+
+	second, err := repo.GetAll(ctx, model.ListQuery{Sort: sort, PageRequest: model.PageRequest{Limit: 2, Cursor: first.NextCursor}})
+	assert.NoError(err)
+	assert.Equal([]string{"2", "3"}, util.MapArray(second.Items, extractId))
+	assert.Nil(second.NextCursor)
+
+	createdAfter := now.Add(-150 * time.Minute)
+	filtered, err := repo.GetAll(ctx, model.ListQuery{NamePrefix: "ap", CreatedAfter: &createdAfter, PageRequest: model.PageRequest{Limit: 10}})
+	assert.NoError(err)
+	assert.Equal([]string{"2", "4"}, util.MapArray(filtered.Items, extractId))
+}
+
+func testGetAllMissingTimeFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
+	// seed data; foods from before created was recorded don't have it
+	query := "CREATE (:Food {id: '1', name: 'old'}), (:Food {id: '2', name: 'older'}), (:Food {id: '3', name: 'new', created: $created})"
+	params := map[string]any{"created": neo4j.LocalDateTime(time.Now())}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	assert := assert.New(t)
+	for _, sort := range []model.SortField{{Field: "created"}, {Field: "modified"}} {
+		ids := []string{}
+		listQuery := model.ListQuery{Sort: []model.SortField{sort}, PageRequest: model.PageRequest{Limit: 1}}
+		for {
+			page, err := repo.GetAll(ctx, listQuery)
+			if !assert.NoError(err) {
+				break
+			}
+			ids = append(ids, util.MapArray(page.Items, func(f model.Food) string { return f.Id })...)
+			if page.NextCursor == nil {
+				break
+			}
+			// the cursor goes through the url like any other
+			listQuery.Cursor, err = model.DecodeCursor(page.NextCursor.Encode())
+			assert.NoError(err)
+		}
+		assert.Equal([]string{"1", "2", "3"}, ids, "foods without a time come first when sorting by %s", sort.Field)
+	}
 }
 
 func testCreateFood(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.FoodRepository, t *testing.T) {
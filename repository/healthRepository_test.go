@@ -0,0 +1,41 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHealthRepository(t *testing.T) {
+	ctx := context.Background()
+
+	neo4jContainer, err := startNeo4j(ctx, t)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	neo4jDriver, err := neo4jDriver(ctx, t, neo4jContainer)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	repo := repository.NewHealthRepository(*neo4jDriver, "")
+
+	// a fresh database has no constraints
+	assert.ErrorContains(t, repo.Ready(ctx), "missing uniqueness constraint")
+
+	session := (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+	_, err = session.Run(ctx, "CREATE CONSTRAINT id IF NOT EXISTS FOR (r:Resource) REQUIRE r.id IS UNIQUE", nil)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	assert.NoError(t, repo.Ready(ctx))
+}
@@ -0,0 +1,204 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryFoodRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Stored foods are copies", func(t *testing.T) {
+		repo := repository.NewMemoryFoodRepository(repository.NewMemoryStore())
+		assert := assert.New(t)
+
+		density := 1.1
+		created, err := repo.Create(ctx, model.Food{Name: "Garlic", Density: &density})
+		assert.NoError(err)
+
+		density = 2
+		*created.Density = 3
+		food, found, err := repo.GetById(ctx, created.Id)
+		assert.NoError(err)
+		assert.True(found)
+		assert.Equal(1.1, *food.Density)
+	})
+
+	t.Run("Get All (sorted and paginated)", func(t *testing.T) {
+		repo := repository.NewMemoryFoodRepository(repository.NewMemoryStore())
+		for _, name := range []string{"banana", "apple", "cherry", "apricot"} {
+			if _, err := repo.Create(ctx, model.Food{Name: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		extractName := func(f model.Food) string { return f.Name }
+		sort := []model.SortField{{Field: "name", Descending: true}}
+		assert := assert.New(t)
+
+		first, err := repo.GetAll(ctx, model.ListQuery{Sort: sort, PageRequest: model.PageRequest{Limit: 3}})
+		assert.NoError(err)
+		assert.Equal([]string{"cherry", "banana", "apricot"}, util.MapArray(first.Items, extractName))
+		assert.NotNil(first.NextCursor)
+
+		second, err := repo.GetAll(ctx, model.ListQuery{Sort: sort, PageRequest: model.PageRequest{Limit: 3, Cursor: first.NextCursor}})
+		assert.NoError(err)
+		assert.Equal([]string{"apple"}, util.MapArray(second.Items, extractName))
+		assert.Nil(second.NextCursor)
+
+		filtered, err := repo.GetAll(ctx, model.ListQuery{NamePrefix: "ap", Sort: []model.SortField{{Field: "created"}}, PageRequest: model.PageRequest{Limit: 10}})
+		assert.NoError(err)
+		assert.Equal([]string{"apple", "apricot"}, util.MapArray(filtered.Items, extractName))
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		repo := repository.NewMemoryFoodRepository(repository.NewMemoryStore())
+		for _, name := range []string{"Tomatoes, red, ripe", "Tomato paste", "Potatoes"} {
+			if _, err := repo.Create(ctx, model.Food{Name: name}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		extractName := func(r model.FoodSearchResult) string { return r.Food.Name }
+		assert := assert.New(t)
+
+		results, err := repo.Search(ctx, model.FoodSearch{Text: "tomato", Limit: 10})
+		assert.NoError(err)
+		assert.Equal([]string{"Tomato paste", "Tomatoes, red, ripe"}, util.MapArray(results, extractName))
+
+		results, err = repo.Search(ctx, model.FoodSearch{Text: "tomatto paste", Limit: 10})
+		assert.NoError(err)
+		assert.Equal([]string{"Tomato paste"}, util.MapArray(results, extractName), "misspellings match fuzzily")
+
+		results, err = repo.Search(ctx, model.FoodSearch{Text: "tomato", Skip: 1, Limit: 10})
+		assert.NoError(err)
+		assert.Len(results, 1)
+
+		results, err = repo.Search(ctx, model.FoodSearch{Text: " ", Limit: 10})
+		assert.NoError(err)
+		assert.Empty(results)
+	})
+
+	t.Run("Concurrent writes", func(t *testing.T) {
+		repo := repository.NewMemoryFoodRepository(repository.NewMemoryStore())
+
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				created, err := repo.Create(ctx, model.Food{Name: fmt.Sprint("food ", i)})
+				if assert.NoError(t, err) {
+					_, err = repo.Update(ctx, model.Food{Id: created.Id, Name: fmt.Sprint("updated food ", i)})
+					assert.NoError(t, err)
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		page, err := repo.GetAll(ctx, model.ListQuery{NamePrefix: "updated", PageRequest: model.PageRequest{Limit: 100}})
+		assert.NoError(t, err)
+		assert.Len(t, page.Items, 50)
+	})
+}
+
+func TestMemoryRecipeRepository(t *testing.T) {
+	ctx := context.Background()
+
+	seed := func(t *testing.T) *repository.MemoryRecipeRepository {
+		store := repository.NewMemoryStore()
+		protein := model.Nutrient{Id: "n1", Name: "Protein", UnitName: "G"}
+		store.AddFood(model.Food{Id: "garlic", Name: "Garlic", Nutrients: []model.HasNutrient{{Amount: 6, Nutrient: protein}}})
+		store.AddFood(model.Food{Id: "oil", Name: "Olive oil", Density: new(float64)})
+		store.AddFood(model.Food{Id: "salt", Name: "Salt", Nutrients: []model.HasNutrient{{Amount: 0, Nutrient: protein}}})
+		return repository.NewMemoryRecipeRepository(store)
+	}
+
+	t.Run("Search", func(t *testing.T) {
+		repo := seed(t)
+		for _, recipe := range []model.Recipe{
+			{Title: "Roast garlic", Ingredients: []model.ContainsIngredient{{IngredientId: "garlic", Amount: model.ExactAmount(1), Unit: "head"}}},
+			{Title: "Aglio e olio", Ingredients: []model.ContainsIngredient{{IngredientId: "garlic", Amount: model.ExactAmount(1), Unit: "head"}, {IngredientId: "oil", Amount: model.ExactAmount(1), Unit: "cup"}}},
+			{Title: "Brine", Ingredients: []model.ContainsIngredient{{IngredientId: "salt", Amount: model.ExactAmount(1), Unit: "cup"}}},
+		} {
+			if _, err := repo.Create(ctx, recipe); err != nil {
+				t.Fatal(err)
+			}
+		}
+		extractTitle := func(r model.RecipeSearchResult) string { return r.Recipe.Title }
+		one := 1
+		assert := assert.New(t)
+
+		results, err := repo.Search(ctx, model.RecipeSearch{Text: "garlic", Limit: 10})
+		assert.NoError(err)
+		assert.ElementsMatch([]string{"Roast garlic", "Aglio e olio"}, util.MapArray(results, extractTitle), "ingredient names are searched too")
+		assert.Equal("Roast garlic", results[0].Recipe.Title, "title matches score higher")
+
+		results, err = repo.Search(ctx, model.RecipeSearch{IncludeFoodIds: []string{"garlic"}, ExcludeFoodIds: []string{"oil"}, Limit: 10})
+		assert.NoError(err)
+		assert.Equal([]string{"Roast garlic"}, util.MapArray(results, extractTitle))
+
+		results, err = repo.Search(ctx, model.RecipeSearch{MaxIngredients: &one, Limit: 10})
+		assert.NoError(err)
+		assert.ElementsMatch([]string{"Roast garlic", "Brine"}, util.MapArray(results, extractTitle))
+	})
+
+	t.Run("Get Nutrition", func(t *testing.T) {
+		repo := seed(t)
+		assert := assert.New(t)
+
+		created, err := repo.Create(ctx, model.Recipe{
+			Title: "Garlic and a pinch of salt",
+			Ingredients: []model.ContainsIngredient{
+				{IngredientId: "garlic", Amount: model.ExactAmount(50), Unit: "g"},
+				{IngredientId: "salt", Amount: model.ExactAmount(1), Unit: "clove"},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nutrition, found, err := repo.GetNutrition(ctx, created.Id)
+		assert.NoError(err)
+		assert.True(found)
+		assert.Len(nutrition.Nutrients, 1)
+		assert.InDelta(3.0, nutrition.Nutrients[0].Amount, 0.0001)
+		assert.Equal([]string{"salt"}, nutrition.UnconvertedIngredientIds)
+
+		_, found, err = repo.GetNutrition(ctx, "missing")
+		assert.NoError(err)
+		assert.False(found)
+	})
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Concurrent first signups", func(t *testing.T) {
+		repo := repository.NewMemoryUserRepository(repository.NewMemoryStore())
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		firsts := 0
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				_, created, err := repo.CreateFirst(ctx, model.User{Username: fmt.Sprint("user ", i), PasswordHash: "hash", Role: model.AdminRole})
+				if assert.NoError(t, err) && created {
+					mu.Lock()
+					firsts++
+					mu.Unlock()
+				}
+			}(i)
+		}
+		wg.Wait()
+
+		assert.Equal(t, 1, firsts)
+	})
+}
@@ -0,0 +1,62 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPurgeRepository(t *testing.T) {
+	ctx := context.Background()
+
+	neo4jContainer, err := startNeo4j(ctx, t)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	neo4jDriver, err := neo4jDriver(ctx, t, neo4jContainer)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// seed data: a food deleted long ago with a portion and a nutrient, a food deleted just now, and an old and a current session
+	longAgo := time.Now().Add(-48 * time.Hour)
+	query := "CREATE (old:Food:Resource {id: '1', name: 'old', created: $longAgo, deleted: $longAgo})-[:HAS_PORTION]->(:Portion {amount: 1.0, unit: 'cup', gramWeight: 100.0})\n" +
+		"CREATE (old)-[:HAS_NUTRIENT {amount: 1.0, deleted: $longAgo}]->(:Nutrient:Resource {id: '2', name: 'Protein', unit_name: 'G'})\n" +
+		"CREATE (:Food:Resource {id: '3', name: 'recent', created: $now, deleted: $now})\n" +
+		"CREATE (u:User:Resource {id: '4', username: 'cook', passwordHash: 'hash', created: $longAgo})-[:HAS_SESSION]->(:Session {tokenHash: 'old', expires: $longAgo, created: $longAgo})\n" +
+		"CREATE (u)-[:HAS_SESSION]->(:Session {tokenHash: 'current', expires: $now, created: $now})"
+	params := map[string]any{"longAgo": neo4j.LocalDateTime(longAgo), "now": neo4j.LocalDateTime(time.Now())}
+	_, err = neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	repo := repository.NewPurgeRepository(*neo4jDriver, "")
+	dayAgo := time.Now().Add(-24 * time.Hour)
+
+	assert := assert.New(t)
+
+	dryRun, err := repo.Purge(ctx, dayAgo, true)
+	assert.NoError(err)
+	assert.Equal(model.PurgeResult{Nodes: 3, Relationships: 1}, dryRun)
+
+	purged, err := repo.Purge(ctx, dayAgo, false)
+	assert.NoError(err)
+	assert.Equal(dryRun, purged)
+
+	again, err := repo.Purge(ctx, dayAgo, false)
+	assert.NoError(err)
+	assert.Equal(model.PurgeResult{}, again, "the recent food, the nutrient, the user and the current session are left alone")
+}
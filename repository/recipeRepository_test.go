@@ -28,7 +28,7 @@ func TestRecipeRepository(t *testing.T) {
 		t.FailNow()
 	}
 
-	repo := repository.NewRecipeRepository(*neo4jDriver)
+	repo := repository.NewRecipeRepository(*neo4jDriver, "")
 
 	t.Run("Get One", func(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
@@ -54,6 +54,10 @@ func TestRecipeRepository(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testCreateRecipeNoDescription(ctx, neo4jDriver, repo, t)
 	})
+	t.Run("Create (fractional and ranged amounts)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testCreateRecipeFractionalAndRangedAmounts(ctx, neo4jDriver, repo, t)
+	})
 	t.Run("Create (one ingredient not found)", func(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testCreateRecipeOneIngredientNotFound(ctx, neo4jDriver, repo, t)
@@ -95,6 +99,18 @@ func TestRecipeRepository(t *testing.T) {
 		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
 		testDeleteRecipe(ctx, neo4jDriver, repo, t)
 	})
+	t.Run("Search", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testSearchRecipe(ctx, neo4jDriver, repo, t)
+	})
+	t.Run("Get Nutrition", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetRecipeNutrition(ctx, neo4jDriver, repo, t)
+	})
+	t.Run("Get Nutrition (does not exist)", func(t *testing.T) {
+		t.Cleanup(func() { clearNeo4j(ctx, neo4jDriver) })
+		testGetRecipeNutritionDoesNotExist(ctx, neo4jDriver, repo, t)
+	})
 }
 
 var seedIngredientsAndRecipes string = `UNWIND $ingredients AS ingredient
@@ -203,23 +219,24 @@ func testGetAllRecipes(ctx context.Context, neo4jDriver *neo4j.DriverWithContext
 	}
 
 	// test
-	recipes, err := repo.GetAll(ctx)
+	recipes, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
 
 	assert := assert.New(t)
 	assert.NoError(err)
 	// comparing time stamps is tricky
-	fmt.Println(recipes)
+	fmt.Println(recipes.Items)
 }
 
 func testGetAllEmptyRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
 	// no seed data
 
 	// test
-	recipes, err := repo.GetAll(ctx)
+	recipes, err := repo.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
 
 	assert := assert.New(t)
 	assert.NoError(err)
-	assert.Empty([]model.Food{}, recipes)
+	assert.Empty(recipes.Items)
+	assert.Nil(recipes.NextCursor)
 }
 
 func testCreateRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
@@ -248,11 +265,12 @@ func testCreateRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext,
 	title := "test recipe"
 	description := "test description"
 	ingredients := []model.ContainsIngredient{
-		{Unit: "cup", Amount: 1, IngredientId: "asdf"},
-		{Unit: "cup", Amount: 1, IngredientId: "zxcv"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "asdf"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "zxcv"},
 	}
 	steps := []string{"cook beans", "cook rice", "combine cooked beans and rice"}
-	recipe := model.Recipe{Title: title, Description: &description, Ingredients: ingredients, Steps: steps}
+	servings := int64(4)
+	recipe := model.Recipe{Title: title, Description: &description, Ingredients: ingredients, Steps: steps, Servings: &servings}
 	createdRecipe, err := repo.Create(ctx, recipe)
 
 	// TODO make a direct Cypher query to verify anything about the state of the graph?
@@ -262,7 +280,9 @@ func testCreateRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext,
 	assert.NotEmpty(createdRecipe.Id)
 	assert.Equal(title, createdRecipe.Title)
 	assert.Equal(description, *createdRecipe.Description)
+	assert.Equal(servings, *createdRecipe.Servings)
 	assert.ElementsMatch(util.MapArray(ingredients, model.ExtractIngredientId), util.MapArray(createdRecipe.Ingredients, model.ExtractIngredientId))
+	assert.ElementsMatch([]string{"rice", "beans"}, util.MapArray(createdRecipe.Ingredients, func(ci model.ContainsIngredient) string { return ci.IngredientName }))
 	assert.WithinDuration(time.Now(), *createdRecipe.Created, time.Duration(1_000_000_000))
 	assert.Nil(createdRecipe.LastModified)
 	assert.Nil(createdRecipe.Deleted)
@@ -293,8 +313,8 @@ func testCreateRecipeNoDescription(ctx context.Context, neo4jDriver *neo4j.Drive
 	// test
 	title := "test recipe"
 	ingredients := []model.ContainsIngredient{
-		{Unit: "cup", Amount: 1, IngredientId: "asdf"},
-		{Unit: "cup", Amount: 1, IngredientId: "zxcv"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "asdf"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "zxcv"},
 	}
 	steps := []string{"cook beans", "cook rice", "combine cooked beans and rice"}
 	recipe := model.Recipe{Title: title, Ingredients: ingredients, Steps: steps}
@@ -307,12 +327,54 @@ func testCreateRecipeNoDescription(ctx context.Context, neo4jDriver *neo4j.Drive
 	assert.NotEmpty(createdRecipe.Id)
 	assert.Equal(title, createdRecipe.Title)
 	assert.Nil(createdRecipe.Description)
+	assert.Nil(createdRecipe.Servings)
 	assert.ElementsMatch(util.MapArray(ingredients, model.ExtractIngredientId), util.MapArray(createdRecipe.Ingredients, model.ExtractIngredientId))
 	assert.WithinDuration(time.Now(), *createdRecipe.Created, time.Duration(1_000_000_000))
 	assert.Nil(createdRecipe.LastModified)
 	assert.Nil(createdRecipe.Deleted)
 }
 
+func testCreateRecipeFractionalAndRangedAmounts(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
+	// seed data
+	query := "UNWIND $ingredients AS i CREATE (:Food {id: i.id, name: i.name, created: $created})"
+	ingredientParams := []map[string]string{
+		{"id": "asdf", "name": "garlic"},
+		{"id": "zxcv", "name": "salt"},
+	}
+	createdTime := time.Now()
+	params := map[string]any{
+		"ingredients": ingredientParams,
+		"created":     neo4j.LocalDateTime(createdTime),
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	ingredients := []model.ContainsIngredient{
+		{Unit: "clove", Amount: model.AmountRange(2, 3), IngredientId: "asdf"},
+		{Unit: "tsp", Amount: model.ExactAmount(1.5), IngredientId: "zxcv"},
+	}
+	recipe := model.Recipe{Title: "test recipe", Ingredients: ingredients, Steps: []string{"season"}}
+	createdRecipe, err := repo.Create(ctx, recipe)
+
+	amountsById := map[string]model.Amount{}
+	for _, ci := range createdRecipe.Ingredients {
+		amountsById[ci.IngredientId] = ci.Amount
+	}
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(model.AmountRange(2, 3), amountsById["asdf"])
+	assert.Equal(model.ExactAmount(1.5), amountsById["zxcv"])
+}
+
 func testCreateRecipeOneIngredientNotFound(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
 	// seed data
 	query := "UNWIND $ingredients AS i CREATE (:Food {id: i.id, name: i.name, created: $created})"
@@ -338,8 +400,8 @@ func testCreateRecipeOneIngredientNotFound(ctx context.Context, neo4jDriver *neo
 	title := "test recipe"
 	description := "test description"
 	ingredients := []model.ContainsIngredient{
-		{Unit: "cup", Amount: 1, IngredientId: "asdf"},
-		{Unit: "cup", Amount: 1, IngredientId: "zxcv"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "asdf"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "zxcv"},
 	}
 	steps := []string{"cook beans", "cook rice", "combine cooked beans and rice"}
 	recipe := model.Recipe{Title: title, Description: &description, Ingredients: ingredients, Steps: steps}
@@ -358,8 +420,8 @@ func testCreateRecipeNoIngredientsFound(ctx context.Context, neo4jDriver *neo4j.
 	title := "test recipe"
 	description := "test description"
 	ingredients := []model.ContainsIngredient{
-		{Unit: "cup", Amount: 1, IngredientId: "asdf"},
-		{Unit: "cup", Amount: 1, IngredientId: "zxcv"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "asdf"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "zxcv"},
 	}
 	steps := []string{"cook beans", "cook rice", "combine cooked beans and rice"}
 	recipe := model.Recipe{Title: title, Description: &description, Ingredients: ingredients, Steps: steps}
@@ -404,7 +466,7 @@ func testUpdateRecipeNoIngredientsChanged(ctx context.Context, neo4jDriver *neo4
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -455,8 +517,8 @@ func testUpdateRecipeIngredientAdded(ctx context.Context, neo4jDriver *neo4j.Dri
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"},
-		{Unit: "cup", Amount: 1, IngredientId: "456"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "456"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -507,7 +569,7 @@ func testUpdateRecipeIngredientRemoved(ctx context.Context, neo4jDriver *neo4j.D
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -559,7 +621,7 @@ func testUpdateRecipeIngredientsKeptAddedRemoved(ctx context.Context, neo4jDrive
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"}, {Unit: "oz", Amount: 12, IngredientId: "789"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"}, {Unit: "oz", Amount: model.ExactAmount(12), IngredientId: "789"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -620,8 +682,8 @@ func testUpdateRecipeReaddIngredient(ctx context.Context, neo4jDriver *neo4j.Dri
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"},
-		{Unit: "cup", Amount: 1, IngredientId: "456"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "456"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -671,8 +733,8 @@ func testUpdateRecipeOneIngredientNotFound(ctx context.Context, neo4jDriver *neo
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "g", Amount: 15, IngredientId: "123"},
-		{Unit: "cup", Amount: 1, IngredientId: "456"},
+		{Unit: "g", Amount: model.ExactAmount(15), IngredientId: "123"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "456"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -715,8 +777,8 @@ func testUpdateRecipeNoIngredientsFound(ctx context.Context, neo4jDriver *neo4j.
 	// test
 	desc := "tastes okay"
 	recipe := model.Recipe{Id: id, Title: "test recipe updated", Description: &desc, Steps: []string{"do prep work", "cook it"}, Ingredients: []model.ContainsIngredient{
-		{Unit: "cup", Amount: 1, IngredientId: "456"},
-		{Unit: "oz", Amount: 1, IngredientId: "789"},
+		{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: "456"},
+		{Unit: "oz", Amount: model.ExactAmount(1), IngredientId: "789"},
 	}}
 	updatedRecipe, err := repo.Update(ctx, recipe)
 
@@ -757,3 +819,175 @@ func testDeleteRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext,
 	assert.NoError(err)
 	assert.Equal(id, deletedId)
 }
+
+func testGetRecipeNutrition(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
+	// seed data
+	id := "1"
+
+	seedIngredients := []map[string]any{
+		{"id": "123", "name": "test ingredient 1"},
+		{"id": "456", "name": "test ingredient 2"},
+		{"id": "789", "name": "test ingredient 3"},
+	}
+
+	seedRecipes := []map[string]any{
+		{"id": id, "title": "test recipe", "description": "tastes alright", "steps": []string{"cook it"},
+			"ingredients": []map[string]any{
+				{"unit": "g", "amount": 200, "ingredient_id": "123"},
+				{"unit": "kg", "amount": 1, "ingredient_id": "456"},
+				{"unit": "pinch", "amount": 1, "ingredient_id": "789"},
+			}},
+	}
+
+	createdTime := time.Now()
+	params := map[string]any{
+		"ingredients": seedIngredients,
+		"recipes":     seedRecipes,
+		"created":     neo4j.LocalDateTime(createdTime),
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, seedIngredientsAndRecipes, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	_, err = neo4j.ExecuteWrite(ctx, (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite}),
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			query := "CREATE (n:Nutrient {id: 'protein', name: 'Protein', unit_name: 'G'})\n" +
+				"WITH n MATCH (f1:Food {id: '123'}), (f2:Food {id: '456'}), (f3:Food {id: '789'})\n" +
+				"CREATE (f1)-[:HAS_NUTRIENT {amount: 10.0}]->(n), (f2)-[:HAS_NUTRIENT {amount: 1.5}]->(n), (f3)-[:HAS_NUTRIENT {amount: 50.0}]->(n)"
+			return tx.Run(ctx, query, nil)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	// test
+	nutrition, found, err := repo.GetNutrition(ctx, id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(id, nutrition.RecipeId)
+	// 200 g * 10 g/100 g + 1000 g * 1.5 g/100 g; the pinch can't be converted to grams
+	assert.Equal([]model.HasNutrient{
+		{Amount: 35.0, Nutrient: model.Nutrient{Id: "protein", Name: "Protein", UnitName: "G"}},
+	}, nutrition.Nutrients)
+	assert.Equal([]string{"789"}, nutrition.UnconvertedIngredientIds)
+}
+
+func testGetRecipeNutritionDoesNotExist(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
+	// no seed data
+
+	// test
+	nutrition, found, err := repo.GetNutrition(ctx, "test id")
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+	assert.Nil(nutrition)
+}
+
+func testSearchRecipe(ctx context.Context, neo4jDriver *neo4j.DriverWithContext, repo model.RecipeRepository, t *testing.T) {
+	// seed data
+	session := (*neo4jDriver).NewSession(ctx, neo4j.SessionConfig{AccessMode: neo4j.AccessModeWrite})
+	defer session.Close(ctx)
+
+	// schema changes can't share a transaction with data changes
+	for _, query := range []string{
+		fmt.Sprintf("CREATE FULLTEXT INDEX %s IF NOT EXISTS FOR (r:Recipe) ON EACH [r.title, r.description, r.stepsText, r.ingredientsText]", repository.RecipeSearchIndex),
+		"CALL db.awaitIndexes()",
+	} {
+		_, err := session.Run(ctx, query, nil)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	query := "UNWIND $ingredients AS i CREATE (:Food {id: i.id, name: i.name, created: $created})"
+	params := map[string]any{
+		"ingredients": []map[string]string{
+			{"id": "asdf", "name": "rice"},
+			{"id": "zxcv", "name": "black beans"},
+			{"id": "qwer", "name": "cilantro"},
+		},
+		"created": neo4j.LocalDateTime(time.Now()),
+	}
+
+	_, err := neo4j.ExecuteWrite(ctx, session,
+		func(tx neo4j.ManagedTransaction) (neo4j.ResultWithContext, error) {
+			return tx.Run(ctx, query, params)
+		})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	cup := func(id string) model.ContainsIngredient {
+		return model.ContainsIngredient{Unit: "cup", Amount: model.ExactAmount(1), IngredientId: id}
+	}
+	riceAndBeans, err := repo.Create(ctx, model.Recipe{Title: "rice and beans", Ingredients: []model.ContainsIngredient{cup("asdf"), cup("zxcv")}, Steps: []string{"simmer"}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	cilantroRice, err := repo.Create(ctx, model.Recipe{Title: "cilantro lime rice", Ingredients: []model.ContainsIngredient{cup("asdf"), cup("qwer")}, Steps: []string{"toss with lime juice"}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+	beans, err := repo.Create(ctx, model.Recipe{Title: "refried", Ingredients: []model.ContainsIngredient{cup("zxcv")}, Steps: []string{"mash"}})
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	resultIds := func(results []model.RecipeSearchResult) []string {
+		return util.MapArray(results, func(r model.RecipeSearchResult) string { return r.Recipe.Id })
+	}
+
+	// test
+	assert := assert.New(t)
+
+	// the fulltext index is updated asynchronously
+	var results []model.RecipeSearchResult
+	assert.Eventually(func() bool {
+		results, err = repo.Search(ctx, model.RecipeSearch{Text: "beans", Limit: 10})
+		return err == nil && len(results) == 2
+	}, 10*time.Second, 100*time.Millisecond)
+	assert.NoError(err)
+	assert.ElementsMatch([]string{riceAndBeans.Id, beans.Id}, resultIds(results), "ingredient names are searchable")
+
+	results, err = repo.Search(ctx, model.RecipeSearch{Text: "lime", Limit: 10})
+	assert.NoError(err)
+	assert.Equal([]string{cilantroRice.Id}, resultIds(results), "steps are searchable")
+
+	results, err = repo.Search(ctx, model.RecipeSearch{IncludeFoodIds: []string{"asdf"}, ExcludeFoodIds: []string{"qwer"}, Limit: 10})
+	assert.NoError(err)
+	assert.Equal([]string{riceAndBeans.Id}, resultIds(results))
+
+	maxIngredients := 1
+	results, err = repo.Search(ctx, model.RecipeSearch{MaxIngredients: &maxIngredients, Limit: 10})
+	assert.NoError(err)
+	assert.Equal([]string{beans.Id}, resultIds(results))
+	assert.Len(results[0].Recipe.Ingredients, 1)
+
+	results, err = repo.Search(ctx, model.RecipeSearch{Text: "rice", IncludeFoodIds: []string{"zxcv"}, Limit: 10})
+	assert.NoError(err)
+	assert.Equal([]string{riceAndBeans.Id}, resultIds(results), "text and filters combine")
+
+	_, err = repository.NewFoodRepository(*neo4jDriver, "").Update(ctx, model.Food{Id: "zxcv", Name: "pinto"})
+	assert.NoError(err)
+	assert.Eventually(func() bool {
+		results, err = repo.Search(ctx, model.RecipeSearch{Text: "pinto", Limit: 10})
+		return err == nil && len(results) == 2
+	}, 10*time.Second, 100*time.Millisecond, "renamed ingredients are searchable by their new name")
+	results, err = repo.Search(ctx, model.RecipeSearch{Text: "beans", Limit: 10})
+	assert.NoError(err)
+	assert.Empty(results)
+}
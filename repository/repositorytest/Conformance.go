@@ -0,0 +1,1572 @@
+// Package repositorytest checks that a storage backend behaves the way the rest of the app expects,
+// so the Neo4j and in-memory repositories, and any added later, are held to the same semantics.
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/stretchr/testify/assert"
+)
+
+type Repositories struct {
+	Foods      model.FoodRepository
+	Recipes    model.RecipeRepository
+	Users      model.UserRepository
+	Households model.HouseholdRepository
+	ListItems  model.ListItemRepository
+}
+
+// Factory returns repositories over empty storage, with all of them sharing it.
+// It's called once per subtest, so it should clean up with t.Cleanup.
+type Factory func(t *testing.T) Repositories
+
+// Run runs every conformance test against the backend
+func Run(t *testing.T, newRepositories Factory) {
+	t.Run("Foods", func(t *testing.T) { TestFoodRepository(t, newRepositories) })
+	t.Run("Recipes", func(t *testing.T) { TestRecipeRepository(t, newRepositories) })
+	t.Run("Users", func(t *testing.T) { TestUserRepository(t, newRepositories) })
+	t.Run("Households", func(t *testing.T) { TestHouseholdRepository(t, newRepositories) })
+	t.Run("List items", func(t *testing.T) { TestListItemRepository(t, newRepositories) })
+}
+
+func TestFoodRepository(t *testing.T, newRepositories Factory) {
+	tests := []struct {
+		name string
+		test func(context.Context, Repositories, *testing.T)
+	}{
+		{"Create", testCreateFood},
+		{"Create (with an id)", testCreateFoodWithId},
+		{"Get One (does not exist)", testGetOneDoesNotExistFood},
+		{"Update", testUpdateFood},
+		{"Update (does not exist)", testUpdateDoesNotExistFood},
+		{"Update (deleted)", testUpdateDeletedFood},
+		{"Delete", testDeleteFood},
+		{"Delete (does not exist)", testDeleteDoesNotExistFood},
+		{"Delete (used by a recipe)", testDeleteFoodUsedByRecipe},
+		{"Author and visibility", testFoodAuthorAndVisibility},
+		{"Get All and Search (visibility)", testFoodVisibility},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(context.Background(), newRepositories(t), t)
+		})
+	}
+}
+
+func testFoodAuthorAndVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	author := createUser(ctx, repos, t, "cook")
+
+	assert := assert.New(t)
+
+	created, err := repos.Foods.Create(ctx, model.Food{Name: "Grandma's spice mix", AuthorId: author.Id, Visibility: model.PrivateVisibility})
+	assert.NoError(err)
+	assert.Equal(author.Id, created.AuthorId)
+	assert.Equal(model.PrivateVisibility, created.Visibility)
+
+	food, found, err := repos.Foods.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(author.Id, food.AuthorId)
+	assert.False(food.InCatalog())
+
+	// updating a food leaves who can see it alone
+	food.Name = "Grandpa's spice mix"
+	food.Visibility = ""
+	updated, err := repos.Foods.Update(ctx, *food)
+	assert.NoError(err)
+	assert.Equal(author.Id, updated.AuthorId)
+	assert.Equal(model.PrivateVisibility, updated.Visibility)
+
+	// without a visibility a food is in the catalog
+	created, err = repos.Foods.Create(ctx, model.Food{Name: "Salt"})
+	assert.NoError(err)
+	assert.Empty(created.AuthorId)
+	assert.True(created.InCatalog())
+}
+
+func testFoodVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	other := createUser(ctx, repos, t, "other")
+	for _, food := range []model.Food{
+		{Name: "Salt"},
+		{Name: "Smoked salt", AuthorId: cook.Id, Visibility: model.PrivateVisibility},
+		{Name: "Celery salt", AuthorId: other.Id, Visibility: model.PrivateVisibility},
+	} {
+		if _, err := repos.Foods.Create(ctx, food); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		viewer   model.Viewer
+		expected []string
+	}{
+		{"Anonymous", model.Viewer{}, []string{"Salt"}},
+		{"Author", model.Viewer{UserId: cook.Id}, []string{"Salt", "Smoked salt"}},
+		{"Everything", model.Viewer{All: true}, []string{"Salt", "Smoked salt", "Celery salt"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repos.Foods.GetAll(ctx, model.ListQuery{Viewer: tt.viewer, PageRequest: model.PageRequest{Limit: 10}})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(page.Items, func(f model.Food) string { return f.Name }))
+
+			results, err := repos.Foods.Search(ctx, model.FoodSearch{Text: "salt", Viewer: tt.viewer, Limit: 10})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(results, func(r model.FoodSearchResult) string { return r.Food.Name }))
+		})
+	}
+}
+
+func TestRecipeRepository(t *testing.T, newRepositories Factory) {
+	tests := []struct {
+		name string
+		test func(context.Context, Repositories, *testing.T)
+	}{
+		{"Create", testCreateRecipe},
+		{"Create (with an id)", testCreateRecipeWithId},
+		{"Create (one ingredient not found)", testCreateRecipeIngredientNotFound},
+		{"Create (ingredient deleted)", testCreateRecipeIngredientDeleted},
+		{"Get One (does not exist)", testGetOneDoesNotExistRecipe},
+		{"Update (ingredients kept, added and removed)", testUpdateRecipeIngredients},
+		{"Update (re-add a removed ingredient)", testUpdateRecipeReaddIngredient},
+		{"Update (one ingredient not found)", testUpdateRecipeIngredientNotFound},
+		{"Update (does not exist)", testUpdateDoesNotExistRecipe},
+		{"Delete", testDeleteRecipe},
+		{"Delete (does not exist)", testDeleteDoesNotExistRecipe},
+		{"Delete (twice)", testDeleteRecipeTwice},
+		{"Get All (every ingredient deleted)", testGetAllRecipesIngredientsDeleted},
+		{"Nutrition (every ingredient deleted)", testRecipeNutritionIngredientsDeleted},
+		{"Author and visibility", testRecipeAuthorAndVisibility},
+		{"Author (does not exist)", testRecipeAuthorDoesNotExist},
+		{"Update (visibility)", testUpdateRecipeVisibility},
+		{"Get All (visibility)", testGetAllRecipesVisibility},
+		{"Search (visibility)", testSearchRecipesVisibility},
+		{"Household", testRecipeHousehold},
+		{"Get All and Search (household)", testHouseholdRecipesVisibility},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(context.Background(), newRepositories(t), t)
+		})
+	}
+}
+
+func createFoods(ctx context.Context, repos Repositories, t *testing.T, names ...string) []model.Food {
+	foods := []model.Food{}
+	for _, name := range names {
+		food, err := repos.Foods.Create(ctx, model.Food{Name: name})
+		if err != nil {
+			t.Fatal(err)
+		}
+		foods = append(foods, *food)
+	}
+	return foods
+}
+
+func createRecipe(ctx context.Context, repos Repositories, t *testing.T, title string, foods ...model.Food) *model.Recipe {
+	ingredients := util.MapArray(foods, func(f model.Food) model.ContainsIngredient {
+		return model.ContainsIngredient{IngredientId: f.Id, Amount: model.ExactAmount(1), Unit: "cup"}
+	})
+	recipe, err := repos.Recipes.Create(ctx, model.Recipe{Title: title, Steps: []string{"Mix"}, Ingredients: ingredients})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return recipe
+}
+
+func ingredientIds(recipe *model.Recipe) []string {
+	return util.MapArray(recipe.Ingredients, model.ExtractIngredientId)
+}
+
+func ingredient(recipe *model.Recipe, id string) model.ContainsIngredient {
+	for _, ci := range recipe.Ingredients {
+		if ci.IngredientId == id {
+			return ci
+		}
+	}
+	return model.ContainsIngredient{}
+}
+
+func testCreateFood(ctx context.Context, repos Repositories, t *testing.T) {
+	density := 0.92
+	created, err := repos.Foods.Create(ctx, model.Food{Name: "Olive oil", Density: &density})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.NotEmpty(created.Id)
+	assert.Equal("Olive oil", created.Name)
+	assert.Equal(0.92, *created.Density)
+	assert.NotNil(created.Created)
+	assert.Nil(created.LastModified)
+	assert.Nil(created.Deleted)
+
+	food, found, err := repos.Foods.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(created.Id, food.Id)
+	assert.Equal("Olive oil", food.Name)
+	assert.Equal(0.92, *food.Density)
+	assert.WithinDuration(*created.Created, *food.Created, 0)
+	assert.Nil(food.LastModified)
+}
+
+// testCreateFoodWithId is how an import recreates an exported food
+func testCreateFoodWithId(ctx context.Context, repos Repositories, t *testing.T) {
+	created, err := repos.Foods.Create(ctx, model.Food{Id: "Food:Resource:exported", Name: "Olive oil"})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal("Food:Resource:exported", created.Id)
+
+	food, found, err := repos.Foods.GetById(ctx, "Food:Resource:exported")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Olive oil", food.Name)
+}
+
+func testGetOneDoesNotExistFood(ctx context.Context, repos Repositories, t *testing.T) {
+	food, found, err := repos.Foods.GetById(ctx, "missing")
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+	assert.Nil(food)
+}
+
+func testUpdateFood(ctx context.Context, repos Repositories, t *testing.T) {
+	created := createFoods(ctx, repos, t, "Garlic")[0]
+
+	density := 1.1
+	updated, err := repos.Foods.Update(ctx, model.Food{Id: created.Id, Name: "Garlic, raw", Density: &density})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(created.Id, updated.Id)
+	assert.Equal("Garlic, raw", updated.Name)
+	assert.Equal(1.1, *updated.Density)
+	assert.WithinDuration(*created.Created, *updated.Created, 0)
+	assert.NotNil(updated.LastModified)
+
+	food, found, err := repos.Foods.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Garlic, raw", food.Name)
+	assert.NotNil(food.LastModified)
+}
+
+func testUpdateDoesNotExistFood(ctx context.Context, repos Repositories, t *testing.T) {
+	_, err := repos.Foods.Update(ctx, model.Food{Id: "missing", Name: "Garlic"})
+
+	assert.Error(t, err)
+}
+
+func testUpdateDeletedFood(ctx context.Context, repos Repositories, t *testing.T) {
+	created := createFoods(ctx, repos, t, "Garlic")[0]
+	if _, err := repos.Foods.Delete(ctx, created.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repos.Foods.Update(ctx, model.Food{Id: created.Id, Name: "Garlic, raw"})
+
+	assert := assert.New(t)
+	assert.Error(err)
+	_, found, err := repos.Foods.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.False(found, "updating a deleted food must not bring it back")
+}
+
+func testDeleteFood(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Ginger")
+
+	deletedId, err := repos.Foods.Delete(ctx, foods[0].Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(foods[0].Id, deletedId)
+
+	_, found, err := repos.Foods.GetById(ctx, foods[0].Id)
+	assert.NoError(err)
+	assert.False(found)
+
+	page, err := repos.Foods.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
+	assert.NoError(err)
+	assert.Equal([]string{foods[1].Id}, util.MapArray(page.Items, func(f model.Food) string { return f.Id }))
+}
+
+func testDeleteDoesNotExistFood(ctx context.Context, repos Repositories, t *testing.T) {
+	_, err := repos.Foods.Delete(ctx, "missing")
+
+	assert.Error(t, err)
+}
+
+func testDeleteFoodUsedByRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Salt")
+	recipe := createRecipe(ctx, repos, t, "Garlic salt", foods...)
+
+	_, err := repos.Foods.Delete(ctx, foods[1].Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+
+	read, found, err := repos.Recipes.GetById(ctx, recipe.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal([]string{foods[0].Id}, ingredientIds(read))
+
+	_, err = repos.Recipes.Create(ctx, model.Recipe{Title: "Brine", Ingredients: []model.ContainsIngredient{{IngredientId: foods[1].Id, Amount: model.ExactAmount(1), Unit: "cup"}}})
+	assert.Error(err, "deleted foods can't be used in new recipes")
+}
+
+func testCreateRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Olive oil")
+	description := "Slow cooked garlic"
+	servings := int64(4)
+
+	created, err := repos.Recipes.Create(ctx, model.Recipe{
+		Title:       "Garlic confit",
+		Description: &description,
+		Steps:       []string{"Peel the garlic", "Cover with oil", "Cook gently"},
+		Servings:    &servings,
+		Ingredients: []model.ContainsIngredient{
+			{IngredientId: foods[0].Id, Amount: model.AmountRange(10, 12), Unit: "clove"},
+			{IngredientId: foods[1].Id, Amount: model.ExactAmount(0.5), Unit: "cup"},
+		},
+	})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.NotEmpty(created.Id)
+	assert.NotNil(created.Created)
+	assert.Nil(created.LastModified)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Garlic confit", recipe.Title)
+	assert.Equal(description, *recipe.Description)
+	assert.Equal([]string{"Peel the garlic", "Cover with oil", "Cook gently"}, recipe.Steps)
+	assert.Equal(servings, *recipe.Servings)
+	assert.ElementsMatch([]string{foods[0].Id, foods[1].Id}, ingredientIds(recipe))
+
+	garlic := ingredient(recipe, foods[0].Id)
+	assert.Equal("Garlic", garlic.IngredientName)
+	assert.Equal(model.AmountRange(10, 12), garlic.Amount)
+	assert.Equal("clove", garlic.Unit)
+	assert.NotNil(garlic.Created)
+	oil := ingredient(recipe, foods[1].Id)
+	assert.Equal("Olive oil", oil.IngredientName)
+	assert.Equal(model.ExactAmount(0.5), oil.Amount)
+}
+
+// testCreateRecipeWithId is how an import recreates an exported recipe
+func testCreateRecipeWithId(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+	created, err := repos.Recipes.Create(ctx, model.Recipe{
+		Id:          "Recipe:Resource:exported",
+		Title:       "Roast garlic",
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "head"}},
+	})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal("Recipe:Resource:exported", created.Id)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, "Recipe:Resource:exported")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal([]string{foods[0].Id}, ingredientIds(recipe))
+}
+
+func testCreateRecipeIngredientNotFound(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+
+	_, err := repos.Recipes.Create(ctx, model.Recipe{
+		Title: "Garlic bread",
+		Ingredients: []model.ContainsIngredient{
+			{IngredientId: foods[0].Id, Amount: model.ExactAmount(2), Unit: "clove"},
+			{IngredientId: "missing", Amount: model.ExactAmount(1), Unit: "loaf"},
+		},
+	})
+
+	assert := assert.New(t)
+	assert.Error(err)
+
+	page, err := repos.Recipes.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
+	assert.NoError(err)
+	assert.Empty(page.Items, "a rejected recipe must not be saved")
+}
+
+func testCreateRecipeIngredientDeleted(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+	if _, err := repos.Foods.Delete(ctx, foods[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repos.Recipes.Create(ctx, model.Recipe{
+		Title:       "Roast garlic",
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "head"}},
+	})
+
+	assert.Error(t, err)
+}
+
+func testGetOneDoesNotExistRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	recipe, found, err := repos.Recipes.GetById(ctx, "missing")
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+	assert.Nil(recipe)
+
+	_, found, err = repos.Recipes.GetNutrition(ctx, "missing")
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func testUpdateRecipeIngredients(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Salt", "Olive oil")
+	created := createRecipe(ctx, repos, t, "Garlic salt", foods[0], foods[1])
+
+	updated, err := repos.Recipes.Update(ctx, model.Recipe{
+		Id:    created.Id,
+		Title: "Garlic oil",
+		Steps: []string{"Infuse"},
+		Ingredients: []model.ContainsIngredient{
+			{IngredientId: foods[0].Id, Amount: model.ExactAmount(8), Unit: "clove"},
+			{IngredientId: foods[2].Id, Amount: model.ExactAmount(1), Unit: "cup"},
+		},
+	})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(created.Id, updated.Id)
+	assert.Equal("Garlic oil", updated.Title)
+	assert.NotNil(updated.LastModified)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Garlic oil", recipe.Title)
+	assert.Equal([]string{"Infuse"}, recipe.Steps)
+	assert.WithinDuration(*created.Created, *recipe.Created, 0)
+	assert.ElementsMatch([]string{foods[0].Id, foods[2].Id}, ingredientIds(recipe))
+
+	kept := ingredient(recipe, foods[0].Id)
+	assert.Equal(model.ExactAmount(8), kept.Amount)
+	assert.Equal("clove", kept.Unit)
+	assert.WithinDuration(*ingredient(created, foods[0].Id).Created, *kept.Created, 0, "a kept ingredient is updated in place")
+	assert.NotNil(kept.LastModified)
+
+	added := ingredient(recipe, foods[2].Id)
+	assert.Equal("Olive oil", added.IngredientName)
+	assert.NotNil(added.Created)
+	assert.Nil(added.LastModified)
+}
+
+func testUpdateRecipeReaddIngredient(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Salt")
+	created := createRecipe(ctx, repos, t, "Garlic salt", foods...)
+
+	_, err := repos.Recipes.Update(ctx, model.Recipe{
+		Id:          created.Id,
+		Title:       created.Title,
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	_, err = repos.Recipes.Update(ctx, model.Recipe{
+		Id:    created.Id,
+		Title: created.Title,
+		Ingredients: []model.ContainsIngredient{
+			{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"},
+			{IngredientId: foods[1].Id, Amount: model.ExactAmount(2), Unit: "tsp"},
+		},
+	})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.ElementsMatch([]string{foods[0].Id, foods[1].Id}, ingredientIds(recipe), "a re-added ingredient appears once")
+
+	salt := ingredient(recipe, foods[1].Id)
+	assert.Equal(model.ExactAmount(2), salt.Amount)
+	assert.Equal("tsp", salt.Unit)
+	assert.True(salt.Created.After(*ingredient(created, foods[1].Id).Created), "a re-added ingredient is new")
+}
+
+func testUpdateRecipeIngredientNotFound(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+	created := createRecipe(ctx, repos, t, "Roast garlic", foods...)
+
+	_, err := repos.Recipes.Update(ctx, model.Recipe{
+		Id:    created.Id,
+		Title: "Garlic bread",
+		Ingredients: []model.ContainsIngredient{
+			{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"},
+			{IngredientId: "missing", Amount: model.ExactAmount(1), Unit: "loaf"},
+		},
+	})
+
+	assert := assert.New(t)
+	assert.Error(err)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Roast garlic", recipe.Title, "a rejected update must not be saved")
+	assert.Equal([]string{foods[0].Id}, ingredientIds(recipe))
+}
+
+func testUpdateDoesNotExistRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+
+	_, err := repos.Recipes.Update(ctx, model.Recipe{
+		Id:          "missing",
+		Title:       "Roast garlic",
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "head"}},
+	})
+
+	assert.Error(t, err)
+}
+
+func testDeleteRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+	created := createRecipe(ctx, repos, t, "Roast garlic", foods...)
+	kept := createRecipe(ctx, repos, t, "Garlic bread", foods...)
+
+	deletedId, err := repos.Recipes.Delete(ctx, created.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(created.Id, deletedId)
+
+	_, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.False(found)
+
+	_, found, err = repos.Recipes.GetNutrition(ctx, created.Id)
+	assert.NoError(err)
+	assert.False(found)
+
+	page, err := repos.Recipes.GetAll(ctx, model.ListQuery{PageRequest: model.PageRequest{Limit: 10}})
+	assert.NoError(err)
+	assert.Equal([]string{kept.Id}, util.MapArray(page.Items, func(r model.Recipe) string { return r.Id }))
+
+	_, err = repos.Recipes.Update(ctx, model.Recipe{Id: created.Id, Title: "Roast garlic", Ingredients: created.Ingredients})
+	assert.Error(err, "deleted recipes can't be updated")
+
+	_, found, err = repos.Foods.GetById(ctx, foods[0].Id)
+	assert.NoError(err)
+	assert.True(found, "deleting a recipe leaves its ingredients alone")
+}
+
+func testDeleteDoesNotExistRecipe(ctx context.Context, repos Repositories, t *testing.T) {
+	_, err := repos.Recipes.Delete(ctx, "missing")
+
+	assert.Error(t, err)
+}
+
+func testDeleteRecipeTwice(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic")
+	created := createRecipe(ctx, repos, t, "Roast garlic", foods...)
+	if _, err := repos.Recipes.Delete(ctx, created.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := repos.Recipes.Delete(ctx, created.Id)
+
+	assert.Error(t, err)
+}
+
+func testGetAllRecipesIngredientsDeleted(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Garlic", "Saffron")
+	first := createRecipe(ctx, repos, t, "A garlic", foods[0])
+	emptied := createRecipe(ctx, repos, t, "B saffron", foods[1])
+	last := createRecipe(ctx, repos, t, "C garlic", foods[0])
+	if _, err := repos.Foods.Delete(ctx, foods[1].Id); err != nil {
+		t.Fatal(err)
+	}
+	query := model.ListQuery{Sort: []model.SortField{{Field: "name"}}, PageRequest: model.PageRequest{Limit: 2}}
+	recipeIds := func(page model.Page[model.Recipe]) []string {
+		return util.MapArray(page.Items, func(r model.Recipe) string { return r.Id })
+	}
+
+	assert := assert.New(t)
+
+	page, err := repos.Recipes.GetAll(ctx, query)
+	assert.NoError(err)
+	assert.Equal([]string{first.Id, emptied.Id}, recipeIds(page))
+	assert.Empty(page.Items[1].Ingredients)
+	if !assert.NotNil(page.NextCursor, "a recipe without ingredients must not end the list early") {
+		return
+	}
+
+	query.Cursor = page.NextCursor
+	page, err = repos.Recipes.GetAll(ctx, query)
+	assert.NoError(err)
+	assert.Equal([]string{last.Id}, recipeIds(page))
+	assert.Nil(page.NextCursor)
+
+	read, found, err := repos.Recipes.GetById(ctx, emptied.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Empty(read.Ingredients)
+}
+
+func testRecipeNutritionIngredientsDeleted(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Saffron")
+	servings := int64(2)
+	created, err := repos.Recipes.Create(ctx, model.Recipe{
+		Title:       "Saffron water",
+		Servings:    &servings,
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "g"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repos.Foods.Delete(ctx, foods[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	nutrition, found, err := repos.Recipes.GetNutrition(ctx, created.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	if !assert.True(found, "a recipe without ingredients still has nutrition, all of it zero") {
+		return
+	}
+	assert.Equal(created.Id, nutrition.RecipeId)
+	assert.Equal(&servings, nutrition.Servings)
+	assert.Empty(nutrition.Nutrients)
+	assert.Empty(nutrition.UnconvertedIngredientIds)
+}
+
+func testRecipeAuthorAndVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Rice")
+	author := createUser(ctx, repos, t, "cook")
+	ingredients := []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}}
+
+	assert := assert.New(t)
+
+	created, err := repos.Recipes.Create(ctx, model.Recipe{Title: "Rice", Steps: []string{"Boil"}, Ingredients: ingredients, AuthorId: author.Id, Visibility: model.PrivateVisibility})
+	assert.NoError(err)
+	assert.Equal(author.Id, created.AuthorId)
+	assert.Equal(model.PrivateVisibility, created.Visibility)
+
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(author.Id, recipe.AuthorId)
+	assert.Equal(model.PrivateVisibility, recipe.Visibility)
+
+	// without a visibility a recipe is public
+	created, err = repos.Recipes.Create(ctx, model.Recipe{Title: "Plain rice", Steps: []string{"Boil"}, Ingredients: ingredients})
+	assert.NoError(err)
+	assert.Empty(created.AuthorId)
+	assert.Equal(model.PublicVisibility, created.Visibility)
+}
+
+func testRecipeAuthorDoesNotExist(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Rice")
+	ingredients := []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}}
+
+	created, err := repos.Recipes.Create(ctx, model.Recipe{Title: "Rice", Steps: []string{"Boil"}, Ingredients: ingredients, AuthorId: "User:Resource:nobody"})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	recipe, found, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Empty(recipe.AuthorId)
+}
+
+func testUpdateRecipeVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Rice")
+	author := createUser(ctx, repos, t, "cook")
+	ingredients := []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}}
+	created, err := repos.Recipes.Create(ctx, model.Recipe{Title: "Rice", Steps: []string{"Boil"}, Ingredients: ingredients, AuthorId: author.Id, Visibility: model.PrivateVisibility})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created.Visibility = model.PublicVisibility
+	updated, err := repos.Recipes.Update(ctx, *created)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(model.PublicVisibility, updated.Visibility)
+	assert.Equal(author.Id, updated.AuthorId)
+
+	recipe, _, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.Equal(model.PublicVisibility, recipe.Visibility)
+	assert.Equal(author.Id, recipe.AuthorId)
+}
+
+// createVisibilityRecipes makes a public and a private recipe by one user and a private recipe by another
+func createVisibilityRecipes(ctx context.Context, repos Repositories, t *testing.T) (*model.User, *model.User, map[string]string) {
+	foods := createFoods(ctx, repos, t, "Rice")
+	cook := createUser(ctx, repos, t, "cook")
+	other := createUser(ctx, repos, t, "other")
+	ingredients := []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}}
+
+	ids := map[string]string{}
+	for _, recipe := range []model.Recipe{
+		{Title: "Public rice", AuthorId: cook.Id, Visibility: model.PublicVisibility},
+		{Title: "Private rice", AuthorId: cook.Id, Visibility: model.PrivateVisibility},
+		{Title: "Other rice", AuthorId: other.Id, Visibility: model.PrivateVisibility},
+	} {
+		recipe.Steps = []string{"Boil"}
+		recipe.Ingredients = ingredients
+		created, err := repos.Recipes.Create(ctx, recipe)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[created.Id] = created.Title
+	}
+	return cook, other, ids
+}
+
+func testGetAllRecipesVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	cook, other, ids := createVisibilityRecipes(ctx, repos, t)
+
+	tests := []struct {
+		name     string
+		viewer   model.Viewer
+		expected []string
+	}{
+		{"Anonymous", model.Viewer{}, []string{"Public rice"}},
+		{"Author", model.Viewer{UserId: cook.Id}, []string{"Public rice", "Private rice"}},
+		{"Someone else", model.Viewer{UserId: other.Id}, []string{"Public rice", "Other rice"}},
+		{"Everything", model.Viewer{All: true}, []string{"Public rice", "Private rice", "Other rice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repos.Recipes.GetAll(ctx, model.ListQuery{Viewer: tt.viewer, PageRequest: model.PageRequest{Limit: 10}})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(page.Items, func(r model.Recipe) string { return ids[r.Id] }))
+		})
+	}
+}
+
+func testSearchRecipesVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	cook, _, ids := createVisibilityRecipes(ctx, repos, t)
+
+	tests := []struct {
+		name     string
+		viewer   model.Viewer
+		expected []string
+	}{
+		{"Anonymous", model.Viewer{}, []string{"Public rice"}},
+		{"Author", model.Viewer{UserId: cook.Id}, []string{"Public rice", "Private rice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := repos.Recipes.Search(ctx, model.RecipeSearch{Viewer: tt.viewer, Limit: 10})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(results, func(r model.RecipeSearchResult) string { return ids[r.Recipe.Id] }))
+		})
+	}
+}
+
+func TestUserRepository(t *testing.T, newRepositories Factory) {
+	tests := []struct {
+		name string
+		test func(context.Context, Repositories, *testing.T)
+	}{
+		{"Create", testCreateUser},
+		{"Create (username taken)", testCreateUserUsernameTaken},
+		{"Create first", testCreateFirstUser},
+		{"Get One (does not exist)", testGetOneDoesNotExistUser},
+		{"Sessions", testSessions},
+		{"Sessions (expired)", testExpiredSession},
+		{"API keys", testApiKeys},
+		{"API keys (someone else's)", testSomeoneElsesApiKey},
+		{"Set role", testSetRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(context.Background(), newRepositories(t), t)
+		})
+	}
+}
+
+func createUser(ctx context.Context, repos Repositories, t *testing.T, username string) *model.User {
+	user, err := repos.Users.Create(ctx, model.User{Username: username, PasswordHash: "hash of " + username})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func testCreateUser(ctx context.Context, repos Repositories, t *testing.T) {
+	assert := assert.New(t)
+
+	hasUsers, err := repos.Users.HasUsers(ctx)
+	assert.NoError(err)
+	assert.False(hasUsers)
+
+	created, err := repos.Users.Create(ctx, model.User{Username: "cook", PasswordHash: "$2a$10$hash"})
+	assert.NoError(err)
+	assert.NotEmpty(created.Id)
+	assert.Equal("cook", created.Username)
+	assert.Equal(model.CookRole, created.Role)
+	assert.NotNil(created.Created)
+
+	user, found, err := repos.Users.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("cook", user.Username)
+	assert.Equal("$2a$10$hash", user.PasswordHash)
+	assert.Equal(model.CookRole, user.Role)
+
+	user, found, err = repos.Users.GetByUsername(ctx, "cook")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(created.Id, user.Id)
+
+	hasUsers, err = repos.Users.HasUsers(ctx)
+	assert.NoError(err)
+	assert.True(hasUsers)
+
+	admin, err := repos.Users.Create(ctx, model.User{Username: "admin", PasswordHash: "$2a$10$hash", Role: model.AdminRole})
+	assert.NoError(err)
+	user, _, err = repos.Users.GetById(ctx, admin.Id)
+	assert.NoError(err)
+	assert.Equal(model.AdminRole, user.Role)
+}
+
+func testCreateUserUsernameTaken(ctx context.Context, repos Repositories, t *testing.T) {
+	createUser(ctx, repos, t, "cook")
+
+	_, err := repos.Users.Create(ctx, model.User{Username: "cook", PasswordHash: "other"})
+
+	assert.True(t, errors.Is(err, model.ErrUsernameTaken), "expected ErrUsernameTaken, got %v", err)
+}
+
+func testCreateFirstUser(ctx context.Context, repos Repositories, t *testing.T) {
+	assert := assert.New(t)
+
+	first, created, err := repos.Users.CreateFirst(ctx, model.User{Username: "admin", PasswordHash: "hash", Role: model.AdminRole})
+	assert.NoError(err)
+	assert.True(created)
+	assert.Equal("admin", first.Username)
+	assert.Equal(model.AdminRole, first.Role)
+
+	user, found, err := repos.Users.GetByUsername(ctx, "admin")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(first.Id, user.Id)
+
+	_, created, err = repos.Users.CreateFirst(ctx, model.User{Username: "cook", PasswordHash: "hash", Role: model.AdminRole})
+	assert.NoError(err)
+	assert.False(created, "there is already a user")
+
+	_, found, err = repos.Users.GetByUsername(ctx, "cook")
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func testGetOneDoesNotExistUser(ctx context.Context, repos Repositories, t *testing.T) {
+	assert := assert.New(t)
+
+	_, found, err := repos.Users.GetById(ctx, "missing")
+	assert.NoError(err)
+	assert.False(found)
+
+	_, found, err = repos.Users.GetByUsername(ctx, "missing")
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func testSessions(ctx context.Context, repos Repositories, t *testing.T) {
+	user := createUser(ctx, repos, t, "cook")
+	assert := assert.New(t)
+
+	err := repos.Users.CreateSession(ctx, user.Id, "session hash", time.Now().Add(time.Hour))
+	assert.NoError(err)
+
+	sessionUser, found, err := repos.Users.GetSessionUser(ctx, "session hash")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(user.Id, sessionUser.Id)
+
+	_, found, err = repos.Users.GetSessionUser(ctx, "other hash")
+	assert.NoError(err)
+	assert.False(found)
+
+	assert.NoError(repos.Users.DeleteSession(ctx, "session hash"))
+	_, found, err = repos.Users.GetSessionUser(ctx, "session hash")
+	assert.NoError(err)
+	assert.False(found, "a deleted session signs nobody in")
+
+	assert.NoError(repos.Users.DeleteSession(ctx, "session hash"), "deleting a session twice is fine")
+	assert.Error(repos.Users.CreateSession(ctx, "missing", "another hash", time.Now().Add(time.Hour)))
+}
+
+func testExpiredSession(ctx context.Context, repos Repositories, t *testing.T) {
+	user := createUser(ctx, repos, t, "cook")
+	if err := repos.Users.CreateSession(ctx, user.Id, "session hash", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	_, found, err := repos.Users.GetSessionUser(ctx, "session hash")
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func testApiKeys(ctx context.Context, repos Repositories, t *testing.T) {
+	user := createUser(ctx, repos, t, "cook")
+	assert := assert.New(t)
+
+	created, err := repos.Users.CreateApiKey(ctx, user.Id, "meal planner", "key hash")
+	assert.NoError(err)
+	assert.NotEmpty(created.Id)
+	assert.Equal("meal planner", created.Name)
+	assert.Nil(created.LastUsed)
+
+	keyUser, found, err := repos.Users.GetApiKeyUser(ctx, "key hash")
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(user.Id, keyUser.Id)
+
+	keys, err := repos.Users.GetApiKeys(ctx, user.Id)
+	assert.NoError(err)
+	if assert.Len(keys, 1) {
+		assert.Equal(created.Id, keys[0].Id)
+		assert.NotNil(keys[0].LastUsed, "using a key records when")
+	}
+
+	deleted, err := repos.Users.DeleteApiKey(ctx, user.Id, created.Id)
+	assert.NoError(err)
+	assert.True(deleted)
+
+	_, found, err = repos.Users.GetApiKeyUser(ctx, "key hash")
+	assert.NoError(err)
+	assert.False(found, "a deleted key signs nobody in")
+
+	keys, err = repos.Users.GetApiKeys(ctx, user.Id)
+	assert.NoError(err)
+	assert.Empty(keys)
+
+	deleted, err = repos.Users.DeleteApiKey(ctx, user.Id, created.Id)
+	assert.NoError(err)
+	assert.False(deleted)
+}
+
+func testSomeoneElsesApiKey(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	other := createUser(ctx, repos, t, "baker")
+	created, err := repos.Users.CreateApiKey(ctx, owner.Id, "meal planner", "key hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert := assert.New(t)
+
+	deleted, err := repos.Users.DeleteApiKey(ctx, other.Id, created.Id)
+	assert.NoError(err)
+	assert.False(deleted)
+
+	keys, err := repos.Users.GetApiKeys(ctx, other.Id)
+	assert.NoError(err)
+	assert.Empty(keys)
+
+	_, found, err := repos.Users.GetApiKeyUser(ctx, "key hash")
+	assert.NoError(err)
+	assert.True(found)
+}
+
+func testSetRole(ctx context.Context, repos Repositories, t *testing.T) {
+	created := createUser(ctx, repos, t, "cook")
+
+	assert := assert.New(t)
+
+	updated, found, err := repos.Users.SetRole(ctx, "cook", model.CuratorRole)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(model.CuratorRole, updated.Role)
+
+	user, _, err := repos.Users.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.Equal(model.CuratorRole, user.Role)
+
+	_, found, err = repos.Users.SetRole(ctx, "nobody", model.AdminRole)
+	assert.NoError(err)
+	assert.False(found)
+}
+
+func TestHouseholdRepository(t *testing.T, newRepositories Factory) {
+	tests := []struct {
+		name string
+		test func(context.Context, Repositories, *testing.T)
+	}{
+		{"Create", testCreateHousehold},
+		{"Get One (does not exist)", testGetOneDoesNotExistHousehold},
+		{"Get One (not a member)", testGetOneNotAMember},
+		{"Invite and accept", testAcceptInvitation},
+		{"Invite (already a member or invited)", testInviteTwice},
+		{"Invite (not a member)", testInviteNotAMember},
+		{"Decline", testDeclineInvitation},
+		{"Accept (someone else's invitation)", testSomeoneElsesInvitation},
+		{"Leave", testLeaveHousehold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(context.Background(), newRepositories(t), t)
+		})
+	}
+}
+
+func createHousehold(ctx context.Context, repos Repositories, t *testing.T, name string, owner *model.User) *model.Household {
+	household, err := repos.Households.Create(ctx, model.Household{Name: name}, owner.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return household
+}
+
+// join invites the user to the household and accepts the invitation
+func join(ctx context.Context, repos Repositories, t *testing.T, household *model.Household, user *model.User) {
+	if err := repos.Households.Invite(ctx, household.Id, user.Id, household.Members[0].UserId); err != nil {
+		t.Fatal(err)
+	}
+	invitations, err := repos.Households.GetInvitations(ctx, user.Id)
+	if err != nil || len(invitations) != 1 {
+		t.Fatalf("expected 1 invitation, got %v, %v", invitations, err)
+	}
+	if accepted, err := repos.Households.Accept(ctx, user.Id, invitations[0].Id); err != nil || !accepted {
+		t.Fatalf("accepting the invitation failed: %v", err)
+	}
+}
+
+func memberNames(household *model.Household) []string {
+	return util.MapArray(household.Members, func(m model.HouseholdMember) string { return m.Username })
+}
+
+func testCreateHousehold(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+
+	assert := assert.New(t)
+
+	created, err := repos.Households.Create(ctx, model.Household{Name: "Home"}, owner.Id)
+	assert.NoError(err)
+	assert.NotEmpty(created.Id)
+	assert.Equal("Home", created.Name)
+	assert.Equal([]string{"cook"}, memberNames(created))
+	assert.NotNil(created.Members[0].Joined)
+
+	household, found, err := repos.Households.GetById(ctx, created.Id, owner.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("Home", household.Name)
+	assert.Equal([]string{"cook"}, memberNames(household))
+
+	households, err := repos.Households.GetForUser(ctx, owner.Id)
+	assert.NoError(err)
+	assert.Equal([]string{created.Id}, util.MapArray(households, func(h model.Household) string { return h.Id }))
+
+	user, _, err := repos.Users.GetById(ctx, owner.Id)
+	assert.NoError(err)
+	assert.Equal([]string{created.Id}, user.HouseholdIds)
+}
+
+func testGetOneDoesNotExistHousehold(ctx context.Context, repos Repositories, t *testing.T) {
+	user := createUser(ctx, repos, t, "cook")
+
+	household, found, err := repos.Households.GetById(ctx, "Household:Resource:nothing", user.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+	assert.Nil(household)
+}
+
+func testGetOneNotAMember(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	other := createUser(ctx, repos, t, "other")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+
+	got, found, err := repos.Households.GetById(ctx, household.Id, other.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.False(found)
+	assert.Nil(got)
+}
+
+func testAcceptInvitation(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+
+	assert := assert.New(t)
+
+	assert.NoError(repos.Households.Invite(ctx, household.Id, guest.Id, owner.Id))
+	invitations, err := repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	if !assert.Len(invitations, 1) {
+		return
+	}
+	assert.Equal(household.Id, invitations[0].HouseholdId)
+	assert.Equal("Home", invitations[0].HouseholdName)
+	assert.Equal("cook", invitations[0].InvitedBy)
+
+	// an invitation doesn't make anyone a member yet
+	user, _, err := repos.Users.GetById(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Empty(user.HouseholdIds)
+
+	invitationId := invitations[0].Id
+	accepted, err := repos.Households.Accept(ctx, guest.Id, invitationId)
+	assert.NoError(err)
+	assert.True(accepted)
+
+	updated, _, err := repos.Households.GetById(ctx, household.Id, guest.Id)
+	assert.NoError(err)
+	assert.Equal([]string{"cook", "guest"}, memberNames(updated))
+	user, _, err = repos.Users.GetById(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Equal([]string{household.Id}, user.HouseholdIds)
+
+	invitations, err = repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Empty(invitations)
+
+	// an invitation can only be accepted once
+	accepted, err = repos.Households.Accept(ctx, guest.Id, invitationId)
+	assert.NoError(err)
+	assert.False(accepted)
+}
+
+func testInviteTwice(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+
+	assert := assert.New(t)
+
+	assert.NoError(repos.Households.Invite(ctx, household.Id, owner.Id, owner.Id))
+	invitations, err := repos.Households.GetInvitations(ctx, owner.Id)
+	assert.NoError(err)
+	assert.Empty(invitations)
+
+	assert.NoError(repos.Households.Invite(ctx, household.Id, guest.Id, owner.Id))
+	assert.NoError(repos.Households.Invite(ctx, household.Id, guest.Id, owner.Id))
+	invitations, err = repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Len(invitations, 1)
+}
+
+func testInviteNotAMember(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	other := createUser(ctx, repos, t, "other")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+
+	assert := assert.New(t)
+
+	assert.Error(repos.Households.Invite(ctx, household.Id, guest.Id, other.Id))
+	invitations, err := repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Empty(invitations)
+}
+
+func testDeclineInvitation(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+	if err := repos.Households.Invite(ctx, household.Id, guest.Id, owner.Id); err != nil {
+		t.Fatal(err)
+	}
+	invitations, err := repos.Households.GetInvitations(ctx, guest.Id)
+	if err != nil || len(invitations) != 1 {
+		t.Fatalf("expected 1 invitation, got %v, %v", invitations, err)
+	}
+
+	assert := assert.New(t)
+
+	declined, err := repos.Households.Decline(ctx, guest.Id, invitations[0].Id)
+	assert.NoError(err)
+	assert.True(declined)
+
+	invitations, err = repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Empty(invitations)
+	updated, _, err := repos.Households.GetById(ctx, household.Id, owner.Id)
+	assert.NoError(err)
+	assert.Equal([]string{"cook"}, memberNames(updated))
+}
+
+func testSomeoneElsesInvitation(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	other := createUser(ctx, repos, t, "other")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+	if err := repos.Households.Invite(ctx, household.Id, guest.Id, owner.Id); err != nil {
+		t.Fatal(err)
+	}
+	invitations, err := repos.Households.GetInvitations(ctx, guest.Id)
+	if err != nil || len(invitations) != 1 {
+		t.Fatalf("expected 1 invitation, got %v, %v", invitations, err)
+	}
+
+	assert := assert.New(t)
+
+	accepted, err := repos.Households.Accept(ctx, other.Id, invitations[0].Id)
+	assert.NoError(err)
+	assert.False(accepted)
+	declined, err := repos.Households.Decline(ctx, other.Id, invitations[0].Id)
+	assert.NoError(err)
+	assert.False(declined)
+
+	updated, _, err := repos.Households.GetById(ctx, household.Id, owner.Id)
+	assert.NoError(err)
+	assert.Equal([]string{"cook"}, memberNames(updated))
+	invitations, err = repos.Households.GetInvitations(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Len(invitations, 1)
+}
+
+func testLeaveHousehold(ctx context.Context, repos Repositories, t *testing.T) {
+	owner := createUser(ctx, repos, t, "cook")
+	guest := createUser(ctx, repos, t, "guest")
+	invited := createUser(ctx, repos, t, "invited")
+	household := createHousehold(ctx, repos, t, "Home", owner)
+	join(ctx, repos, t, household, guest)
+	if err := repos.Households.Invite(ctx, household.Id, invited.Id, owner.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	assert := assert.New(t)
+
+	left, err := repos.Households.Leave(ctx, household.Id, owner.Id)
+	assert.NoError(err)
+	assert.True(left)
+	updated, found, err := repos.Households.GetById(ctx, household.Id, guest.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal([]string{"guest"}, memberNames(updated))
+	_, found, err = repos.Households.GetById(ctx, household.Id, owner.Id)
+	assert.NoError(err)
+	assert.False(found, "someone who left isn't a member any more")
+
+	left, err = repos.Households.Leave(ctx, household.Id, owner.Id)
+	assert.NoError(err)
+	assert.False(left)
+
+	// the last member to leave deletes the household, along with its invitations and lists
+	food := createFoods(ctx, repos, t, "Rice")[0]
+	if _, err := repos.ListItems.Create(ctx, model.ListItem{HouseholdId: household.Id, List: model.PantryList, FoodId: food.Id}, guest.Id); err != nil {
+		t.Fatal(err)
+	}
+	left, err = repos.Households.Leave(ctx, household.Id, guest.Id)
+	assert.NoError(err)
+	assert.True(left)
+	_, found, err = repos.Households.GetById(ctx, household.Id, guest.Id)
+	assert.NoError(err)
+	assert.False(found)
+
+	invitations, err := repos.Households.GetInvitations(ctx, invited.Id)
+	assert.NoError(err)
+	assert.Empty(invitations)
+	items, err := repos.ListItems.GetForHousehold(ctx, household.Id, guest.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Empty(items)
+	user, _, err := repos.Users.GetById(ctx, guest.Id)
+	assert.NoError(err)
+	assert.Empty(user.HouseholdIds)
+}
+
+func testRecipeHousehold(ctx context.Context, repos Repositories, t *testing.T) {
+	foods := createFoods(ctx, repos, t, "Rice")
+	author := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", author)
+	ingredients := []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}}
+
+	assert := assert.New(t)
+
+	created, err := repos.Recipes.Create(ctx, model.Recipe{Title: "Rice", Steps: []string{"Boil"}, Ingredients: ingredients, AuthorId: author.Id, HouseholdId: household.Id})
+	assert.NoError(err)
+	assert.Equal(household.Id, created.HouseholdId)
+
+	recipe, _, err := repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.Equal(household.Id, recipe.HouseholdId)
+
+	// updating keeps the household, and an empty one takes the recipe out of it
+	recipe.Title = "Fried rice"
+	updated, err := repos.Recipes.Update(ctx, *recipe)
+	assert.NoError(err)
+	assert.Equal(household.Id, updated.HouseholdId)
+
+	updated.HouseholdId = ""
+	updated, err = repos.Recipes.Update(ctx, *updated)
+	assert.NoError(err)
+	assert.Empty(updated.HouseholdId)
+	recipe, _, err = repos.Recipes.GetById(ctx, created.Id)
+	assert.NoError(err)
+	assert.Empty(recipe.HouseholdId)
+
+	recipe.HouseholdId = household.Id
+	updated, err = repos.Recipes.Update(ctx, *recipe)
+	assert.NoError(err)
+	assert.Equal(household.Id, updated.HouseholdId)
+
+	// a household that doesn't exist is left out, like an author that doesn't
+	created, err = repos.Recipes.Create(ctx, model.Recipe{Title: "Plain rice", Steps: []string{"Boil"}, Ingredients: ingredients, HouseholdId: "Household:Resource:nothing"})
+	assert.NoError(err)
+	assert.Empty(created.HouseholdId)
+}
+
+func testHouseholdRecipesVisibility(ctx context.Context, repos Repositories, t *testing.T) {
+	cook, other, ids := createVisibilityRecipes(ctx, repos, t)
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	join(ctx, repos, t, household, other)
+	member := createUser(ctx, repos, t, "member")
+	join(ctx, repos, t, household, member)
+
+	foods := createFoods(ctx, repos, t, "Beans")
+	created, err := repos.Recipes.Create(ctx, model.Recipe{
+		Title:       "Household beans",
+		Steps:       []string{"Soak"},
+		Ingredients: []model.ContainsIngredient{{IngredientId: foods[0].Id, Amount: model.ExactAmount(1), Unit: "cup"}},
+		AuthorId:    other.Id,
+		Visibility:  model.PrivateVisibility,
+		HouseholdId: household.Id,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids[created.Id] = created.Title
+
+	memberViewer := func(user *model.User) model.Viewer {
+		u, _, err := repos.Users.GetById(ctx, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return u.Viewer()
+	}
+
+	tests := []struct {
+		name     string
+		viewer   model.Viewer
+		expected []string
+	}{
+		{"Anonymous", model.Viewer{}, []string{"Public rice"}},
+		{"Member", memberViewer(member), []string{"Public rice", "Household beans"}},
+		{"Another household", model.Viewer{UserId: "User:Resource:nobody", HouseholdIds: []string{"Household:Resource:nothing"}}, []string{"Public rice"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := repos.Recipes.GetAll(ctx, model.ListQuery{Viewer: tt.viewer, PageRequest: model.PageRequest{Limit: 10}})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(page.Items, func(r model.Recipe) string { return ids[r.Id] }))
+
+			results, err := repos.Recipes.Search(ctx, model.RecipeSearch{Viewer: tt.viewer, Limit: 10})
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, util.MapArray(results, func(r model.RecipeSearchResult) string { return ids[r.Recipe.Id] }))
+		})
+	}
+}
+
+func TestListItemRepository(t *testing.T, newRepositories Factory) {
+	tests := []struct {
+		name string
+		test func(context.Context, Repositories, *testing.T)
+	}{
+		{"Create and get", testCreateListItems},
+		{"Create (household or food does not exist)", testCreateListItemNotFound},
+		{"Update (move to the pantry)", testUpdateListItem},
+		{"Delete", testDeleteListItem},
+		{"Food deleted", testListItemFoodDeleted},
+		{"Another household, or not a member", testListItemsOfAnotherHousehold},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(context.Background(), newRepositories(t), t)
+		})
+	}
+}
+
+func createListItem(ctx context.Context, repos Repositories, t *testing.T, household *model.Household, list string, food model.Food, quantity string) *model.ListItem {
+	item, err := repos.ListItems.Create(ctx, model.ListItem{HouseholdId: household.Id, List: list, FoodId: food.Id, Quantity: quantity}, household.Members[0].UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return item
+}
+
+func itemFoodNames(items []model.ListItem) []string {
+	return util.MapArray(items, func(i model.ListItem) string { return i.FoodName })
+}
+
+func testCreateListItems(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	foods := createFoods(ctx, repos, t, "Rice", "Beans", "Limes")
+
+	created := createListItem(ctx, repos, t, household, model.PantryList, foods[0], "2 kg")
+	createListItem(ctx, repos, t, household, model.PantryList, foods[1], "3 cans")
+	createListItem(ctx, repos, t, household, model.ShoppingList, foods[2], "")
+
+	assert := assert.New(t)
+	assert.NotEmpty(created.Id)
+	assert.Equal(household.Id, created.HouseholdId)
+	assert.Equal(model.PantryList, created.List)
+	assert.Equal(foods[0].Id, created.FoodId)
+	assert.Equal("Rice", created.FoodName)
+	assert.Equal("2 kg", created.Quantity)
+	assert.NotNil(created.Created)
+
+	item, found, err := repos.ListItems.GetById(ctx, household.Id, cook.Id, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal(*created, *item)
+
+	pantry, err := repos.ListItems.GetForHousehold(ctx, household.Id, cook.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Equal([]string{"Beans", "Rice"}, itemFoodNames(pantry))
+
+	shopping, err := repos.ListItems.GetForHousehold(ctx, household.Id, cook.Id, model.ShoppingList)
+	assert.NoError(err)
+	assert.Equal([]string{"Limes"}, itemFoodNames(shopping))
+}
+
+func testCreateListItemNotFound(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	food := createFoods(ctx, repos, t, "Rice")[0]
+
+	_, err := repos.ListItems.Create(ctx, model.ListItem{HouseholdId: household.Id, List: model.PantryList, FoodId: "missing"}, cook.Id)
+	assert.Error(t, err)
+
+	_, err = repos.ListItems.Create(ctx, model.ListItem{HouseholdId: "missing", List: model.PantryList, FoodId: food.Id}, cook.Id)
+	assert.Error(t, err)
+}
+
+func testUpdateListItem(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	food := createFoods(ctx, repos, t, "Limes")[0]
+	created := createListItem(ctx, repos, t, household, model.ShoppingList, food, "4")
+
+	created.List = model.PantryList
+	created.Quantity = "6"
+	updated, err := repos.ListItems.Update(ctx, *created, cook.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(model.PantryList, updated.List)
+	assert.Equal("6", updated.Quantity)
+	assert.NotNil(updated.LastModified)
+
+	shopping, err := repos.ListItems.GetForHousehold(ctx, household.Id, cook.Id, model.ShoppingList)
+	assert.NoError(err)
+	assert.Empty(shopping)
+	pantry, err := repos.ListItems.GetForHousehold(ctx, household.Id, cook.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Equal([]string{created.Id}, util.MapArray(pantry, func(i model.ListItem) string { return i.Id }))
+}
+
+func testDeleteListItem(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	food := createFoods(ctx, repos, t, "Rice")[0]
+	created := createListItem(ctx, repos, t, household, model.PantryList, food, "")
+
+	deletedId, err := repos.ListItems.Delete(ctx, household.Id, cook.Id, created.Id)
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(created.Id, deletedId)
+	_, found, err := repos.ListItems.GetById(ctx, household.Id, cook.Id, created.Id)
+	assert.NoError(err)
+	assert.False(found)
+
+	_, err = repos.ListItems.Delete(ctx, household.Id, cook.Id, created.Id)
+	assert.Error(err)
+}
+
+func testListItemFoodDeleted(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	household := createHousehold(ctx, repos, t, "Home", cook)
+	foods := createFoods(ctx, repos, t, "Rice", "Beans")
+	createListItem(ctx, repos, t, household, model.PantryList, foods[0], "")
+	createListItem(ctx, repos, t, household, model.PantryList, foods[1], "")
+	if _, err := repos.Foods.Delete(ctx, foods[1].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	pantry, err := repos.ListItems.GetForHousehold(ctx, household.Id, cook.Id, model.PantryList)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Rice"}, itemFoodNames(pantry))
+}
+
+func testListItemsOfAnotherHousehold(ctx context.Context, repos Repositories, t *testing.T) {
+	cook := createUser(ctx, repos, t, "cook")
+	neighbor := createUser(ctx, repos, t, "neighbor")
+	home := createHousehold(ctx, repos, t, "Home", cook)
+	away := createHousehold(ctx, repos, t, "Away", neighbor)
+	food := createFoods(ctx, repos, t, "Rice")[0]
+	created := createListItem(ctx, repos, t, home, model.PantryList, food, "1 kg")
+
+	assert := assert.New(t)
+
+	// through the neighbor's own household
+	_, found, err := repos.ListItems.GetById(ctx, away.Id, neighbor.Id, created.Id)
+	assert.NoError(err)
+	assert.False(found)
+	items, err := repos.ListItems.GetForHousehold(ctx, away.Id, neighbor.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Empty(items)
+	_, err = repos.ListItems.Update(ctx, model.ListItem{Id: created.Id, HouseholdId: away.Id, List: model.PantryList, Quantity: "none"}, neighbor.Id)
+	assert.Error(err)
+	_, err = repos.ListItems.Delete(ctx, away.Id, neighbor.Id, created.Id)
+	assert.Error(err)
+
+	// through the household itself, which the neighbor isn't a member of
+	_, found, err = repos.ListItems.GetById(ctx, home.Id, neighbor.Id, created.Id)
+	assert.NoError(err)
+	assert.False(found)
+	items, err = repos.ListItems.GetForHousehold(ctx, home.Id, neighbor.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Empty(items)
+	_, err = repos.ListItems.Create(ctx, model.ListItem{HouseholdId: home.Id, List: model.PantryList, FoodId: food.Id}, neighbor.Id)
+	assert.Error(err)
+	_, err = repos.ListItems.Update(ctx, model.ListItem{Id: created.Id, HouseholdId: home.Id, List: model.PantryList, Quantity: "none"}, neighbor.Id)
+	assert.Error(err)
+	_, err = repos.ListItems.Delete(ctx, home.Id, neighbor.Id, created.Id)
+	assert.Error(err)
+
+	item, found, err := repos.ListItems.GetById(ctx, home.Id, cook.Id, created.Id)
+	assert.NoError(err)
+	assert.True(found)
+	assert.Equal("1 kg", item.Quantity)
+	items, err = repos.ListItems.GetForHousehold(ctx, home.Id, cook.Id, model.PantryList)
+	assert.NoError(err)
+	assert.Len(items, 1)
+}
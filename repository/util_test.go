@@ -0,0 +1,30 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFulltextQuery(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	testCases := []testCase{
+		{name: "Empty", input: "  ", expected: ""},
+		{name: "Short words are prefixes", input: "egg", expected: "egg*"},
+		{name: "Long words are also fuzzy", input: "Tomato", expected: "(tomato* OR tomato~)"},
+		{name: "Every word has to match", input: "raw egg", expected: "raw* AND egg*"},
+		{name: "Special characters are escaped", input: "a:b", expected: `a\:b*`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, repository.FulltextQuery(tc.input))
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package static
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed htmx.min.js style.css
+var files embed.FS
+
+// content hashes, used to version asset urls so they can be cached forever
+var versions = hashFiles()
+
+func hashFiles() map[string]string {
+	hashes := map[string]string{}
+	entries, err := fs.ReadDir(files, ".")
+	if err != nil {
+		panic(err)
+	}
+	for _, entry := range entries {
+		content, err := files.ReadFile(entry.Name())
+		if err != nil {
+			panic(err)
+		}
+		hash := sha256.Sum256(content)
+		hashes[entry.Name()] = hex.EncodeToString(hash[:8])
+	}
+	return hashes
+}
+
+// Path is the url of an embedded file, versioned by its content
+func Path(name string) string {
+	return "/static/" + name + "?v=" + versions[name]
+}
+
+// Handler serves the embedded files. It expects the /static/ prefix to already be stripped.
+func Handler() http.Handler {
+	fileServer := http.FileServer(http.FS(files))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, found := versions[r.URL.Path]
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("ETag", `"`+version+`"`)
+		if r.URL.Query().Get("v") == version {
+			w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			w.Header().Set("Cache-Control", "no-cache")
+		}
+		fileServer.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,56 @@
+package static_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ThomasMatlak/food/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	handler := http.StripPrefix("/static/", static.Handler())
+
+	type testCase struct {
+		name                 string
+		url                  string
+		expectedStatus       int
+		expectedCacheControl string
+	}
+
+	testCases := []testCase{
+		{name: "Versioned url", url: static.Path("htmx.min.js"), expectedStatus: http.StatusOK, expectedCacheControl: "public, max-age=31536000, immutable"},
+		{name: "Unversioned url", url: "/static/style.css", expectedStatus: http.StatusOK, expectedCacheControl: "no-cache"},
+		{name: "Outdated version", url: "/static/style.css?v=0", expectedStatus: http.StatusOK, expectedCacheControl: "no-cache"},
+		{name: "Unknown file", url: "/static/missing.js", expectedStatus: http.StatusNotFound},
+		{name: "Directory listing", url: "/static/", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.url, nil))
+
+			assert.Equal(t, tc.expectedStatus, recorder.Code)
+			assert.Equal(t, tc.expectedCacheControl, recorder.Header().Get("Cache-Control"))
+		})
+	}
+}
+
+func TestHandlerNotModified(t *testing.T) {
+	handler := http.StripPrefix("/static/", static.Handler())
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, static.Path("style.css"), nil))
+	etag := recorder.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `"`))
+
+	request := httptest.NewRequest(http.MethodGet, static.Path("style.css"), nil)
+	request.Header.Set("If-None-Match", etag)
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusNotModified, recorder.Code)
+}
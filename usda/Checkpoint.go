@@ -0,0 +1,77 @@
+package usda
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// checkpoint records how many rows of each file have been written, so an interrupted import can pick up where it stopped
+type checkpoint struct {
+	path string
+	// identifies the archive, so a checkpoint isn't applied to a different release
+	Source string         `json:"source"`
+	Rows   map[string]int `json:"rows"`
+}
+
+func loadCheckpoint(path string, source string) (*checkpoint, error) {
+	cp := &checkpoint{path: path, Source: source, Rows: map[string]int{}}
+	if path == "" {
+		return cp, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cp, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("could not read checkpoint: %w", err)
+	}
+
+	if err := json.Unmarshal(content, cp); err != nil {
+		return nil, fmt.Errorf("could not parse checkpoint %s: %w", path, err)
+	}
+	if cp.Source != source {
+		return nil, fmt.Errorf("checkpoint %s is for %s, not %s; delete it to start over", path, cp.Source, source)
+	}
+	return cp, nil
+}
+
+// save writes to a temporary file first, so a crash mid-write can't leave a corrupt checkpoint behind
+func (cp *checkpoint) save() error {
+	if cp.path == "" {
+		return nil
+	}
+
+	content, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(cp.path), filepath.Base(cp.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("could not write checkpoint: %w", err)
+	}
+	return os.Rename(tmp.Name(), cp.path)
+}
+
+func (cp *checkpoint) remove() error {
+	if cp.path == "" {
+		return nil
+	}
+	err := os.Remove(cp.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
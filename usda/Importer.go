@@ -0,0 +1,442 @@
+package usda
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+
+	"github.com/ThomasMatlak/food/model"
+	"github.com/ThomasMatlak/food/repository"
+	"github.com/ThomasMatlak/food/util"
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
+	"github.com/rs/zerolog/log"
+)
+
+const DefaultBatchSize = 5000
+
+type Options struct {
+	// rows per write transaction
+	BatchSize int
+	// where to record progress; empty disables resuming
+	CheckpointFile string
+}
+
+// Importer loads a FoodData Central CSV download (https://fdc.nal.usda.gov/download-datasets.html) into the database
+type Importer struct {
+	// empty uses the server's default database
+	database string
+	driver   neo4j.DriverWithContext
+	options  Options
+	// writes one batch of a file's rows with its query; tests swap it out to see what would be written
+	write func(ctx context.Context, statement string, rows []any) error
+}
+
+func NewImporter(driver neo4j.DriverWithContext, database string, options Options) *Importer {
+	if options.BatchSize < 1 {
+		options.BatchSize = DefaultBatchSize
+	}
+	importer := &Importer{driver: driver, database: database, options: options}
+	importer.write = importer.writeBatch
+	return importer
+}
+
+type Stats struct {
+	File       string
+	Rows       int
+	Written    int
+	Duplicates int
+	// malformed rows, and rows that refer to a food, nutrient or unit that was skipped
+	Skipped int
+}
+
+// file is one CSV from the archive, with how to clean its rows and write them.
+// clean returns the row's key for deduplication (0 when the file isn't deduplicated) and the row's query parameters.
+type file struct {
+	name  string
+	clean func(row Row) (int64, map[string]any, error)
+	query string
+}
+
+// Import streams the CSVs out of the zip at zipPath, so nothing is unpacked to disk. Rows already recorded in the checkpoint
+// are still read, to know which foods and nutrients exist, but aren't written again.
+func (i *Importer) Import(ctx context.Context, zipPath string) ([]Stats, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.Close()
+
+	info, err := os.Stat(zipPath)
+	if err != nil {
+		return nil, err
+	}
+	cp, err := loadCheckpoint(i.options.CheckpointFile, fmt.Sprintf("%s (%d bytes)", path.Base(zipPath), info.Size()))
+	if err != nil {
+		return nil, err
+	}
+
+	units := map[int64]string{}
+	measureUnits, err := openCsv(archive, "measure_unit.csv")
+	if err != nil {
+		return nil, err
+	}
+	defer measureUnits.Close()
+	_, err = ReadRows(measureUnits, "measure_unit.csv", func(line int, row Row) error {
+		id, name, err := ParseMeasureUnit(row)
+		if err != nil {
+			log.Debug().Str("file", "measure_unit.csv").Int("line", line).Err(err).Msg("skipping row")
+			return nil
+		}
+		units[id] = name
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nutrients := util.Set[int64]{}
+	foods := util.Set[int64]{}
+	files := []file{
+		{
+			name: "nutrient.csv",
+			clean: func(row Row) (int64, map[string]any, error) {
+				nutrient, err := ParseNutrient(row)
+				if err != nil {
+					return 0, nil, err
+				}
+				nutrients[nutrient.Id] = struct{}{}
+				return nutrient.Id, map[string]any{
+					"id":       fmt.Sprint(nutrientIdPrefix, nutrient.Id),
+					"name":     nutrient.Name,
+					"unitName": nutrient.UnitName,
+				}, nil
+			},
+			query: fmt.Sprintf("UNWIND $rows AS row\n"+
+				"MERGE (n:`%s`:`%s` {id: row.id})\n"+
+				"ON CREATE SET n += {name: row.name, unit_name: row.unitName}",
+				repository.NutrientLabel, repository.ResourceLabel),
+		},
+		{
+			name: "food.csv",
+			clean: func(row Row) (int64, map[string]any, error) {
+				food, err := ParseFood(row)
+				if err != nil {
+					return 0, nil, err
+				}
+				foods[food.FdcId] = struct{}{}
+				created := time.Now()
+				if food.Published != nil {
+					created = *food.Published
+				}
+				return food.FdcId, map[string]any{
+					"id":      fmt.Sprint(foodIdPrefix, food.FdcId),
+					"name":    food.Name,
+					"created": neo4j.LocalDateTime(created),
+				}, nil
+			},
+			query: fmt.Sprintf("UNWIND $rows AS row\n"+
+				"MERGE (f:`%s`:`%s` {id: row.id})\n"+
+				"ON CREATE SET f += {name: row.name, created: row.created}",
+				repository.FoodLabel, repository.ResourceLabel),
+		},
+		{
+			name: "food_nutrient.csv",
+			// there are too many of these to keep every key in memory, and MERGE already keeps the first amount for a food and nutrient
+			clean: func(row Row) (int64, map[string]any, error) {
+				foodNutrient, err := ParseFoodNutrient(row)
+				if err != nil {
+					return 0, nil, err
+				}
+				if _, found := foods[foodNutrient.FdcId]; !found {
+					return 0, nil, fmt.Errorf("unknown fdc_id %d", foodNutrient.FdcId)
+				}
+				if _, found := nutrients[foodNutrient.NutrientId]; !found {
+					return 0, nil, fmt.Errorf("unknown nutrient_id %d", foodNutrient.NutrientId)
+				}
+				return 0, map[string]any{
+					"foodId":     fmt.Sprint(foodIdPrefix, foodNutrient.FdcId),
+					"nutrientId": fmt.Sprint(nutrientIdPrefix, foodNutrient.NutrientId),
+					"amount":     foodNutrient.Amount,
+				}, nil
+			},
+			// the :Resource id constraint's index gets used where the :Food and :Nutrient id indexes don't
+			query: fmt.Sprintf("UNWIND $rows AS row\n"+
+				"MATCH (n:`%[1]s`:`%[2]s` {id: row.nutrientId})\n"+
+				"MATCH (f:`%[3]s`:`%[2]s` {id: row.foodId})\n"+
+				"MERGE (f)-[rel:`%[4]s`]->(n)\n"+
+				"ON CREATE SET rel += {amount: row.amount}",
+				repository.NutrientLabel, repository.ResourceLabel, repository.FoodLabel, repository.HasNutrientLabel),
+		},
+		{
+			name: "food_portion.csv",
+			clean: func(row Row) (int64, map[string]any, error) {
+				portion, err := ParsePortion(row, units)
+				if err != nil {
+					return 0, nil, err
+				}
+				if _, found := foods[portion.FdcId]; !found {
+					return 0, nil, fmt.Errorf("unknown fdc_id %d", portion.FdcId)
+				}
+				return portion.Id, map[string]any{
+					"id":          fmt.Sprint(portionIdPrefix, portion.Id),
+					"foodId":      fmt.Sprint(foodIdPrefix, portion.FdcId),
+					"amount":      portion.Amount,
+					"unit":        portion.Unit,
+					"description": portion.Description,
+					"modifier":    portion.Modifier,
+					"gramWeight":  portion.GramWeight,
+				}, nil
+			},
+			query: fmt.Sprintf("UNWIND $rows AS row\n"+
+				"MATCH (f:`%s`:`%s` {id: row.foodId})\n"+
+				"MERGE (f)-[:`%s`]->(p:`%s` {id: row.id})\n"+
+				"ON CREATE SET p += {amount: row.amount, unit: row.unit, description: row.description, modifier: row.modifier, gramWeight: row.gramWeight}",
+				repository.FoodLabel, repository.ResourceLabel, repository.HasPortionLabel, repository.PortionLabel),
+		},
+	}
+
+	allStats := []Stats{}
+	for _, f := range files {
+		stats, err := i.importFile(ctx, archive, f, cp)
+		if err != nil {
+			return allStats, err
+		}
+		allStats = append(allStats, stats)
+	}
+
+	if err := i.setDensities(ctx); err != nil {
+		return allStats, err
+	}
+
+	return allStats, cp.remove()
+}
+
+func (i *Importer) importFile(ctx context.Context, archive *zip.ReadCloser, f file, cp *checkpoint) (Stats, error) {
+	stats := Stats{File: f.name}
+	seen := util.Set[int64]{}
+	alreadyWritten := cp.Rows[f.name]
+	batch := []any{}
+
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := i.write(ctx, f.query, batch); err != nil {
+				return fmt.Errorf("could not write %s: %w", f.name, err)
+			}
+			stats.Written += len(batch)
+			batch = []any{}
+		}
+
+		cp.Rows[f.name] = stats.Rows
+		return cp.save()
+	}
+
+	content, err := openCsv(archive, f.name)
+	if err != nil {
+		return stats, err
+	}
+	defer content.Close()
+
+	log.Info().Str("file", f.name).Int("resuming_after", alreadyWritten).Msg("importing")
+	malformed, err := ReadRows(content, f.name, func(line int, row Row) error {
+		stats.Rows++
+
+		key, params, err := f.clean(row)
+		if err != nil {
+			stats.Skipped++
+			log.Debug().Str("file", f.name).Int("line", line).Err(err).Msg("skipping row")
+			return nil
+		}
+		if key != 0 {
+			if _, found := seen[key]; found {
+				stats.Duplicates++
+				return nil
+			}
+			seen[key] = struct{}{}
+		}
+
+		if stats.Rows <= alreadyWritten {
+			return nil
+		}
+		batch = append(batch, params)
+		if len(batch) < i.options.BatchSize {
+			return nil
+		}
+
+		if err := flush(); err != nil {
+			return err
+		}
+		log.Info().
+			Str("file", f.name).
+			Str("progress", fmt.Sprintf("%.1f%%", content.percent())).
+			Int("rows", stats.Rows).
+			Int("written", stats.Written).
+			Msg("progress")
+		return nil
+	})
+	stats.Skipped += malformed
+	if err != nil {
+		return stats, err
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	log.Info().
+		Str("file", f.name).
+		Int("rows", stats.Rows).
+		Int("written", stats.Written).
+		Int("duplicates", stats.Duplicates).
+		Int("skipped", stats.Skipped).
+		Msg("imported")
+	return stats, nil
+}
+
+func (i *Importer) writeBatch(ctx context.Context, statement string, rows []any) error {
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		return neo4j.ExecuteWrite(ctx, session, func(tx neo4j.ManagedTransaction) (any, error) {
+			*query = statement
+			params = map[string]any{"rows": rows}
+
+			result, err := tx.Run(ctx, *query, params)
+			if err != nil {
+				return nil, err
+			}
+			return result.Consume(ctx)
+		})
+	}
+
+	_, err := repository.RunQuery(ctx, i.driver, i.database, "import usda rows", neo4j.AccessModeWrite, work)
+	return err
+}
+
+// setDensities averages grams per milliliter over every portion measured by volume. Foods that already have a density,
+// from an earlier run or set by hand, are left alone.
+func (i *Importer) setDensities(ctx context.Context) error {
+	milliliters := map[string]any{}
+	for _, unit := range model.MeasuringUnits {
+		if unit.Dimension != model.Volume {
+			continue
+		}
+		for _, name := range append([]string{unit.Name}, unit.Aliases...) {
+			milliliters[name] = unit.BaseAmount
+		}
+	}
+
+	work := func(ctx context.Context, session neo4j.SessionWithContext, query *string, params map[string]any) (any, error) {
+		// CALL { ... } IN TRANSACTIONS only works in an auto-commit transaction
+		*query = fmt.Sprintf("MATCH (f:`%[1]s`) WHERE f.density IS NULL AND exists { (f)-[:`%[2]s`]->(:`%[3]s`) }\n"+
+			"CALL {\n"+
+			"  WITH f\n"+
+			"  MATCH (f)-[:`%[2]s`]->(p:`%[3]s`)\n"+
+			"  WITH f, p, coalesce($milliliters[p.unit], $milliliters[toLower(p.unit)]) AS milliliters\n"+
+			"  WHERE milliliters IS NOT NULL AND p.amount > 0 AND p.gramWeight > 0\n"+
+			"  WITH f, avg(p.gramWeight / (p.amount * milliliters)) AS density\n"+
+			"  SET f.density = density\n"+
+			"} IN TRANSACTIONS",
+			repository.FoodLabel, repository.HasPortionLabel, repository.PortionLabel)
+		params = map[string]any{"milliliters": milliliters}
+
+		result, err := session.Run(ctx, *query, params)
+		if err != nil {
+			return nil, err
+		}
+		return result.Consume(ctx)
+	}
+
+	log.Info().Msg("setting food densities")
+	_, err := repository.RunQuery(ctx, i.driver, i.database, "set food densities", neo4j.AccessModeWrite, work)
+	return err
+}
+
+// progressReader counts how much of a file has been read, for progress reporting
+type progressReader struct {
+	io.ReadCloser
+	read uint64
+	size uint64
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	p.read += uint64(n)
+	return n, err
+}
+
+func (p *progressReader) percent() float64 {
+	if p.size == 0 {
+		return 100
+	}
+	return float64(p.read) * 100 / float64(p.size)
+}
+
+func openCsv(archive *zip.ReadCloser, name string) (*progressReader, error) {
+	// the release nests its files in a directory named after the release
+	for _, f := range archive.File {
+		if path.Base(f.Name) == name {
+			content, err := f.Open()
+			if err != nil {
+				return nil, err
+			}
+			return &progressReader{ReadCloser: content, size: f.UncompressedSize64}, nil
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain %s", name)
+}
+
+// ReadRows parses CSV content from r, calling fn with each record after the header, and returns how many records were malformed.
+// Records with the wrong number of fields are skipped; quotes are parsed leniently, since some descriptions contain stray ones.
+func ReadRows(r io.Reader, name string, fn func(line int, row Row) error) (int, error) {
+	// some exports start with a byte order mark
+	buffered := bufio.NewReader(r)
+	if first, _, err := buffered.ReadRune(); err == nil && first != '\ufeff' {
+		buffered.UnreadRune()
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("could not read the header of %s: %w", name, err)
+	}
+
+	malformed := 0
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return malformed, nil
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			malformed++
+			log.Debug().Str("file", name).Int("line", parseErr.Line).Err(err).Msg("skipping malformed row")
+			continue
+		} else if err != nil {
+			return malformed, fmt.Errorf("could not read %s: %w", name, err)
+		}
+
+		line, _ := reader.FieldPos(0)
+		if len(record) != len(header) {
+			malformed++
+			log.Debug().Str("file", name).Int("line", line).Int("fields", len(record)).Msg("skipping row with the wrong number of fields")
+			continue
+		}
+
+		row := Row{}
+		for i, column := range header {
+			row[column] = record[i]
+		}
+		if err := fn(line, row); err != nil {
+			return malformed, err
+		}
+	}
+}
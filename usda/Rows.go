@@ -0,0 +1,197 @@
+package usda
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Row is a CSV record keyed by the header row
+type Row map[string]string
+
+const nutrientIdPrefix = "grn:tm-food:nutrient:resource:"
+const foodIdPrefix = "grn:tm-food:food:resource:"
+const portionIdPrefix = "grn:tm-food:portion:"
+
+type Nutrient struct {
+	Id       int64
+	Name     string
+	UnitName string
+}
+
+type Food struct {
+	FdcId int64
+	Name  string
+	// nil when the row's publication date is missing or malformed
+	Published *time.Time
+}
+
+type FoodNutrient struct {
+	FdcId      int64
+	NutrientId int64
+	Amount     float64
+}
+
+type Portion struct {
+	Id          int64
+	FdcId       int64
+	Amount      float64
+	Unit        string
+	Description string
+	Modifier    string
+	GramWeight  float64
+}
+
+func ParseNutrient(row Row) (Nutrient, error) {
+	id, err := idField(row, "id")
+	if err != nil {
+		return Nutrient{}, err
+	}
+	name, err := textField(row, "name")
+	if err != nil {
+		return Nutrient{}, err
+	}
+	unitName, err := textField(row, "unit_name")
+	if err != nil {
+		return Nutrient{}, err
+	}
+	return Nutrient{Id: id, Name: name, UnitName: unitName}, nil
+}
+
+func ParseFood(row Row) (Food, error) {
+	fdcId, err := idField(row, "fdc_id")
+	if err != nil {
+		return Food{}, err
+	}
+	name, err := textField(row, "description")
+	if err != nil {
+		return Food{}, err
+	}
+
+	food := Food{FdcId: fdcId, Name: name}
+	if published, err := time.Parse(time.DateOnly, strings.TrimSpace(row["publication_date"])); err == nil {
+		food.Published = &published
+	}
+	return food, nil
+}
+
+func ParseFoodNutrient(row Row) (FoodNutrient, error) {
+	fdcId, err := idField(row, "fdc_id")
+	if err != nil {
+		return FoodNutrient{}, err
+	}
+	nutrientId, err := idField(row, "nutrient_id")
+	if err != nil {
+		return FoodNutrient{}, err
+	}
+	amount, err := amountField(row, "amount")
+	if err != nil {
+		return FoodNutrient{}, err
+	}
+	return FoodNutrient{FdcId: fdcId, NutrientId: nutrientId, Amount: amount}, nil
+}
+
+// ParsePortion looks up the portion's unit in units, which maps measure_unit.csv ids to names
+func ParsePortion(row Row, units map[int64]string) (Portion, error) {
+	id, err := idField(row, "id")
+	if err != nil {
+		return Portion{}, err
+	}
+	fdcId, err := idField(row, "fdc_id")
+	if err != nil {
+		return Portion{}, err
+	}
+
+	// a missing amount means one of the unit
+	amount := 1.0
+	if strings.TrimSpace(row["amount"]) != "" {
+		amount, err = amountField(row, "amount")
+		if err != nil {
+			return Portion{}, err
+		}
+		if amount == 0 {
+			return Portion{}, errors.New("amount must be greater than 0")
+		}
+	}
+
+	gramWeight, err := amountField(row, "gram_weight")
+	if err != nil {
+		return Portion{}, err
+	}
+	if gramWeight == 0 {
+		return Portion{}, errors.New("gram_weight must be greater than 0")
+	}
+
+	unitId, err := idField(row, "measure_unit_id")
+	if err != nil {
+		return Portion{}, err
+	}
+	unit, found := units[unitId]
+	if !found {
+		return Portion{}, fmt.Errorf("unknown measure_unit_id %d", unitId)
+	}
+
+	modifier := cleanText(row["modifier"])
+	// SR Legacy portions leave the unit undetermined and put it at the start of the modifier instead, e.g. "cup, chopped"
+	if unit == "undetermined" {
+		unit = strings.TrimSpace(strings.Split(modifier, ",")[0])
+	}
+	if unit == "" {
+		return Portion{}, errors.New("portion has no unit")
+	}
+
+	return Portion{
+		Id:          id,
+		FdcId:       fdcId,
+		Amount:      amount,
+		Unit:        unit,
+		Description: cleanText(row["portion_description"]),
+		Modifier:    modifier,
+		GramWeight:  gramWeight,
+	}, nil
+}
+
+// ParseMeasureUnit returns a measure_unit.csv row's id and name
+func ParseMeasureUnit(row Row) (int64, string, error) {
+	id, err := idField(row, "id")
+	if err != nil {
+		return 0, "", err
+	}
+	name, err := textField(row, "name")
+	if err != nil {
+		return 0, "", err
+	}
+	return id, name, nil
+}
+
+// cleanText trims the value and collapses runs of whitespace, which some descriptions contain
+func cleanText(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func textField(row Row, name string) (string, error) {
+	value := cleanText(row[name])
+	if value == "" {
+		return "", fmt.Errorf("%s is empty", name)
+	}
+	return value, nil
+}
+
+func idField(row Row, name string) (int64, error) {
+	id, err := strconv.ParseInt(strings.TrimSpace(row[name]), 10, 64)
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%s %q is not a positive integer", name, row[name])
+	}
+	return id, nil
+}
+
+func amountField(row Row, name string) (float64, error) {
+	amount, err := strconv.ParseFloat(strings.TrimSpace(row[name]), 64)
+	if err != nil || amount < 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return 0, fmt.Errorf("%s %q is not a non-negative number", name, row[name])
+	}
+	return amount, nil
+}
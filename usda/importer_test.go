@@ -0,0 +1,126 @@
+package usda
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// idsFile keys each row by its id, so rows with an id that was already seen are duplicates
+var idsFile = file{
+	name: "ids.csv",
+	clean: func(row Row) (int64, map[string]any, error) {
+		id, err := strconv.ParseInt(row["id"], 10, 64)
+		if err != nil {
+			return 0, nil, err
+		}
+		return id, map[string]any{"id": id}, nil
+	},
+	query: "UNWIND $rows AS row RETURN row",
+}
+
+func writeZip(t *testing.T, name string, content string) string {
+	zipPath := filepath.Join(t.TempDir(), "FoodData_Central_csv.zip")
+	out, err := os.Create(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(out)
+	// the release nests its files in a directory
+	w, err := archive.Create("FoodData_Central_csv/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return zipPath
+}
+
+// recordingImporter collects the ids of the rows written, and fails the batch numbered failBatch, counting from 1
+func recordingImporter(failBatch int) (*Importer, *[]int64) {
+	written := []int64{}
+	batches := 0
+	importer := &Importer{options: Options{BatchSize: 2}}
+	importer.write = func(ctx context.Context, statement string, rows []any) error {
+		batches++
+		if batches == failBatch {
+			return errors.New("connection reset")
+		}
+		for _, row := range rows {
+			written = append(written, row.(map[string]any)["id"].(int64))
+		}
+		return nil
+	}
+	return importer, &written
+}
+
+func importIds(t *testing.T, importer *Importer, zipPath string, checkpointPath string) (Stats, error) {
+	archive, err := zip.OpenReader(zipPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	cp, err := loadCheckpoint(checkpointPath, "ids")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return importer.importFile(context.Background(), archive, idsFile, cp)
+}
+
+func TestImportDuplicates(t *testing.T) {
+	zipPath := writeZip(t, idsFile.name, "id\n1\n2\n1\n3\n2\nx\n")
+	importer, written := recordingImporter(0)
+
+	stats, err := importIds(t, importer, zipPath, "")
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal([]int64{1, 2, 3}, *written)
+	assert.Equal(Stats{File: idsFile.name, Rows: 6, Written: 3, Duplicates: 2, Skipped: 1}, stats)
+}
+
+func TestImportResume(t *testing.T) {
+	zipPath := writeZip(t, idsFile.name, "id\n1\n2\n1\n3\n2\n4\n5\n")
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+	assert := assert.New(t)
+
+	// the second batch fails, after the first was written and checkpointed
+	interrupted, firstRun := recordingImporter(2)
+	_, err := importIds(t, interrupted, zipPath, checkpointPath)
+	assert.Error(err)
+	assert.Equal([]int64{1, 2}, *firstRun)
+
+	cp, err := loadCheckpoint(checkpointPath, "ids")
+	assert.NoError(err)
+	assert.Equal(2, cp.Rows[idsFile.name])
+
+	resumed, secondRun := recordingImporter(0)
+	stats, err := importIds(t, resumed, zipPath, checkpointPath)
+	assert.NoError(err)
+	// rows before the checkpoint aren't written again, but still count as seen, so later duplicates of them are dropped
+	assert.Equal([]int64{3, 4, 5}, *secondRun)
+	assert.Equal(7, stats.Rows)
+	assert.Equal(3, stats.Written)
+	assert.Equal(2, stats.Duplicates)
+
+	cp, err = loadCheckpoint(checkpointPath, "ids")
+	assert.NoError(err)
+	assert.Equal(7, cp.Rows[idsFile.name])
+
+	_, err = loadCheckpoint(checkpointPath, "another release")
+	assert.Error(err, "a checkpoint isn't applied to a different archive")
+}
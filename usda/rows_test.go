@@ -0,0 +1,151 @@
+package usda_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ThomasMatlak/food/usda"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFood(t *testing.T) {
+	type testCase struct {
+		name       string
+		row        usda.Row
+		expected   usda.Food
+		shouldFail bool
+	}
+
+	published := time.Date(2019, time.April, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []testCase{
+		{
+			name:     "Clean",
+			row:      usda.Row{"fdc_id": "167512", "description": "Pillsbury Golden Layer Buttermilk Biscuits", "publication_date": "2019-04-01"},
+			expected: usda.Food{FdcId: 167512, Name: "Pillsbury Golden Layer Buttermilk Biscuits", Published: &published},
+		},
+		{
+			name:     "Extra whitespace",
+			row:      usda.Row{"fdc_id": " 167512 ", "description": "  Biscuits,\tplain  ", "publication_date": "2019-04-01"},
+			expected: usda.Food{FdcId: 167512, Name: "Biscuits, plain", Published: &published},
+		},
+		{
+			name:     "Malformed date",
+			row:      usda.Row{"fdc_id": "167512", "description": "Biscuits", "publication_date": "4/1/2019"},
+			expected: usda.Food{FdcId: 167512, Name: "Biscuits"},
+		},
+		{name: "Missing id", row: usda.Row{"fdc_id": "", "description": "Biscuits"}, shouldFail: true},
+		{name: "Non numeric id", row: usda.Row{"fdc_id": "abc", "description": "Biscuits"}, shouldFail: true},
+		{name: "Missing name", row: usda.Row{"fdc_id": "167512", "description": " "}, shouldFail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := usda.ParseFood(tc.row)
+
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParseFoodNutrient(t *testing.T) {
+	type testCase struct {
+		name       string
+		row        usda.Row
+		expected   usda.FoodNutrient
+		shouldFail bool
+	}
+
+	testCases := []testCase{
+		{name: "Clean", row: usda.Row{"fdc_id": "1", "nutrient_id": "1003", "amount": "3.5"}, expected: usda.FoodNutrient{FdcId: 1, NutrientId: 1003, Amount: 3.5}},
+		{name: "Missing amount", row: usda.Row{"fdc_id": "1", "nutrient_id": "1003", "amount": ""}, shouldFail: true},
+		{name: "Negative amount", row: usda.Row{"fdc_id": "1", "nutrient_id": "1003", "amount": "-1"}, shouldFail: true},
+		{name: "Not a number", row: usda.Row{"fdc_id": "1", "nutrient_id": "1003", "amount": "NaN"}, shouldFail: true},
+		{name: "Missing nutrient", row: usda.Row{"fdc_id": "1", "amount": "3.5"}, shouldFail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := usda.ParseFoodNutrient(tc.row)
+
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParsePortion(t *testing.T) {
+	type testCase struct {
+		name       string
+		row        usda.Row
+		expected   usda.Portion
+		shouldFail bool
+	}
+
+	units := map[int64]string{1000: "cup", 9999: "undetermined"}
+	testCases := []testCase{
+		{
+			name:     "Clean",
+			row:      usda.Row{"id": "1", "fdc_id": "2", "amount": "0.5", "measure_unit_id": "1000", "portion_description": "", "modifier": "chopped", "gram_weight": "80"},
+			expected: usda.Portion{Id: 1, FdcId: 2, Amount: 0.5, Unit: "cup", Modifier: "chopped", GramWeight: 80},
+		},
+		{
+			name:     "Missing amount means one",
+			row:      usda.Row{"id": "1", "fdc_id": "2", "amount": "", "measure_unit_id": "1000", "gram_weight": "160"},
+			expected: usda.Portion{Id: 1, FdcId: 2, Amount: 1, Unit: "cup", GramWeight: 160},
+		},
+		{
+			name:     "Undetermined unit comes from the modifier",
+			row:      usda.Row{"id": "1", "fdc_id": "2", "amount": "1", "measure_unit_id": "9999", "modifier": "tbsp, melted", "gram_weight": "14"},
+			expected: usda.Portion{Id: 1, FdcId: 2, Amount: 1, Unit: "tbsp", Modifier: "tbsp, melted", GramWeight: 14},
+		},
+		{name: "Undetermined unit without a modifier", row: usda.Row{"id": "1", "fdc_id": "2", "amount": "1", "measure_unit_id": "9999", "gram_weight": "14"}, shouldFail: true},
+		{name: "Unknown unit", row: usda.Row{"id": "1", "fdc_id": "2", "amount": "1", "measure_unit_id": "5", "gram_weight": "14"}, shouldFail: true},
+		{name: "Zero gram weight", row: usda.Row{"id": "1", "fdc_id": "2", "amount": "1", "measure_unit_id": "1000", "gram_weight": "0"}, shouldFail: true},
+		{name: "Zero amount", row: usda.Row{"id": "1", "fdc_id": "2", "amount": "0", "measure_unit_id": "1000", "gram_weight": "14"}, shouldFail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual, err := usda.ParsePortion(tc.row, units)
+
+			if tc.shouldFail {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestReadRows(t *testing.T) {
+	content := "\ufeff\"id\",\"name\",\"unit_name\"\n" +
+		"\"1003\",\"Protein\",\"G\"\n" +
+		"1004,Pie crust 9\" diameter,G\n" +
+		"\"1005\",\"too\",\"many\",\"fields\"\n" +
+		"\"1008\",\"Energy\",\"KCAL\"\n"
+
+	rows := []usda.Row{}
+	malformed, err := usda.ReadRows(strings.NewReader(content), "nutrient.csv", func(line int, row usda.Row) error {
+		rows = append(rows, row)
+		return nil
+	})
+
+	assert := assert.New(t)
+	assert.NoError(err)
+	assert.Equal(1, malformed)
+	assert.Len(rows, 3)
+	assert.Equal("1003", rows[0]["id"], "the byte order mark is stripped from the header")
+	assert.Equal(`Pie crust 9" diameter`, rows[1]["name"], "stray quotes are kept")
+	assert.Equal("Energy", rows[2]["name"])
+}
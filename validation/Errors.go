@@ -0,0 +1,56 @@
+// Package validation collects what's wrong with a request field by field, so API clients and forms can point at each problem.
+package validation
+
+import (
+	"fmt"
+	"strings"
+)
+
+type FieldError struct {
+	// the field's JSON name, with an index for list items, e.g. ingredients[1].unit
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors holds at most one message per field, the first one added
+type Errors []FieldError
+
+func (e *Errors) Add(field string, message string) {
+	if e.Message(field) == "" {
+		*e = append(*e, FieldError{Field: field, Message: message})
+	}
+}
+
+// Merge adds other's messages for fields that don't have one yet
+func (e *Errors) Merge(other Errors) {
+	for _, fieldError := range other {
+		e.Add(fieldError.Field, fieldError.Message)
+	}
+}
+
+func (e Errors) Valid() bool {
+	return len(e) == 0
+}
+
+// Message returns the field's message, or "" if it's fine
+func (e Errors) Message(field string) string {
+	for _, fieldError := range e {
+		if fieldError.Field == field {
+			return fieldError.Message
+		}
+	}
+	return ""
+}
+
+// Item names a field of one item in a list, e.g. Item("ingredients", 1, "unit") is ingredients[1].unit
+func Item(field string, index int, itemField string) string {
+	return fmt.Sprintf("%s[%d].%s", field, index, itemField)
+}
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldError := range e {
+		messages[i] = fieldError.Field + ": " + fieldError.Message
+	}
+	return strings.Join(messages, "; ")
+}
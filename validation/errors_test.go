@@ -0,0 +1,27 @@
+package validation_test
+
+import (
+	"testing"
+
+	"github.com/ThomasMatlak/food/validation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	var problems validation.Errors
+	assert.True(t, problems.Valid())
+
+	problems.Add("title", "is required")
+	problems.Add(validation.Item("ingredients", 1, "unit"), `unknown unit "cupz"`)
+	// only the first message for a field is kept
+	problems.Add("title", "is too long")
+	problems.Merge(validation.Errors{{Field: "title", Message: "is blank"}, {Field: "servings", Message: "must be at least 1"}})
+
+	assert := assert.New(t)
+	assert.False(problems.Valid())
+	assert.Equal("is required", problems.Message("title"))
+	assert.Equal(`unknown unit "cupz"`, problems.Message("ingredients[1].unit"))
+	assert.Equal("must be at least 1", problems.Message("servings"))
+	assert.Empty(problems.Message("description"))
+	assert.Equal(`title: is required; ingredients[1].unit: unknown unit "cupz"; servings: must be at least 1`, problems.Error())
+}